@@ -0,0 +1,140 @@
+// Package memory implements cache.Cache in the process. It stores values the
+// way the Redis cache does, so the two can be swapped, and is used where
+// state must not be shared with other processes.
+package memory
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"shenanigigs/common/cache"
+)
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+type Cache struct {
+	mutex      sync.Mutex
+	entries    map[string]entry
+	defaultTTL time.Duration
+	closed     bool
+	now        func() time.Time
+}
+
+func New(opts cache.Options) *Cache {
+	if opts.DefaultTTL <= 0 {
+		opts.DefaultTTL = cache.DefaultOptions().DefaultTTL
+	}
+	return &Cache{
+		entries:    make(map[string]entry),
+		defaultTTL: opts.DefaultTTL,
+		now:        time.Now,
+	}
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if key == "" {
+		return cache.ErrInvalidKey
+	}
+	data, err := encode(value)
+	if err != nil {
+		return err
+	}
+	if ttl == 0 {
+		ttl = c.defaultTTL
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return cache.ErrClosed
+	}
+	c.entries[key] = entry{value: data, expiresAt: c.now().Add(ttl)}
+	return nil
+}
+
+func (c *Cache) Get(ctx context.Context, key string, value interface{}) error {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return cache.ErrClosed
+	}
+	e, ok := c.entries[key]
+	if ok && !c.now().Before(e.expiresAt) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mutex.Unlock()
+	if !ok {
+		return cache.ErrNotFound
+	}
+
+	switch v := value.(type) {
+	case *string:
+		*v = string(e.value)
+	case encoding.BinaryUnmarshaler:
+		return v.UnmarshalBinary(e.value)
+	default:
+		return cache.ErrInvalidValue
+	}
+	return nil
+}
+
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return cache.ErrClosed
+	}
+	delete(c.entries, key)
+	return nil
+}
+
+func (c *Cache) Clear(ctx context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return cache.ErrClosed
+	}
+	c.entries = make(map[string]entry)
+	return nil
+}
+
+func (c *Cache) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.closed = true
+	c.entries = nil
+	return nil
+}
+
+// encode converts value to bytes like the Redis client does for SET.
+func encode(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return append([]byte(nil), v...), nil
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(nil, v, 10), nil
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	case encoding.BinaryMarshaler:
+		return v.MarshalBinary()
+	default:
+		return nil, fmt.Errorf("%w: can't marshal %T", cache.ErrInvalidValue, value)
+	}
+}
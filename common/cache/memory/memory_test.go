@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"shenanigigs/common/cache"
+)
+
+type point struct {
+	X, Y int
+}
+
+func (p point) MarshalBinary() ([]byte, error) { return json.Marshal(p) }
+
+func (p *point) UnmarshalBinary(data []byte) error { return json.Unmarshal(data, p) }
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	c := New(cache.Options{})
+
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"text", "text"},
+		{[]byte("bytes"), "bytes"},
+		{42, "42"},
+		{true, "1"},
+		{point{1, 2}, `{"X":1,"Y":2}`},
+	}
+	for _, tt := range tests {
+		if err := c.Set(ctx, "key", tt.value, time.Minute); err != nil {
+			t.Fatalf("Set(%v) error = %v", tt.value, err)
+		}
+		var got string
+		if err := c.Get(ctx, "key", &got); err != nil || got != tt.want {
+			t.Errorf("Get() after Set(%v) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+
+	var p point
+	if err := c.Get(ctx, "key", &p); err != nil || p != (point{1, 2}) {
+		t.Errorf("Get() into a BinaryUnmarshaler = %+v, %v", p, err)
+	}
+	var n int
+	if err := c.Get(ctx, "key", &n); !errors.Is(err, cache.ErrInvalidValue) {
+		t.Errorf("Get() into an int error = %v, want %v", err, cache.ErrInvalidValue)
+	}
+	if err := c.Set(ctx, "key", struct{}{}, 0); !errors.Is(err, cache.ErrInvalidValue) {
+		t.Errorf("Set() of a struct error = %v, want %v", err, cache.ErrInvalidValue)
+	}
+
+	if err := c.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	var got string
+	if err := c.Get(ctx, "key", &got); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, cache.ErrNotFound)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	c := New(cache.Options{DefaultTTL: time.Hour})
+	c.now = func() time.Time { return now }
+
+	_ = c.Set(ctx, "short", "a", time.Minute)
+	_ = c.Set(ctx, "default", "b", 0)
+
+	now = now.Add(time.Minute)
+	var got string
+	if err := c.Get(ctx, "short", &got); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get() after its TTL error = %v, want %v", err, cache.ErrNotFound)
+	}
+	if err := c.Get(ctx, "default", &got); err != nil || got != "b" {
+		t.Errorf("Get() within the default TTL = %q, %v", got, err)
+	}
+
+	now = now.Add(time.Hour)
+	if err := c.Get(ctx, "default", &got); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get() after the default TTL error = %v, want %v", err, cache.ErrNotFound)
+	}
+}
@@ -133,6 +133,12 @@ func (c *Cache) Clear(ctx context.Context) error {
 	return err
 }
 
+// Client returns the underlying Redis client so callers can share the
+// connection for operations the Cache interface does not cover.
+func (c *Cache) Client() *redis.Client {
+	return c.client
+}
+
 func (c *Cache) Close() error {
 	return c.client.Close()
 }
@@ -0,0 +1,278 @@
+// Package config loads service configuration into a struct. Values come
+// from, in increasing order of precedence: the default tag of each field, a
+// YAML or TOML file, environment variables and command line flags.
+//
+// Fields are described by struct tags:
+//
+//	config:"redis_addr"      key in the file, REDIS_ADDR in the environment
+//	                         and -redis-addr on the command line
+//	default:"localhost:6379" value used when no source sets the field
+//	validate:"required,min=0,max=15,oneof=a|b"
+//	secret:"true"            redacted by Print
+//	sep:";"                  separator of list and map items given as
+//	                         text, "," by default
+//	usage:"..."              help text of the flag
+//
+// Fields without a config tag are left alone. Every environment variable can
+// also be given as NAME_FILE, naming a file that holds the value, which is
+// how secrets are usually mounted.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FileKey names the config file setting: -config on the command line and
+// CONFIG_FILE in the environment.
+const FileKey = "config"
+
+type Options struct {
+	// File is the config file read when neither -config nor CONFIG_FILE is
+	// given. Empty means no file.
+	File string
+	// EnvPrefix is prepended to the name of every environment variable.
+	EnvPrefix string
+	// FlagSet, when set, gets a flag for every field and is parsed from
+	// Args. Flags of the program itself can be registered on it beforehand.
+	FlagSet *flag.FlagSet
+	Args    []string
+	// LookupEnv defaults to os.LookupEnv.
+	LookupEnv func(string) (string, bool)
+}
+
+// Error lists every problem found while loading a config.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Validator is implemented by configs with rules across fields. Validate is
+// called after every field has been loaded and checked.
+type Validator interface {
+	Validate() error
+}
+
+type field struct {
+	key      string
+	value    reflect.Value
+	def      string
+	hasDef   bool
+	validate string
+	secret   bool
+	sep      string
+	usage    string
+}
+
+func (f *field) envName(prefix string) string {
+	return prefix + strings.ToUpper(f.key)
+}
+
+func (f *field) flagName() string {
+	return strings.ReplaceAll(f.key, "_", "-")
+}
+
+func (f *field) isBool() bool {
+	return f.value.Kind() == reflect.Bool
+}
+
+// Load fills dst, a pointer to a struct, and validates it. Problems with
+// any source are reported together in an *Error.
+func Load(dst interface{}, opts Options) error {
+	fields, err := fieldsOf(dst)
+	if err != nil {
+		return err
+	}
+	lookup := opts.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	file := opts.File
+	if path, ok := lookup(opts.EnvPrefix + strings.ToUpper(FileKey) + "_FILE"); ok {
+		file = path
+	}
+	flagValues := make(map[string]string)
+	if opts.FlagSet != nil {
+		fileFlag := opts.FlagSet.String(FileKey, file, "YAML or TOML config file")
+		registerFlags(opts.FlagSet, fields, opts.EnvPrefix, flagValues)
+		if err := opts.FlagSet.Parse(opts.Args); err != nil {
+			return err
+		}
+		file = *fileFlag
+	}
+
+	problems := &Error{}
+	for _, f := range fields {
+		if !f.hasDef {
+			continue
+		}
+		if err := setText(f.value, f.def, f.sep); err != nil {
+			problems.add("default of %s: %v", f.key, err)
+		}
+	}
+
+	if file != "" {
+		values, err := readFile(file)
+		if err != nil {
+			problems.add("%v", err)
+		} else {
+			byKey := make(map[string]*field, len(fields))
+			for _, f := range fields {
+				byKey[f.key] = f
+			}
+			applyFile(file, "", values, byKey, problems)
+		}
+	}
+
+	for _, f := range fields {
+		name := f.envName(opts.EnvPrefix)
+		value, ok, err := lookupEnv(lookup, name)
+		if err != nil {
+			problems.add("%v", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := setText(f.value, value, f.sep); err != nil {
+			problems.add("%s: %v", name, err)
+		}
+	}
+
+	for _, f := range fields {
+		value, ok := flagValues[f.key]
+		if !ok {
+			continue
+		}
+		if err := setText(f.value, value, f.sep); err != nil {
+			problems.add("-%s: %v", f.flagName(), err)
+		}
+	}
+
+	if len(problems.Problems) > 0 {
+		return problems
+	}
+
+	for _, f := range fields {
+		for _, problem := range check(f) {
+			problems.add("%s %s", f.key, problem)
+		}
+	}
+	if validator, ok := dst.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			problems.add("%v", err)
+		}
+	}
+	if len(problems.Problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func (e *Error) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+func fieldsOf(dst interface{}) ([]*field, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: destination must be a pointer to a struct, got %T", dst)
+	}
+	v = v.Elem()
+	t := v.Type()
+
+	var fields []*field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, ok := sf.Tag.Lookup("config")
+		if !ok || !sf.IsExported() {
+			continue
+		}
+		def, hasDef := sf.Tag.Lookup("default")
+		sep := sf.Tag.Get("sep")
+		if sep == "" {
+			sep = ","
+		}
+		fields = append(fields, &field{
+			key:      key,
+			value:    v.Field(i),
+			def:      def,
+			hasDef:   hasDef,
+			validate: sf.Tag.Get("validate"),
+			secret:   sf.Tag.Get("secret") == "true",
+			sep:      sep,
+			usage:    sf.Tag.Get("usage"),
+		})
+	}
+	return fields, nil
+}
+
+func registerFlags(fs *flag.FlagSet, fields []*field, envPrefix string, values map[string]string) {
+	for _, f := range fields {
+		f := f
+		usage := f.usage
+		if usage == "" {
+			usage = "overrides " + f.envName(envPrefix)
+		}
+		set := func(value string) error {
+			values[f.key] = value
+			return nil
+		}
+		if f.isBool() {
+			fs.BoolFunc(f.flagName(), usage, set)
+		} else {
+			fs.Func(f.flagName(), usage, set)
+		}
+	}
+}
+
+// lookupEnv reads name, or the file named by name_FILE. Setting both is an
+// error, since it is unclear which one was meant.
+func lookupEnv(lookup func(string) (string, bool), name string) (string, bool, error) {
+	value, ok := lookup(name)
+	path, fromFile := lookup(name + "_FILE")
+	if !fromFile {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("both %s and %s_FILE are set", name, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// applyFile sets the fields named by the keys of values. A table whose key is
+// not a field groups fields by prefix, so that redis_addr can also be
+// written as addr in a redis table.
+func applyFile(file, prefix string, values map[string]interface{}, byKey map[string]*field, problems *Error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key, raw := prefix+name, values[name]
+		if f, ok := byKey[key]; ok {
+			if err := setValue(f.value, raw, f.sep); err != nil {
+				problems.add("%s: %s: %v", file, key, err)
+			}
+			continue
+		}
+		if table, ok := raw.(map[string]interface{}); ok {
+			applyFile(file, key+"_", table, byKey, problems)
+			continue
+		}
+		problems.add("%s: unknown key %s", file, key)
+	}
+}
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setText parses text into v. Lists are items separated by sep; maps are
+// name=value items separated by sep.
+func setText(v reflect.Value, text string, sep string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(strings.TrimSpace(text))
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", text)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", text)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(text), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", text)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(text), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", text)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", text)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(text, sep) {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setText(elem, item, sep); err != nil {
+				return err
+			}
+			items = reflect.Append(items, elem)
+		}
+		if items.Len() == 0 {
+			items = reflect.Zero(v.Type())
+		}
+		v.Set(items)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map type %s", v.Type())
+		}
+		items := reflect.MakeMap(v.Type())
+		for _, item := range strings.Split(text, sep) {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			name, raw, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("invalid item %q, want name=value", item)
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setText(elem, strings.TrimSpace(raw), sep); err != nil {
+				return err
+			}
+			items.SetMapIndex(reflect.ValueOf(strings.TrimSpace(name)), elem)
+		}
+		v.Set(items)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// setValue sets v from a value decoded from a config file. Strings are
+// parsed like environment variables, so lists and maps can be written either
+// natively or as text.
+func setValue(v reflect.Value, raw interface{}, sep string) error {
+	switch raw := raw.(type) {
+	case nil:
+		return nil
+	case string:
+		return setText(v, raw, sep)
+	case []interface{}:
+		if v.Kind() != reflect.Slice {
+			return fmt.Errorf("unexpected list for %s", v.Type())
+		}
+		items := reflect.MakeSlice(v.Type(), 0, len(raw))
+		for _, item := range raw {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, item, sep); err != nil {
+				return err
+			}
+			items = reflect.Append(items, elem)
+		}
+		v.Set(items)
+		return nil
+	case map[string]interface{}:
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unexpected table for %s", v.Type())
+		}
+		items := reflect.MakeMap(v.Type())
+		for name, item := range raw {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, item, sep); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			items.SetMapIndex(reflect.ValueOf(name), elem)
+		}
+		v.Set(items)
+		return nil
+	default:
+		if v.Type() == durationType {
+			return fmt.Errorf("duration %v needs a unit, such as \"%vs\"", raw, raw)
+		}
+		if v.Kind() == reflect.Slice || v.Kind() == reflect.Map || v.Kind() == reflect.String {
+			return fmt.Errorf("unexpected %T for %s", raw, v.Type())
+		}
+		return setText(v, fmt.Sprint(raw), sep)
+	}
+}
+
+// readFile decodes a YAML or TOML file, picked by extension.
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format, want .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// check applies the validate tag of f and returns the problems found.
+func check(f *field) []string {
+	if f.validate == "" {
+		return nil
+	}
+	var problems []string
+	for _, rule := range strings.Split(f.validate, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			if isEmpty(f.value) {
+				problems = append(problems, "is required")
+			}
+		case "min", "max":
+			if problem := checkBound(f.value, name, arg); problem != "" {
+				problems = append(problems, problem)
+			}
+		case "oneof":
+			allowed := strings.Split(arg, "|")
+			for _, value := range stringValues(f.value) {
+				if !contains(allowed, value) {
+					problems = append(problems, fmt.Sprintf("must be one of %s, got %q", strings.Join(allowed, ", "), value))
+				}
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("has unknown validation rule %q", name))
+		}
+	}
+	return problems
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	default:
+		return v.IsZero()
+	}
+}
+
+func checkBound(v reflect.Value, rule, arg string) string {
+	var value, bound float64
+	var shown string
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return fmt.Sprintf("has invalid %s bound %q", rule, arg)
+		}
+		value, bound, shown = float64(v.Int()), float64(d), time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Map:
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Sprintf("has invalid %s bound %q", rule, arg)
+		}
+		value, bound, shown = float64(v.Len()), n, fmt.Sprintf("%d items", v.Len())
+	default:
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Sprintf("has invalid %s bound %q", rule, arg)
+		}
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			value = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			value = float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			value = v.Float()
+		default:
+			return fmt.Sprintf("cannot have a %s bound", rule)
+		}
+		bound, shown = n, fmt.Sprint(v.Interface())
+	}
+
+	if rule == "min" && value < bound {
+		return fmt.Sprintf("must be at least %s, got %s", arg, shown)
+	}
+	if rule == "max" && value > bound {
+		return fmt.Sprintf("must be at most %s, got %s", arg, shown)
+	}
+	return ""
+}
+
+func stringValues(v reflect.Value) []string {
+	switch v.Kind() {
+	case reflect.String:
+		return []string{v.String()}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = v.Index(i).String()
+		}
+		return values
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	return keys
+}
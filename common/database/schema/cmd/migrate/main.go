@@ -46,6 +46,17 @@ func main() {
 
 	migrations := []schema.Migration{
 		migrations.CreateJobsTable,
+		migrations.AddBoardColumnsToJobs,
+		migrations.CreateCandidatesTable,
+		migrations.CreateFreelancePostsTable,
+		migrations.AddStatusColumnsToJobs,
+		migrations.AddRemovedAtToJobs,
+		migrations.AddSoftDeleteToCandidates,
+		migrations.AddSoftDeleteToFreelancePosts,
+		migrations.AddMarkdownAndLinksToJobs,
+		migrations.AddProvenanceToJobs,
+		migrations.CreateIngestionRunsTable,
+		migrations.AddCommentsQueuedToIngestionRuns,
 	}
 
 	for _, migration := range migrations {
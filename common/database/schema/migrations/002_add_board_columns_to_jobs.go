@@ -0,0 +1,20 @@
+package migrations
+
+import "shenanigigs/common/database/schema"
+
+var AddBoardColumnsToJobs = schema.Migration{
+	Version:     2,
+	Description: "Add department, employment type and source board to jobs",
+	Up: `
+		ALTER TABLE jobs
+			ADD COLUMN IF NOT EXISTS department String DEFAULT '' AFTER remote_policy,
+			ADD COLUMN IF NOT EXISTS employment_type String DEFAULT '' AFTER department,
+			ADD COLUMN IF NOT EXISTS source_board String DEFAULT '' AFTER source_url
+	`,
+	Down: `
+		ALTER TABLE jobs
+			DROP COLUMN IF EXISTS department,
+			DROP COLUMN IF EXISTS employment_type,
+			DROP COLUMN IF EXISTS source_board
+	`,
+}
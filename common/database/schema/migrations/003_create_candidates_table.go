@@ -0,0 +1,31 @@
+package migrations
+
+import "shenanigigs/common/database/schema"
+
+var CreateCandidatesTable = schema.Migration{
+	Version:     3,
+	Description: "Create candidates table",
+	Up: `
+		CREATE TABLE IF NOT EXISTS candidates (
+			id UUID,
+			thread_id UInt64,
+			location String,
+			remote String,
+			willing_to_relocate String,
+			technologies Array(String),
+			resume_url String,
+			email String,
+			description String,
+			source String,
+			source_url String,
+			created_at DateTime,
+			updated_at DateTime,
+			raw_data String,
+			PRIMARY KEY (id)
+		) ENGINE = ReplacingMergeTree(updated_at)
+		PARTITION BY toYYYYMM(created_at)
+		ORDER BY (id, created_at)
+		SETTINGS index_granularity = 8192
+	`,
+	Down: `DROP TABLE IF EXISTS candidates`,
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import "shenanigigs/common/database/schema"
+
+var CreateFreelancePostsTable = schema.Migration{
+	Version:     4,
+	Description: "Create freelance posts table",
+	Up: `
+		CREATE TABLE IF NOT EXISTS freelance_posts (
+			id UUID,
+			thread_id UInt64,
+			kind LowCardinality(String),
+			location String,
+			remote_policy String,
+			technologies Array(String),
+			rate String,
+			email String,
+			url String,
+			description String,
+			source String,
+			source_url String,
+			created_at DateTime,
+			updated_at DateTime,
+			raw_data String,
+			PRIMARY KEY (id)
+		) ENGINE = ReplacingMergeTree(updated_at)
+		PARTITION BY toYYYYMM(created_at)
+		ORDER BY (id, created_at)
+		SETTINGS index_granularity = 8192
+	`,
+	Down: `DROP TABLE IF EXISTS freelance_posts`,
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import "shenanigigs/common/database/schema"
+
+var AddStatusColumnsToJobs = schema.Migration{
+	Version:     5,
+	Description: "Add updates and open/filled status to jobs",
+	Up: `
+		ALTER TABLE jobs
+			ADD COLUMN IF NOT EXISTS updates Array(String) DEFAULT [] AFTER source_board,
+			ADD COLUMN IF NOT EXISTS is_active UInt8 DEFAULT 1 AFTER updates,
+			ADD COLUMN IF NOT EXISTS status LowCardinality(String) DEFAULT 'open' AFTER is_active,
+			ADD COLUMN IF NOT EXISTS status_changed_at Nullable(DateTime) AFTER status
+	`,
+	Down: `
+		ALTER TABLE jobs
+			DROP COLUMN IF EXISTS updates,
+			DROP COLUMN IF EXISTS is_active,
+			DROP COLUMN IF EXISTS status,
+			DROP COLUMN IF EXISTS status_changed_at
+	`,
+}
@@ -0,0 +1,16 @@
+package migrations
+
+import "shenanigigs/common/database/schema"
+
+var AddRemovedAtToJobs = schema.Migration{
+	Version:     6,
+	Description: "Add removed_at to jobs",
+	Up: `
+		ALTER TABLE jobs
+			ADD COLUMN IF NOT EXISTS removed_at Nullable(DateTime) AFTER status_changed_at
+	`,
+	Down: `
+		ALTER TABLE jobs
+			DROP COLUMN IF EXISTS removed_at
+	`,
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import "shenanigigs/common/database/schema"
+
+var AddSoftDeleteToCandidates = schema.Migration{
+	Version:     7,
+	Description: "Add is_active and removed_at to candidates",
+	Up: `
+		ALTER TABLE candidates
+			ADD COLUMN IF NOT EXISTS is_active UInt8 DEFAULT 1 AFTER source_url,
+			ADD COLUMN IF NOT EXISTS removed_at Nullable(DateTime) AFTER is_active
+	`,
+	Down: `
+		ALTER TABLE candidates
+			DROP COLUMN IF EXISTS is_active,
+			DROP COLUMN IF EXISTS removed_at
+	`,
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import "shenanigigs/common/database/schema"
+
+var AddSoftDeleteToFreelancePosts = schema.Migration{
+	Version:     8,
+	Description: "Add is_active and removed_at to freelance posts",
+	Up: `
+		ALTER TABLE freelance_posts
+			ADD COLUMN IF NOT EXISTS is_active UInt8 DEFAULT 1 AFTER source_url,
+			ADD COLUMN IF NOT EXISTS removed_at Nullable(DateTime) AFTER is_active
+	`,
+	Down: `
+		ALTER TABLE freelance_posts
+			DROP COLUMN IF EXISTS is_active,
+			DROP COLUMN IF EXISTS removed_at
+	`,
+}
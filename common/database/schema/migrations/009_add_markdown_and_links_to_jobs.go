@@ -0,0 +1,18 @@
+package migrations
+
+import "shenanigigs/common/database/schema"
+
+var AddMarkdownAndLinksToJobs = schema.Migration{
+	Version:     9,
+	Description: "Add Markdown description and links to jobs",
+	Up: `
+		ALTER TABLE jobs
+			ADD COLUMN IF NOT EXISTS description_markdown String DEFAULT '' AFTER description,
+			ADD COLUMN IF NOT EXISTS links Array(String) DEFAULT [] AFTER description_markdown
+	`,
+	Down: `
+		ALTER TABLE jobs
+			DROP COLUMN IF EXISTS description_markdown,
+			DROP COLUMN IF EXISTS links
+	`,
+}
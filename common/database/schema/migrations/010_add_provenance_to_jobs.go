@@ -0,0 +1,22 @@
+package migrations
+
+import "shenanigigs/common/database/schema"
+
+var AddProvenanceToJobs = schema.Migration{
+	Version:     10,
+	Description: "Add author, thread and source item to jobs",
+	Up: `
+		ALTER TABLE jobs
+			ADD COLUMN IF NOT EXISTS source_item_id String DEFAULT '' AFTER source_board,
+			ADD COLUMN IF NOT EXISTS author String DEFAULT '' AFTER source_item_id,
+			ADD COLUMN IF NOT EXISTS thread_id UInt64 DEFAULT 0 AFTER author,
+			ADD COLUMN IF NOT EXISTS thread_month String DEFAULT '' AFTER thread_id
+	`,
+	Down: `
+		ALTER TABLE jobs
+			DROP COLUMN IF EXISTS source_item_id,
+			DROP COLUMN IF EXISTS author,
+			DROP COLUMN IF EXISTS thread_id,
+			DROP COLUMN IF EXISTS thread_month
+	`,
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import "shenanigigs/common/database/schema"
+
+var CreateIngestionRunsTable = schema.Migration{
+	Version:     11,
+	Description: "Create ingestion runs table",
+	Up: `
+		CREATE TABLE IF NOT EXISTS ingestion_runs (
+			id String,
+			source String,
+			trigger String,
+			target String,
+			state String,
+			started_at DateTime64(3),
+			finished_at DateTime64(3),
+			thread_ids Array(UInt64),
+			thread_types Array(String),
+			thread_months Array(String),
+			hiring_threads_found UInt32,
+			candidate_threads_found UInt32,
+			freelance_threads_found UInt32,
+			comments_fetched UInt32,
+			comments_published UInt32,
+			comments_skipped UInt32,
+			comments_removed UInt32,
+			stories_failed UInt32,
+			comments_failed UInt32,
+			error String,
+			error_samples Array(String)
+		) ENGINE = MergeTree()
+		PARTITION BY toYYYYMM(started_at)
+		ORDER BY (source, started_at, id)
+		TTL toDateTime(started_at) + INTERVAL 1 YEAR
+		SETTINGS index_granularity = 8192
+	`,
+	Down: `DROP TABLE IF EXISTS ingestion_runs`,
+}
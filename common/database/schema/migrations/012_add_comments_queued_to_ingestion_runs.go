@@ -0,0 +1,16 @@
+package migrations
+
+import "shenanigigs/common/database/schema"
+
+var AddCommentsQueuedToIngestionRuns = schema.Migration{
+	Version:     12,
+	Description: "Add queued comment count to ingestion runs",
+	Up: `
+		ALTER TABLE ingestion_runs
+			ADD COLUMN IF NOT EXISTS comments_queued UInt32 DEFAULT 0 AFTER freelance_threads_found
+	`,
+	Down: `
+		ALTER TABLE ingestion_runs
+			DROP COLUMN IF EXISTS comments_queued
+	`,
+}
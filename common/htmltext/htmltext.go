@@ -0,0 +1,199 @@
+package htmltext
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Link is a hyperlink found in a document. Text is what was shown to the
+// reader, which HN shortens for long URLs.
+type Link struct {
+	URL  string `json:"url"`
+	Text string `json:"text"`
+}
+
+// Document is an HTML fragment rendered as plain text and Markdown, with
+// entities decoded and its links extracted.
+type Document struct {
+	HTML     string
+	Text     string
+	Markdown string
+	Links    []Link
+}
+
+var (
+	blankLines      = regexp.MustCompile(`\n{3,}`)
+	trailingSpaces  = regexp.MustCompile(`[ \t]+\n`)
+	markdownSpecial = strings.NewReplacer(
+		`\`, `\\`,
+		"`", "\\`",
+		`*`, `\*`,
+		`_`, `\_`,
+		`[`, `\[`,
+		`]`, `\]`,
+	)
+)
+
+// Convert renders an HTML fragment such as an HN comment. HN separates
+// paragraphs with bare <p> tags and supports links, <i> and <pre><code>
+// blocks; other markup is reduced to its text.
+func Convert(fragment string) Document {
+	doc := Document{HTML: fragment}
+
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		// The tokenizer only fails on read errors, which a string reader
+		// never returns; fall back to the unescaped input all the same.
+		text := html.UnescapeString(fragment)
+		doc.Text, doc.Markdown = text, text
+		return doc
+	}
+
+	r := &renderer{}
+	for _, node := range nodes {
+		r.render(node)
+	}
+
+	doc.Text = tidy(r.text.String())
+	doc.Markdown = tidy(r.markdown.String())
+	doc.Links = r.links
+	return doc
+}
+
+type renderer struct {
+	text     strings.Builder
+	markdown strings.Builder
+	links    []Link
+}
+
+func (r *renderer) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text.WriteString(n.Data)
+		r.markdown.WriteString(markdownSpecial.Replace(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		r.renderChildren(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.P, atom.Div:
+		r.paragraph()
+		r.renderChildren(n)
+		r.paragraph()
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		r.paragraph()
+		r.markdown.WriteString("### ")
+		r.renderChildren(n)
+		r.paragraph()
+	case atom.Ul, atom.Ol:
+		r.paragraph()
+		r.renderChildren(n)
+		r.paragraph()
+	case atom.Br:
+		r.text.WriteString("\n")
+		r.markdown.WriteString("\n")
+	case atom.A:
+		r.link(n)
+	case atom.I, atom.Em:
+		r.wrap(n, "*")
+	case atom.B, atom.Strong:
+		r.wrap(n, "**")
+	case atom.Pre:
+		r.paragraph()
+		code := strings.TrimRight(textOf(n), "\n")
+		r.text.WriteString(code)
+		r.markdown.WriteString("```\n" + code + "\n```")
+		r.paragraph()
+	case atom.Li:
+		r.text.WriteString("\n- ")
+		r.markdown.WriteString("\n- ")
+		r.renderChildren(n)
+	case atom.Script, atom.Style:
+	default:
+		r.renderChildren(n)
+	}
+}
+
+func (r *renderer) renderChildren(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		r.render(child)
+	}
+}
+
+func (r *renderer) paragraph() {
+	r.text.WriteString("\n\n")
+	r.markdown.WriteString("\n\n")
+}
+
+func (r *renderer) wrap(n *html.Node, marker string) {
+	text := strings.TrimSpace(textOf(n))
+	if text == "" {
+		r.renderChildren(n)
+		return
+	}
+	r.text.WriteString(textOf(n))
+	r.markdown.WriteString(marker + markdownSpecial.Replace(text) + marker)
+}
+
+func (r *renderer) link(n *html.Node) {
+	href := ""
+	for _, attr := range n.Attr {
+		if attr.Key == "href" {
+			href = strings.TrimSpace(attr.Val)
+		}
+	}
+	text := strings.TrimSpace(textOf(n))
+	if href == "" {
+		r.text.WriteString(text)
+		r.markdown.WriteString(markdownSpecial.Replace(text))
+		return
+	}
+
+	r.links = append(r.links, Link{URL: href, Text: text})
+
+	// Long URLs are shown shortened with a trailing "..."; write the full
+	// URL rather than the truncated one.
+	if text == "" || text == href || isShortened(text, href) {
+		r.text.WriteString(href)
+		r.markdown.WriteString("<" + href + ">")
+		return
+	}
+	r.text.WriteString(text)
+	r.markdown.WriteString("[" + markdownSpecial.Replace(text) + "](" + href + ")")
+}
+
+func isShortened(text, href string) bool {
+	prefix := strings.TrimSuffix(text, "...")
+	return prefix != text && strings.HasPrefix(href, prefix)
+}
+
+func textOf(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func tidy(s string) string {
+	s = trailingSpaces.ReplaceAllString(s, "\n")
+	s = blankLines.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,147 @@
+package htmltext
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// TestConvertGolden renders every testdata/*.html fragment and compares the
+// text, Markdown and links with the matching .golden file. Run with -update
+// after an intended change in the output.
+func TestConvertGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no testdata/*.html fixtures")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".html")
+		t.Run(name, func(t *testing.T) {
+			fragment, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := render(Convert(string(fragment)))
+
+			golden := strings.TrimSuffix(input, ".html") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("reading golden file: %v (run with -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("Convert(%s) mismatch\n--- got ---\n%s\n--- want ---\n%s", input, got, want)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name         string
+		fragment     string
+		wantText     string
+		wantMarkdown string
+		wantLinks    []Link
+	}{
+		{
+			name:         "plain text",
+			fragment:     "Just text",
+			wantText:     "Just text",
+			wantMarkdown: "Just text",
+		},
+		{
+			name:         "entities",
+			fragment:     "Go &amp; Rust &#x2F; C&#x27;s &lt;3",
+			wantText:     "Go & Rust / C's <3",
+			wantMarkdown: "Go & Rust / C's <3",
+		},
+		{
+			name:         "bare paragraphs",
+			fragment:     "first<p>second<p>third",
+			wantText:     "first\n\nsecond\n\nthird",
+			wantMarkdown: "first\n\nsecond\n\nthird",
+		},
+		{
+			name:         "line break",
+			fragment:     "one<br>two",
+			wantText:     "one\ntwo",
+			wantMarkdown: "one\ntwo",
+		},
+		{
+			name:         "italic",
+			fragment:     "a <i>very</i> good fit",
+			wantText:     "a very good fit",
+			wantMarkdown: "a *very* good fit",
+		},
+		{
+			name:         "shortened link",
+			fragment:     `<a href="https://example.com/a/very/long/path">https://example.com/a/ver...</a>`,
+			wantText:     "https://example.com/a/very/long/path",
+			wantMarkdown: "<https://example.com/a/very/long/path>",
+			wantLinks:    []Link{{URL: "https://example.com/a/very/long/path", Text: "https://example.com/a/ver..."}},
+		},
+		{
+			name:         "named link",
+			fragment:     `see <a href="https://example.com">our site</a>`,
+			wantText:     "see our site",
+			wantMarkdown: "see [our site](https://example.com)",
+			wantLinks:    []Link{{URL: "https://example.com", Text: "our site"}},
+		},
+		{
+			name:         "markdown special characters",
+			fragment:     "use *ptr and a_b [x]",
+			wantText:     "use *ptr and a_b [x]",
+			wantMarkdown: `use \*ptr and a\_b \[x\]`,
+		},
+		{
+			name:     "empty",
+			fragment: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := Convert(tt.fragment)
+			if doc.HTML != tt.fragment {
+				t.Errorf("HTML = %q, want the input %q", doc.HTML, tt.fragment)
+			}
+			if doc.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", doc.Text, tt.wantText)
+			}
+			if doc.Markdown != tt.wantMarkdown {
+				t.Errorf("Markdown = %q, want %q", doc.Markdown, tt.wantMarkdown)
+			}
+			if fmt.Sprint(doc.Links) != fmt.Sprint(tt.wantLinks) {
+				t.Errorf("Links = %v, want %v", doc.Links, tt.wantLinks)
+			}
+		})
+	}
+}
+
+// render lays a Document out as the sections of a golden file.
+func render(doc Document) string {
+	var b strings.Builder
+	b.WriteString("-- text --\n")
+	b.WriteString(doc.Text)
+	b.WriteString("\n-- markdown --\n")
+	b.WriteString(doc.Markdown)
+	b.WriteString("\n-- links --\n")
+	for _, link := range doc.Links {
+		fmt.Fprintf(&b, "%s %q\n", link.URL, link.Text)
+	}
+	return b.String()
+}
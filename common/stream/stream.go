@@ -0,0 +1,75 @@
+// Package stream defines the JetStream stream that carries postings from
+// ingestion to processing. Both services provision it at startup, so that
+// either one can start first.
+package stream
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	DefaultName = "JOBS"
+
+	JobPostingsSubject = "jobs.new"
+	CandidatesSubject  = "candidates.new"
+	FreelanceSubject   = "freelance.new"
+	JobStatusSubject   = "jobs.status"
+	JobRemovedSubject  = "jobs.removed"
+)
+
+// Subjects are the subjects stored by the stream.
+var Subjects = []string{
+	JobPostingsSubject,
+	CandidatesSubject,
+	FreelanceSubject,
+	JobStatusSubject,
+	JobRemovedSubject,
+}
+
+type Options struct {
+	Name            string
+	MaxAge          time.Duration
+	Replicas        int
+	DuplicateWindow time.Duration
+}
+
+func Config(opts Options) jetstream.StreamConfig {
+	name := opts.Name
+	if name == "" {
+		name = DefaultName
+	}
+	return jetstream.StreamConfig{
+		Name:       name,
+		Subjects:   Subjects,
+		Retention:  jetstream.LimitsPolicy,
+		Storage:    jetstream.FileStorage,
+		Discard:    jetstream.DiscardOld,
+		MaxAge:     opts.MaxAge,
+		Replicas:   opts.Replicas,
+		Duplicates: opts.DuplicateWindow,
+	}
+}
+
+// Ensure creates the stream, or updates it to match opts.
+func Ensure(ctx context.Context, js jetstream.JetStream, opts Options) (jetstream.Stream, error) {
+	cfg := Config(opts)
+	s, err := js.CreateOrUpdateStream(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("provisioning stream %s: %w", cfg.Name, err)
+	}
+	return s, nil
+}
+
+// MsgID is the deduplication ID of a message. It covers the content, so
+// that a publish retried within the duplicate window is stored once while an
+// edited posting is stored again.
+func MsgID(subject string, data []byte) string {
+	sum := sha256.Sum256(append([]byte(subject+"\x00"), data...))
+	return hex.EncodeToString(sum[:16])
+}
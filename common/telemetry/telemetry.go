@@ -25,6 +25,10 @@ func Int(key string, value int) attribute.KeyValue {
 	return attribute.Int(key, value)
 }
 
+func Bool(key string, value bool) attribute.KeyValue {
+	return attribute.Bool(key, value)
+}
+
 func InitTracer(ctx context.Context, serviceName string, collectorURL string) (func(), error) {
 	conn, err := grpc.DialContext(ctx, collectorURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
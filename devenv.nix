@@ -13,6 +13,10 @@
   services.redis.enable = true;
   services.clickhouse.enable = true;
 
+  # The job pipeline runs on a JetStream stream, so the server needs
+  # JetStream enabled.
+  processes.nats.exec = "nats-server -js -sd ${config.env.DEVENV_STATE}/nats";
+
   pre-commit = {
     hooks = {
       golangci-lint = {
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"shenanigigs/common/cache"
+	"shenanigigs/common/cache/redis"
+	"shenanigigs/ingestion/internal/api"
+	"shenanigigs/ingestion/internal/archive"
+	"shenanigigs/ingestion/internal/config"
+	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/models"
+	"shenanigigs/ingestion/internal/scheduler"
+	"shenanigigs/ingestion/internal/sources"
+
+	"go.uber.org/zap"
+)
+
+const monthLayout = "2006-01"
+
+type window struct {
+	label string
+	from  time.Time
+	to    time.Time
+}
+
+type backfillState struct {
+	CompletedStories map[int]time.Time `json:"completed_stories"`
+}
+
+type threadSearcher interface {
+	SearchHiringThreadsBetween(ctx context.Context, from, to time.Time) (models.IntSlice, error)
+}
+
+type storyProcessor interface {
+	ProcessStories(ctx context.Context, stories models.IntSlice) (scheduler.RunStats, error)
+}
+
+// backfiller runs the hiring threads of each window through the story
+// pipeline, skipping the threads the state records as completed.
+type backfiller struct {
+	source    threadSearcher
+	processor storyProcessor
+	state     *backfillState
+	// save persists the state after each completed thread. When nil the
+	// state is only kept in memory.
+	save   func(*backfillState) error
+	logger *zap.Logger
+}
+
+func main() {
+	from := flag.String("from", "", "start of the backfill range (YYYY-MM or YYYY-MM-DD, inclusive)")
+	to := flag.String("to", "", "end of the backfill range (YYYY-MM or YYYY-MM-DD, inclusive)")
+	months := flag.String("months", "", "comma separated list of months to backfill (YYYY-MM)")
+	statePath := flag.String("state", "backfill-state.json", "file recording completed threads so the run can be resumed")
+	flag.Parse()
+
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		log.Fatalf("failed to create logger: %v", err)
+	}
+	defer func() {
+		if err := logger.Sync(); err != nil {
+			log.Printf("failed to sync logger: %v", err)
+		}
+	}()
+
+	windows, err := parseWindows(*from, *to, *months)
+	if err != nil {
+		logger.Fatal("invalid backfill range", zap.Error(err))
+	}
+
+	state, err := loadState(*statePath)
+	if err != nil {
+		logger.Fatal("failed to load backfill state", zap.String("path", *statePath), zap.Error(err))
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Fatal("failed to load config", zap.Error(err))
+	}
+
+	redisCache := redis.New(cache.Options{
+		RedisURL:      cfg.RedisAddr,
+		RedisPassword: cfg.RedisPassword,
+		RedisDB:       cfg.RedisDB,
+		DefaultTTL:    cfg.CacheTTL,
+	})
+	defer func() {
+		if err := redisCache.Close(); err != nil {
+			logger.Warn("failed to close cache", zap.Error(err))
+		}
+	}()
+
+	limiters, err := api.NewLimiters(cfg, redisCache.Client())
+	if err != nil {
+		logger.Fatal("failed to create rate limiters", zap.Error(err))
+	}
+
+	hnClient := api.NewJobSourceClient(logger, cfg, redisCache, limiters)
+
+	var itemArchive *archive.Archive
+	if cfg.ArchiveDir != "" {
+		itemArchive, err = archive.New(cfg.ArchiveDir, cfg.ArchiveMaxSegmentBytes, logger)
+		if err != nil {
+			logger.Fatal("failed to open item archive", zap.Error(err))
+		}
+		defer func() {
+			if err := itemArchive.Close(); err != nil {
+				logger.Warn("failed to close item archive", zap.Error(err))
+			}
+		}()
+	}
+
+	publisher, err := messaging.New(logger, cfg)
+	if err != nil {
+		logger.Fatal("failed to create publisher", zap.Error(err))
+	}
+	defer publisher.Close()
+
+	runState := scheduler.NewRunState(cfg, redisCache, logger)
+	jobScheduler := scheduler.NewJobScheduler(hnClient, publisher, runState.Cache, runState.Threads, sources.NewRegistry(), itemArchive, nil, logger, cfg)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	logger.Info("starting backfill",
+		zap.Int("windows", len(windows)),
+		zap.Int("already_completed", len(state.CompletedStories)),
+		zap.String("state", *statePath))
+
+	b := &backfiller{
+		source:    hnClient,
+		processor: jobScheduler,
+		state:     state,
+		logger:    logger,
+	}
+	// Threads a dry or JSONL run went through are still to be published to
+	// NATS, so only runs sharing state record them.
+	if cfg.SharesState() {
+		b.save = func(state *backfillState) error { return saveState(*statePath, state) }
+	}
+
+	var totals scheduler.RunStats
+	for i, w := range windows {
+		stats, err := b.backfillWindow(ctx, w)
+		totals.HiringThreadsFound += stats.HiringThreadsFound
+		totals.CandidateThreadsFound += stats.CandidateThreadsFound
+		totals.FreelanceThreadsFound += stats.FreelanceThreadsFound
+		totals.CommentsProcessed += stats.CommentsProcessed
+		if err != nil {
+			logger.Fatal("backfill interrupted, rerun with the same state file to resume",
+				zap.String("window", w.label),
+				zap.Error(err))
+		}
+
+		logger.Info("backfill progress",
+			zap.String("window", w.label),
+			zap.String("progress", fmt.Sprintf("%d/%d", i+1, len(windows))),
+			zap.Int("hiring_threads_found", totals.HiringThreadsFound),
+			zap.Int("candidate_threads_found", totals.CandidateThreadsFound),
+			zap.Int("freelance_threads_found", totals.FreelanceThreadsFound),
+			zap.Int("comments_processed", totals.CommentsProcessed))
+	}
+
+	logger.Info("backfill complete",
+		zap.Int("windows", len(windows)),
+		zap.Int("hiring_threads_found", totals.HiringThreadsFound),
+		zap.Int("candidate_threads_found", totals.CandidateThreadsFound),
+		zap.Int("freelance_threads_found", totals.FreelanceThreadsFound),
+		zap.Int("comments_processed", totals.CommentsProcessed))
+}
+
+// backfillWindow processes the threads posted in w one at a time and returns
+// the stats of the completed ones. A thread with failed stories or comments
+// is left out of the state, so the next run retries it.
+func (b *backfiller) backfillWindow(ctx context.Context, w window) (scheduler.RunStats, error) {
+	var totals scheduler.RunStats
+
+	stories, err := b.source.SearchHiringThreadsBetween(ctx, w.from, w.to)
+	if err != nil {
+		return totals, fmt.Errorf("searching hiring threads: %w", err)
+	}
+	if len(stories) == 0 {
+		b.logger.Warn("no hiring threads found", zap.String("window", w.label))
+	}
+
+	for _, id := range stories {
+		if _, done := b.state.CompletedStories[id]; done {
+			b.logger.Info("skipping completed thread",
+				zap.String("window", w.label),
+				zap.Int("story_id", id))
+			continue
+		}
+
+		stats, err := b.processor.ProcessStories(ctx, models.IntSlice{id})
+		if err != nil {
+			return totals, fmt.Errorf("processing thread %d: %w", id, err)
+		}
+
+		if stats.StoriesFailed > 0 || stats.CommentsFailed > 0 {
+			b.logger.Warn("thread not fully backfilled, it will be retried on the next run",
+				zap.String("window", w.label),
+				zap.Int("story_id", id),
+				zap.Int("stories_failed", stats.StoriesFailed),
+				zap.Int("comments_failed", stats.CommentsFailed))
+			continue
+		}
+
+		b.state.CompletedStories[id] = time.Now()
+		if b.save != nil {
+			if err := b.save(b.state); err != nil {
+				return totals, fmt.Errorf("saving backfill state: %w", err)
+			}
+		}
+
+		totals.HiringThreadsFound += stats.HiringThreadsFound
+		totals.CandidateThreadsFound += stats.CandidateThreadsFound
+		totals.FreelanceThreadsFound += stats.FreelanceThreadsFound
+		totals.CommentsProcessed += stats.CommentsProcessed
+		b.logger.Info("backfilled thread",
+			zap.String("window", w.label),
+			zap.Int("story_id", id),
+			zap.Int("hiring_threads_found", stats.HiringThreadsFound),
+			zap.Int("candidate_threads_found", stats.CandidateThreadsFound),
+			zap.Int("freelance_threads_found", stats.FreelanceThreadsFound),
+			zap.Int("comments_processed", stats.CommentsProcessed),
+			zap.Int("comments_skipped", stats.CommentsSkipped),
+			zap.Int("comments_removed", stats.CommentsRemoved),
+			zap.Int("comments_failed", stats.CommentsFailed))
+	}
+	return totals, nil
+}
+
+func parseWindows(from, to, months string) ([]window, error) {
+	if months != "" {
+		if from != "" || to != "" {
+			return nil, fmt.Errorf("-months cannot be combined with -from/-to")
+		}
+		return parseMonths(months)
+	}
+
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("either -months or both -from and -to are required")
+	}
+
+	start, _, err := parseBound(from)
+	if err != nil {
+		return nil, fmt.Errorf("parsing -from: %w", err)
+	}
+	_, end, err := parseBound(to)
+	if err != nil {
+		return nil, fmt.Errorf("parsing -to: %w", err)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("-to must not be before -from")
+	}
+
+	var windows []window
+	for month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); month.Before(end); month = month.AddDate(0, 1, 0) {
+		w := window{label: month.Format(monthLayout), from: month, to: month.AddDate(0, 1, 0)}
+		if w.from.Before(start) {
+			w.from = start
+		}
+		if w.to.After(end) {
+			w.to = end
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func parseMonths(months string) ([]window, error) {
+	seen := make(map[string]bool)
+	var windows []window
+	for _, value := range strings.Split(months, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[value] {
+			continue
+		}
+		month, err := time.Parse(monthLayout, value)
+		if err != nil {
+			return nil, fmt.Errorf("parsing month %q: %w", value, err)
+		}
+		seen[value] = true
+		windows = append(windows, window{label: value, from: month, to: month.AddDate(0, 1, 0)})
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no months given")
+	}
+
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].from.Before(windows[j].from)
+	})
+	return windows, nil
+}
+
+// parseBound returns the start and the exclusive end of the period named by
+// value, which is either a whole month or a single day.
+func parseBound(value string) (time.Time, time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, t.AddDate(0, 0, 1), nil
+	}
+	t, err := time.Parse(monthLayout, value)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return t, t.AddDate(0, 1, 0), nil
+}
+
+func loadState(path string) (*backfillState, error) {
+	state := &backfillState{CompletedStories: make(map[int]time.Time)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	if state.CompletedStories == nil {
+		state.CompletedStories = make(map[int]time.Time)
+	}
+	return state, nil
+}
+
+func saveState(path string, state *backfillState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"shenanigigs/ingestion/internal/models"
+	"shenanigigs/ingestion/internal/scheduler"
+
+	"go.uber.org/zap"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		months   string
+		want     []window
+		wantFail bool
+	}{
+		{
+			name: "month range",
+			from: "2025-11",
+			to:   "2026-01",
+			want: []window{
+				{"2025-11", date(2025, 11, 1), date(2025, 12, 1)},
+				{"2025-12", date(2025, 12, 1), date(2026, 1, 1)},
+				{"2026-01", date(2026, 1, 1), date(2026, 2, 1)},
+			},
+		},
+		{
+			name: "day range clipped to the days",
+			from: "2026-01-15",
+			to:   "2026-02-02",
+			want: []window{
+				{"2026-01", date(2026, 1, 15), date(2026, 2, 1)},
+				{"2026-02", date(2026, 2, 1), date(2026, 2, 3)},
+			},
+		},
+		{
+			name:   "months sorted without duplicates",
+			months: "2026-03, 2025-06,2026-03,",
+			want: []window{
+				{"2025-06", date(2025, 6, 1), date(2025, 7, 1)},
+				{"2026-03", date(2026, 3, 1), date(2026, 4, 1)},
+			},
+		},
+		{name: "nothing given", wantFail: true},
+		{name: "only from", from: "2026-01", wantFail: true},
+		{name: "months and range", from: "2026-01", to: "2026-02", months: "2026-01", wantFail: true},
+		{name: "to before from", from: "2026-02", to: "2026-01", wantFail: true},
+		{name: "invalid month", months: "2026-13", wantFail: true},
+		{name: "empty months", months: ",", wantFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWindows(tt.from, tt.to, tt.months)
+			if tt.wantFail {
+				if err == nil {
+					t.Fatalf("parseWindows() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseWindows() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseWindows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeSearcher struct {
+	stories models.IntSlice
+	windows []window
+}
+
+func (s *fakeSearcher) SearchHiringThreadsBetween(ctx context.Context, from, to time.Time) (models.IntSlice, error) {
+	s.windows = append(s.windows, window{from: from, to: to})
+	return s.stories, nil
+}
+
+// fakeProcessor records the stories it processes and fails the comments
+// of those in failing.
+type fakeProcessor struct {
+	processed []int
+	failing   map[int]bool
+}
+
+func (p *fakeProcessor) ProcessStories(ctx context.Context, stories models.IntSlice) (scheduler.RunStats, error) {
+	p.processed = append(p.processed, stories...)
+	stats := scheduler.RunStats{HiringThreadsFound: len(stories), CommentsProcessed: 10 * len(stories)}
+	for _, id := range stories {
+		if p.failing[id] {
+			stats.CommentsFailed++
+		}
+	}
+	return stats, nil
+}
+
+// newTestBackfiller returns a backfiller saving its state to a file in a
+// temporary directory, with the path of that file.
+func newTestBackfiller(t *testing.T, source threadSearcher, processor storyProcessor) (*backfiller, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "backfill-state.json")
+	state, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() error = %v", err)
+	}
+	return &backfiller{
+		source:    source,
+		processor: processor,
+		state:     state,
+		save:      func(state *backfillState) error { return saveState(path, state) },
+		logger:    zap.NewNop(),
+	}, path
+}
+
+func completed(t *testing.T, path string) []int {
+	t.Helper()
+	state, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() error = %v", err)
+	}
+	var ids []int
+	for id := range state.CompletedStories {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestBackfillWindowSkipsCompletedThreads(t *testing.T) {
+	source := &fakeSearcher{stories: models.IntSlice{300, 200, 100}}
+	processor := &fakeProcessor{}
+	b, path := newTestBackfiller(t, source, processor)
+	b.state.CompletedStories[200] = time.Now()
+
+	w := window{label: "2026-01", from: date(2026, 1, 15), to: date(2026, 2, 1)}
+	stats, err := b.backfillWindow(context.Background(), w)
+	if err != nil {
+		t.Fatalf("backfillWindow() error = %v", err)
+	}
+
+	if len(source.windows) != 1 || !source.windows[0].from.Equal(w.from) || !source.windows[0].to.Equal(w.to) {
+		t.Errorf("searched %v, want only the window", source.windows)
+	}
+	if !slices.Equal(processor.processed, []int{300, 100}) {
+		t.Errorf("processed %v, want the threads not completed yet", processor.processed)
+	}
+	if stats.HiringThreadsFound != 2 || stats.CommentsProcessed != 20 {
+		t.Errorf("stats = %+v, want the two processed threads", stats)
+	}
+	// The thread completed before was only in memory.
+	if got := completed(t, path); !slices.Equal(got, []int{100, 200, 300}) {
+		t.Errorf("saved completed threads %v", got)
+	}
+}
+
+func TestBackfillWindowRetriesFailedThreads(t *testing.T) {
+	source := &fakeSearcher{stories: models.IntSlice{300, 200}}
+	processor := &fakeProcessor{failing: map[int]bool{200: true}}
+	b, path := newTestBackfiller(t, source, processor)
+	w := window{label: "2026-01", from: date(2026, 1, 1), to: date(2026, 2, 1)}
+
+	stats, err := b.backfillWindow(context.Background(), w)
+	if err != nil {
+		t.Fatalf("backfillWindow() error = %v", err)
+	}
+	if stats.HiringThreadsFound != 1 {
+		t.Errorf("stats = %+v, want only the completed thread counted", stats)
+	}
+	if got := completed(t, path); !slices.Equal(got, []int{300}) {
+		t.Fatalf("saved completed threads %v, want the failed thread left out", got)
+	}
+
+	// A resumed run picks up the failed thread only.
+	resumed, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() error = %v", err)
+	}
+	b.state = resumed
+	processor.processed = nil
+	processor.failing = nil
+	if _, err := b.backfillWindow(context.Background(), w); err != nil {
+		t.Fatalf("resumed backfillWindow() error = %v", err)
+	}
+	if !slices.Equal(processor.processed, []int{200}) {
+		t.Errorf("resumed run processed %v, want the failed thread", processor.processed)
+	}
+	if got := completed(t, path); !slices.Equal(got, []int{200, 300}) {
+		t.Errorf("saved completed threads %v after the retry", got)
+	}
+}
+
+func TestBackfillWindowWithoutSaving(t *testing.T) {
+	source := &fakeSearcher{stories: models.IntSlice{100}}
+	b, path := newTestBackfiller(t, source, &fakeProcessor{})
+	// A dry or JSONL run.
+	b.save = nil
+
+	if _, err := b.backfillWindow(context.Background(), window{label: "2026-01"}); err != nil {
+		t.Fatalf("backfillWindow() error = %v", err)
+	}
+	if _, done := b.state.CompletedStories[100]; !done {
+		t.Error("thread not recorded as completed for the rest of the run")
+	}
+	if got := completed(t, path); len(got) != 0 {
+		t.Errorf("saved completed threads %v without a save function", got)
+	}
+}
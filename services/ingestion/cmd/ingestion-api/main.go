@@ -2,30 +2,255 @@ package main
 
 import (
 	"context"
+	stderrors "errors"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"shenanigigs/common/cache"
+	"shenanigigs/common/cache/redis"
+	"shenanigigs/common/database"
+	"shenanigigs/ingestion/internal/api"
+	"shenanigigs/ingestion/internal/archive"
+	"shenanigigs/ingestion/internal/config"
+	"shenanigigs/ingestion/internal/controlapi"
+	"shenanigigs/ingestion/internal/leader"
+	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/runstore"
+	"shenanigigs/ingestion/internal/scheduler"
+	"shenanigigs/ingestion/internal/sources"
+	"shenanigigs/ingestion/internal/workqueue"
 
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+// shutdownTimeout bounds the whole stop sequence; the scheduler drain gets
+// cfg.ShutdownTimeout of it.
+const shutdownTimeout = 45 * time.Second
+
+func newLogger(cfg *config.Config) (*zap.Logger, error) {
+	return zap.NewProduction()
+}
+
+func newCache(cfg *config.Config, lc fx.Lifecycle) *redis.Cache {
+	redisCache := redis.New(cache.Options{
+		RedisURL:      cfg.RedisAddr,
+		RedisPassword: cfg.RedisPassword,
+		RedisDB:       cfg.RedisDB,
+		DefaultTTL:    cfg.CacheTTL,
+	})
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return redisCache.Close()
+		},
+	})
+	return redisCache
+}
+
+func newLimiters(cfg *config.Config, redisCache *redis.Cache) (api.Limiters, error) {
+	return api.NewLimiters(cfg, redisCache.Client())
+}
+
+func newJobSourceClient(cfg *config.Config, logger *zap.Logger, redisCache *redis.Cache, limiters api.Limiters) api.JobSourceClient {
+	return api.NewJobSourceClient(logger, cfg, redisCache, limiters)
+}
+
+func newPublisher(cfg *config.Config, logger *zap.Logger, lc fx.Lifecycle) (messaging.Publisher, error) {
+	publisher, err := messaging.New(logger, cfg)
+	if err != nil {
+		return nil, err
+	}
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			publisher.Close()
+			return nil
+		},
+	})
+	return publisher, nil
+}
+
+func newArchive(cfg *config.Config, logger *zap.Logger, lc fx.Lifecycle) (*archive.Archive, error) {
+	if cfg.ArchiveDir == "" {
+		return nil, nil
+	}
+	itemArchive, err := archive.New(cfg.ArchiveDir, cfg.ArchiveMaxSegmentBytes, logger)
+	if err != nil {
+		return nil, err
+	}
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return itemArchive.Close()
+		},
+	})
+	return itemArchive, nil
+}
+
+// newRunStore connects to ClickHouse for the run history. The history is
+// disabled when no DSN is configured.
+func newRunStore(cfg *config.Config, logger *zap.Logger, lc fx.Lifecycle) (*runstore.Store, error) {
+	if cfg.ClickHouseDSN == "" {
+		return nil, nil
+	}
+	db, err := database.New(context.Background(), database.Options{
+		DSN:             cfg.ClickHouseDSN,
+		MaxOpenConns:    cfg.ClickHouseMaxOpenConns,
+		MaxIdleConns:    cfg.ClickHouseMaxIdleConns,
+		ConnMaxLifetime: cfg.ClickHouseConnMaxLife,
+		Username:        cfg.ClickHouseUsername,
+		Password:        cfg.ClickHousePassword,
+		Database:        cfg.ClickHouseDatabase,
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return db.Close()
+		},
+	})
+	return runstore.New(db.Conn(), logger), nil
+}
+
+func newRegistry(cfg *config.Config, logger *zap.Logger, state scheduler.RunState) *sources.Registry {
+	return sources.NewDefaultRegistry(cfg, state.Cache, logger)
+}
+
+func newJobScheduler(cfg *config.Config, logger *zap.Logger, hnClient api.JobSourceClient, publisher messaging.Publisher, redisCache *redis.Cache, state scheduler.RunState, registry *sources.Registry, itemArchive *archive.Archive, runStore *runstore.Store) (*scheduler.JobScheduler, error) {
+	var history scheduler.RunRecorder
+	if runStore != nil {
+		history = runStore
+	}
+	jobScheduler := scheduler.NewJobScheduler(hnClient, publisher, state.Cache, state.Threads, registry, itemArchive, history, logger, cfg)
+
+	commentQueue, err := workqueue.New(context.Background(), cfg, redisCache.Client(), logger)
+	if err != nil {
+		return nil, err
+	}
+	if commentQueue != nil {
+		jobScheduler.SetCommentQueue(commentQueue)
+	}
+	return jobScheduler, nil
+}
+
+// registerControlPlane runs the scheduler and serves the control API next
+// to it. Scheduled and on-demand runs share one context, cancelled on stop
+// before the scheduler is drained.
+func registerControlPlane(lc fx.Lifecycle, cfg *config.Config, logger *zap.Logger, jobScheduler *scheduler.JobScheduler, runStore *runstore.Store, redisCache *redis.Cache) {
+	var history controlapi.History
+	if runStore != nil {
+		history = runStore
+	}
+
+	var elector *leader.Elector
+	var leadership controlapi.Leadership
+	if cfg.LeaderElection {
+		elector = leader.New(redisCache.Client(), cfg.LeaderKey, cfg.InstanceID, cfg.LeaderLease, logger)
+		leadership = elector
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	server := &http.Server{
+		Addr:              cfg.APIAddr,
+		Handler:           controlapi.NewServer(runCtx, jobScheduler, history, leadership, cfg.APIToken, logger),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", cfg.APIAddr)
+			if err != nil {
+				cancel()
+				return err
+			}
+
+			workers.Add(2)
+			go func() {
+				defer workers.Done()
+				runScheduler(runCtx, logger, jobScheduler, elector)
+			}()
+			go func() {
+				defer workers.Done()
+				if err := jobScheduler.ConsumeComments(runCtx, cfg.InstanceID, cfg.CommentWorkers); err != nil && runCtx.Err() == nil {
+					logger.Error("comment consumers failed", zap.Error(err))
+				}
+			}()
+			go func() {
+				if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+					logger.Error("control API failed", zap.Error(err))
+				}
+			}()
+
+			logger.Info("ingestion API started", zap.String("addr", cfg.APIAddr))
+			return nil
+		},
+		// The scheduler is drained before the connections it uses are
+		// closed by the hooks registered earlier, which fx stops later.
+		OnStop: func(ctx context.Context) error {
+			err := server.Shutdown(ctx)
+
+			drainCtx, cancelDrain := context.WithTimeout(ctx, cfg.ShutdownTimeout)
+			defer cancelDrain()
+			jobScheduler.Stop(drainCtx)
+
+			cancel()
+			workers.Wait()
+			return err
+		},
+	})
+}
+
+// runScheduler polls job sources until ctx is cancelled. With leader
+// election enabled only the elected replica polls; the control API keeps
+// serving on every replica.
+func runScheduler(ctx context.Context, logger *zap.Logger, jobScheduler *scheduler.JobScheduler, elector *leader.Elector) {
+	run := func(ctx context.Context) {
+		if err := jobScheduler.Start(ctx); err != nil && ctx.Err() == nil && !stderrors.Is(err, scheduler.ErrStopping) {
+			logger.Error("job scheduler failed", zap.Error(err))
+		}
+	}
+	if elector == nil {
+		run(ctx)
+		return
+	}
+	elector.Run(ctx, run)
+}
+
 func main() {
 	app := fx.New(
 		fx.Provide(
-			func() (*zap.Logger, error) {
-				return zap.NewProduction()
-			},
+			config.LoadConfig,
+			newLogger,
+			newCache,
+			scheduler.NewRunState,
+			newLimiters,
+			newJobSourceClient,
+			newPublisher,
+			newArchive,
+			newRunStore,
+			newRegistry,
+			newJobScheduler,
 		),
-
-		fx.Invoke(func(*zap.Logger) {}),
+		fx.Invoke(registerControlPlane),
 	)
 
 	if err := app.Start(context.Background()); err != nil {
 		log.Fatal(err)
 	}
 
-	<-app.Done()
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	<-c
 
-	if err := app.Stop(context.Background()); err != nil {
+	stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := app.Stop(stopCtx); err != nil {
 		log.Fatal(err)
 	}
 }
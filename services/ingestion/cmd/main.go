@@ -2,21 +2,44 @@ package main
 
 import (
 	"context"
+	stderrors "errors"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
-	"time"
 
+	"shenanigigs/common/cache"
+	"shenanigigs/common/cache/redis"
+	commonconfig "shenanigigs/common/config"
 	"shenanigigs/ingestion/internal/api"
+	"shenanigigs/ingestion/internal/archive"
 	"shenanigigs/ingestion/internal/config"
+	"shenanigigs/ingestion/internal/leader"
 	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/models"
 	"shenanigigs/ingestion/internal/scheduler"
+	"shenanigigs/ingestion/internal/sources"
+	"shenanigigs/ingestion/internal/workqueue"
 
 	"go.uber.org/zap"
 )
 
 func main() {
+	once := flag.Bool("once", false, "run a single pass over Hacker News hiring threads and exit")
+	printConfig := flag.Bool("print-config", false, "print the effective config, with secrets redacted, and exit")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	if *printConfig {
+		if err := commonconfig.Print(os.Stdout, cfg); err != nil {
+			log.Fatalf("failed to print config: %v", err)
+		}
+		return
+	}
+
 	logger, err := zap.NewDevelopment()
 	if err != nil {
 		log.Fatalf("failed to create logger: %v", err)
@@ -27,35 +50,89 @@ func main() {
 		}
 	}()
 
-	cfg := &config.Config{
-		HNAPIBaseURL:       "https://hacker-news.firebaseio.com/v0",
-		HNSearchAPIBaseURL: "https://hn.algolia.com/api/v1",
-		HNAPITimeout:       10 * time.Second,
-		PollingInterval:    30 * time.Second,
-	}
-
 	logger.Info("starting ingestion service",
 		zap.String("hn_api_url", cfg.HNAPIBaseURL),
 		zap.Duration("api_timeout", cfg.HNAPITimeout),
-		zap.Duration("polling_interval", cfg.PollingInterval))
+		zap.Duration("polling_interval", cfg.PollingInterval),
+		zap.Strings("sources", cfg.Sources))
+
+	redisCache := redis.New(cache.Options{
+		RedisURL:      cfg.RedisAddr,
+		RedisPassword: cfg.RedisPassword,
+		RedisDB:       cfg.RedisDB,
+		DefaultTTL:    cfg.CacheTTL,
+	})
+
+	limiters, err := api.NewLimiters(cfg, redisCache.Client())
+	if err != nil {
+		logger.Fatal("failed to create rate limiters", zap.Error(err))
+	}
 
-	hnClient := api.NewJobSourceClient(logger, cfg)
+	hnClient := api.NewJobSourceClient(logger, cfg, redisCache, limiters)
 
-	publisher, err := messaging.NewPublisher(logger, cfg)
+	var itemArchive *archive.Archive
+	if cfg.ArchiveDir != "" {
+		itemArchive, err = archive.New(cfg.ArchiveDir, cfg.ArchiveMaxSegmentBytes, logger)
+		if err != nil {
+			logger.Fatal("failed to open item archive", zap.Error(err))
+		}
+	}
+
+	publisher, err := messaging.New(logger, cfg)
 	if err != nil {
-		logger.Fatal("failed to create NATS publisher", zap.Error(err))
+		logger.Fatal("failed to create publisher", zap.Error(err))
+	}
+	closeConnections := func() {
+		publisher.Close()
+		if err := itemArchive.Close(); err != nil {
+			logger.Warn("failed to close item archive", zap.Error(err))
+		}
+		if err := redisCache.Close(); err != nil {
+			logger.Warn("failed to close cache", zap.Error(err))
+		}
 	}
-	defer publisher.Close()
 
-	jobScheduler := scheduler.NewJobScheduler(hnClient, publisher, logger, cfg)
+	state := scheduler.NewRunState(cfg, redisCache, logger)
+	registry := sources.NewDefaultRegistry(cfg, state.Cache, logger)
+	jobScheduler := scheduler.NewJobScheduler(hnClient, publisher, state.Cache, state.Threads, registry, itemArchive, nil, logger, cfg)
+
+	if *once {
+		os.Exit(runOnce(jobScheduler, publisher, logger, closeConnections))
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	var workers sync.WaitGroup
 
-	go func() {
-		if err := jobScheduler.Start(ctx); err != nil {
+	commentQueue, err := workqueue.New(ctx, cfg, redisCache.Client(), logger)
+	if err != nil {
+		logger.Fatal("failed to set up comment queue", zap.Error(err))
+	}
+	if commentQueue != nil {
+		jobScheduler.SetCommentQueue(commentQueue)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			if err := jobScheduler.ConsumeComments(ctx, cfg.InstanceID, cfg.CommentWorkers); err != nil && ctx.Err() == nil {
+				logger.Error("comment consumers failed", zap.Error(err))
+			}
+		}()
+	}
+
+	runScheduler := func(ctx context.Context) {
+		if err := jobScheduler.Start(ctx); err != nil && ctx.Err() == nil && !stderrors.Is(err, scheduler.ErrStopping) {
 			logger.Error("job scheduler failed", zap.Error(err))
 		}
+	}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		if !cfg.LeaderElection {
+			runScheduler(ctx)
+			return
+		}
+		elector := leader.New(redisCache.Client(), cfg.LeaderKey, cfg.InstanceID, cfg.LeaderLease, logger)
+		elector.Run(ctx, runScheduler)
 	}()
 
 	logger.Info("ingestion service started successfully")
@@ -65,6 +142,45 @@ func main() {
 	<-sigCh
 
 	logger.Info("shutting down...")
-	jobScheduler.Stop()
+
+	// Drain the scheduler while its connections are still open, then stop
+	// the elector, which releases its lease through Redis, and close the
+	// connections in reverse order of use.
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	jobScheduler.Stop(drainCtx)
+	cancelDrain()
+	cancel()
+	workers.Wait()
+
+	closeConnections()
 	logger.Info("shutdown complete")
 }
+
+// runOnce runs a single pass over the Hacker News hiring threads, fetching
+// comments in process, and returns the exit code. An interrupted pass leaves
+// a checkpoint that the next one resumes from.
+func runOnce(jobScheduler *scheduler.JobScheduler, publisher messaging.Publisher, logger *zap.Logger, closeConnections func()) int {
+	defer func() {
+		if err := logger.Sync(); err != nil {
+			log.Printf("failed to sync logger: %v", err)
+		}
+	}()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	defer closeConnections()
+
+	status, err := jobScheduler.RunOnce(ctx, models.SourceHackerNews)
+	if ferr := publisher.Flush(context.WithoutCancel(ctx)); ferr != nil {
+		logger.Error("failed to flush publisher", zap.Error(ferr))
+	}
+	logger.Info("single pass finished",
+		zap.String("run_id", status.ID),
+		zap.String("state", status.State),
+		zap.Any("stats", status.Stats),
+		zap.Any("checkpoint", status.Checkpoint))
+	if err != nil {
+		logger.Error("single pass failed", zap.Error(err))
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"shenanigigs/ingestion/internal/archive"
+	"shenanigigs/ingestion/internal/config"
+	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/models"
+	"shenanigigs/ingestion/internal/ratelimit"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	dir := flag.String("dir", "", "archive directory (defaults to ARCHIVE_DIR)")
+	threads := flag.String("threads", "", "comma separated thread IDs to replay (defaults to every archived thread)")
+	rate := flag.Float64("rate", 20, "maximum number of items published per second")
+	flag.Parse()
+
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		log.Fatalf("failed to create logger: %v", err)
+	}
+	defer func() {
+		if err := logger.Sync(); err != nil {
+			log.Printf("failed to sync logger: %v", err)
+		}
+	}()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Fatal("failed to load config", zap.Error(err))
+	}
+	if *dir == "" {
+		*dir = cfg.ArchiveDir
+	}
+	if *dir == "" {
+		logger.Fatal("no archive directory given, set -dir or ARCHIVE_DIR")
+	}
+
+	threadIDs, err := parseThreads(*threads)
+	if err != nil {
+		logger.Fatal("invalid -threads", zap.Error(err))
+	}
+	if len(threadIDs) == 0 {
+		if threadIDs, err = archive.Threads(*dir); err != nil {
+			logger.Fatal("failed to list archived threads", zap.Error(err))
+		}
+	}
+
+	limiter, err := ratelimit.New(ratelimit.BackendLocal, nil, "", *rate, 1)
+	if err != nil {
+		logger.Fatal("failed to create rate limiter", zap.Error(err))
+	}
+
+	publisher, err := messaging.New(logger, cfg)
+	if err != nil {
+		logger.Fatal("failed to create publisher", zap.Error(err))
+	}
+	defer publisher.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	logger.Info("starting replay",
+		zap.String("dir", *dir),
+		zap.Int("threads", len(threadIDs)),
+		zap.Float64("rate", *rate))
+
+	total := 0
+	for i, threadID := range threadIDs {
+		published, err := replayThread(ctx, *dir, threadID, publisher, limiter)
+		total += published
+		if err != nil {
+			logger.Fatal("replay failed",
+				zap.Int("thread_id", threadID),
+				zap.Int("published", total),
+				zap.Error(err))
+		}
+		logger.Info("replayed thread",
+			zap.Int("thread_id", threadID),
+			zap.String("progress", fmt.Sprintf("%d/%d", i+1, len(threadIDs))),
+			zap.Int("published", published))
+	}
+
+	logger.Info("replay complete", zap.Int("threads", len(threadIDs)), zap.Int("published", total))
+}
+
+// replayThread publishes the latest archived version of every top-level
+// comment of the thread. Replies were archived too but are not replayed.
+func replayThread(ctx context.Context, dir string, threadID int, publisher messaging.Publisher, limiter ratelimit.Limiter) (int, error) {
+	var story *models.SourcePost
+	latest := make(map[int]*models.SourcePost)
+	err := archive.ReadThread(dir, threadID, func(record archive.Record) error {
+		switch {
+		case record.Item == nil:
+		case record.Item.ID == threadID:
+			story = record.Item
+		case record.Item.Parent == threadID:
+			latest[record.Item.ID] = record.Item
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	threadType := models.ThreadTypeHiring
+	threadMonth := ""
+	if story != nil {
+		if t, ok := models.ClassifyThread(story); ok {
+			threadType = t
+		}
+		threadMonth = story.ThreadMonth()
+	}
+
+	ids := make([]int, 0, len(latest))
+	for id := range latest {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	published := 0
+	for _, id := range ids {
+		comment := latest[id]
+		if comment.Deleted || comment.Dead {
+			continue
+		}
+		if _, err := limiter.Wait(ctx); err != nil {
+			return published, err
+		}
+
+		month := threadMonth
+		if month == "" {
+			month = comment.ThreadMonth()
+		}
+
+		switch threadType {
+		case models.ThreadTypeCandidates:
+			err = publisher.PublishCandidate(ctx, comment.ToThreadPost(threadID, threadType, month))
+		case models.ThreadTypeFreelance:
+			err = publisher.PublishFreelance(ctx, comment.ToThreadPost(threadID, threadType, month))
+		default:
+			err = publisher.PublishJobPosting(ctx, comment.ToJobPosting(threadID, month))
+		}
+		if err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+func parseThreads(value string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid thread ID %q: %w", part, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
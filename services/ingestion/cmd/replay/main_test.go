@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shenanigigs/ingestion/internal/archive"
+	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/models"
+	"shenanigigs/ingestion/internal/ratelimit"
+
+	"go.uber.org/zap"
+)
+
+// recordingPublisher keeps what it is given. Replay only publishes thread
+// comments, so the other methods are left unimplemented.
+type recordingPublisher struct {
+	messaging.Publisher
+	postings   []*models.JobPosting
+	candidates []*models.ThreadPost
+}
+
+func (p *recordingPublisher) PublishJobPosting(ctx context.Context, posting *models.JobPosting) error {
+	p.postings = append(p.postings, posting)
+	return nil
+}
+
+func (p *recordingPublisher) PublishCandidate(ctx context.Context, post *models.ThreadPost) error {
+	p.candidates = append(p.candidates, post)
+	return nil
+}
+
+// archiveThread writes the items to a new archive in order, as separate
+// runs fetching them would have.
+func archiveThread(t *testing.T, threadID int, runs ...[]*models.SourcePost) string {
+	t.Helper()
+	dir := t.TempDir()
+	a, err := archive.New(dir, 0, zap.NewNop())
+	if err != nil {
+		t.Fatalf("archive.New() error = %v", err)
+	}
+	for _, items := range runs {
+		for _, item := range items {
+			if err := a.Append(threadID, item); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+		}
+		if err := a.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+	return dir
+}
+
+func replay(t *testing.T, dir string, threadID int) *recordingPublisher {
+	t.Helper()
+	limiter, err := ratelimit.New(ratelimit.BackendLocal, nil, "", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publisher := &recordingPublisher{}
+	if _, err := replayThread(context.Background(), dir, threadID, publisher, limiter); err != nil {
+		t.Fatalf("replayThread() error = %v", err)
+	}
+	return publisher
+}
+
+func TestReplayThreadPublishesLatestComments(t *testing.T) {
+	const threadID = 41000000
+	posted := time.Date(2026, 10, 1, 15, 0, 0, 0, time.UTC).Unix()
+	story := &models.SourcePost{ID: threadID, By: "whoishiring", Title: "Ask HN: Who wants to be hired? (October 2026)", Time: posted}
+
+	dir := archiveThread(t, threadID,
+		[]*models.SourcePost{
+			story,
+			{ID: 2, Parent: threadID, By: "bob", Text: "Go developer", Time: posted},
+			{ID: 1, Parent: threadID, By: "alice", Text: "SRE", Time: posted},
+			{ID: 3, Parent: threadID, By: "carol", Text: "Designer", Time: posted},
+		},
+		[]*models.SourcePost{
+			{ID: 1, Parent: threadID, By: "alice", Text: "SRE, open to remote", Time: posted},
+			{ID: 3, Parent: threadID, Deleted: true},
+			{ID: 4, Parent: 1, By: "dave", Text: "Are you in Berlin?", Time: posted},
+		},
+	)
+
+	publisher := replay(t, dir, threadID)
+	if len(publisher.postings) != 0 {
+		t.Errorf("published %d job postings for a candidates thread", len(publisher.postings))
+	}
+	var texts []string
+	for _, post := range publisher.candidates {
+		if post.ThreadMonth != "2026-10" || post.ThreadType != models.ThreadTypeCandidates {
+			t.Errorf("candidate %s published for %s %s", post.ID, post.ThreadType, post.ThreadMonth)
+		}
+		texts = append(texts, post.Text)
+	}
+	// Comments in ID order, at their latest version, without the deleted
+	// one or replies.
+	if len(texts) != 2 || texts[0] != "SRE, open to remote" || texts[1] != "Go developer" {
+		t.Errorf("published %q", texts)
+	}
+}
+
+func TestReplayThreadWithoutStoryPublishesJobPostings(t *testing.T) {
+	const threadID = 41000000
+	posted := time.Date(2026, 9, 30, 23, 0, 0, 0, time.UTC).Unix()
+	dir := archiveThread(t, threadID, []*models.SourcePost{
+		{ID: 1, Parent: threadID, By: "acme", Text: "Acme | Berlin", Time: posted},
+	})
+
+	publisher := replay(t, dir, threadID)
+	if len(publisher.postings) != 1 {
+		t.Fatalf("published %d job postings, want 1", len(publisher.postings))
+	}
+	if got := publisher.postings[0].ThreadMonth; got != "2026-09" {
+		t.Errorf("ThreadMonth = %q, want the comment's month", got)
+	}
+}
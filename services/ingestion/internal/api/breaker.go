@@ -0,0 +1,160 @@
+package api
+
+import (
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"shenanigigs/ingestion/internal/errors"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerOpenTimeout      = 30 * time.Second
+	defaultBreakerHalfOpenMaxCalls = 1
+)
+
+// ErrCircuitOpen is wrapped by the errors returned while a backend's circuit
+// breaker is rejecting calls.
+var ErrCircuitOpen = stderrors.New("circuit breaker is open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker stops calls to a backend after failureThreshold consecutive
+// outage failures. Once openTimeout has passed it lets up to halfOpenMaxCalls
+// probe calls through; a successful probe closes the circuit again and a
+// failed one reopens it.
+type circuitBreaker struct {
+	name             string
+	logger           *zap.Logger
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenMaxCalls int
+	now              func() time.Time
+
+	mutex    sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+func newCircuitBreaker(name string, failureThreshold int, openTimeout time.Duration, halfOpenMaxCalls int, logger *zap.Logger) *circuitBreaker {
+	if failureThreshold <= 0 {
+		failureThreshold = defaultBreakerFailureThreshold
+	}
+	if openTimeout <= 0 {
+		openTimeout = defaultBreakerOpenTimeout
+	}
+	if halfOpenMaxCalls <= 0 {
+		halfOpenMaxCalls = defaultBreakerHalfOpenMaxCalls
+	}
+	return &circuitBreaker{
+		name:             name,
+		logger:           logger,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		halfOpenMaxCalls: halfOpenMaxCalls,
+		now:              time.Now,
+	}
+}
+
+// allow reports whether a call may proceed. Every allowed call must be
+// followed by exactly one call to done.
+func (b *circuitBreaker) allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == breakerOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		b.setState(breakerHalfOpen)
+	}
+
+	switch b.state {
+	case breakerOpen:
+		return errors.Unavailable(fmt.Sprintf("%s unavailable", b.name), ErrCircuitOpen)
+	case breakerHalfOpen:
+		if b.probes >= b.halfOpenMaxCalls {
+			return errors.Unavailable(fmt.Sprintf("%s unavailable", b.name), ErrCircuitOpen)
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// done records the outcome of an allowed call. Only outage errors count as
+// failures; a not-found item or an unparseable response says nothing about
+// the health of the backend.
+func (b *circuitBreaker) done(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == breakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+
+	if err != nil && isRetryable(err) {
+		b.failures++
+		if b.state == breakerHalfOpen || b.failures >= b.failureThreshold {
+			b.openedAt = b.now()
+			b.setState(breakerOpen)
+		}
+		return
+	}
+
+	b.failures = 0
+	if b.state == breakerHalfOpen {
+		b.setState(breakerClosed)
+	}
+}
+
+// release gives back an allowed call whose outcome says nothing about the
+// backend, such as one abandoned because its context was cancelled.
+func (b *circuitBreaker) release() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == breakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+func (b *circuitBreaker) currentState() breakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state
+}
+
+func (b *circuitBreaker) setState(state breakerState) {
+	if b.state == state {
+		return
+	}
+	b.logger.Warn("circuit breaker state changed",
+		zap.String("backend", b.name),
+		zap.String("from", b.state.String()),
+		zap.String("to", state.String()),
+		zap.Int("consecutive_failures", b.failures))
+	b.state = state
+	b.probes = 0
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"shenanigigs/ingestion/internal/errors"
+
+	"go.uber.org/zap"
+)
+
+// newTestBreaker returns a breaker opening after two failures for a minute,
+// on a clock the test moves with the returned function.
+func newTestBreaker(halfOpenMaxCalls int) (*circuitBreaker, func(time.Duration)) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	b := newCircuitBreaker("firebase", 2, time.Minute, halfOpenMaxCalls, zap.NewNop())
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+// call runs one call through b that ends with err, and reports whether it
+// was allowed.
+func call(b *circuitBreaker, err error) bool {
+	if b.allow() != nil {
+		return false
+	}
+	b.done(err)
+	return true
+}
+
+func TestCircuitBreakerCycle(t *testing.T) {
+	b, advance := newTestBreaker(1)
+	outage := errors.Unavailable("unexpected status code: 503", nil)
+
+	if !call(b, outage) || b.currentState() != breakerClosed {
+		t.Fatalf("state after one failure = %v, want closed", b.currentState())
+	}
+	if !call(b, outage) || b.currentState() != breakerOpen {
+		t.Fatalf("state after two failures = %v, want open", b.currentState())
+	}
+
+	err := b.allow()
+	if !stderrors.Is(err, ErrCircuitOpen) || !errors.HasType(err, errors.ErrTypeUnavailable) {
+		t.Fatalf("allow() while open = %v, want an unavailable ErrCircuitOpen", err)
+	}
+	advance(59 * time.Second)
+	if b.allow() == nil {
+		t.Fatal("allow() before the open timeout passed = nil")
+	}
+
+	// After the timeout a single probe goes through; a failed probe
+	// reopens the circuit.
+	advance(time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow() after the open timeout = %v, want a probe", err)
+	}
+	if b.currentState() != breakerHalfOpen {
+		t.Fatalf("state while probing = %v, want half-open", b.currentState())
+	}
+	if b.allow() == nil {
+		t.Fatal("allow() for a second concurrent probe = nil")
+	}
+	b.done(outage)
+	if b.currentState() != breakerOpen {
+		t.Fatalf("state after a failed probe = %v, want open", b.currentState())
+	}
+
+	// A successful probe closes it and resets the failure count.
+	advance(time.Minute)
+	if !call(b, nil) || b.currentState() != breakerClosed {
+		t.Fatalf("state after a successful probe = %v, want closed", b.currentState())
+	}
+	if !call(b, outage) || b.currentState() != breakerClosed {
+		t.Errorf("state after one new failure = %v, want closed", b.currentState())
+	}
+}
+
+func TestCircuitBreakerCountsOnlyOutages(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want breakerState
+	}{
+		{"unavailable", errors.Unavailable("executing request", nil), breakerOpen},
+		{"rate limited", errors.RateLimit("unexpected status code: 429", nil), breakerOpen},
+		{"not found", errors.NotFound("item not found", nil), breakerClosed},
+		{"internal", errors.Internal("decoding response", nil), breakerClosed},
+		{"success", nil, breakerClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := newTestBreaker(1)
+			for i := 0; i < 3; i++ {
+				call(b, tt.err)
+			}
+			if got := b.currentState(); got != tt.want {
+				t.Errorf("state after three calls = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	b, _ := newTestBreaker(1)
+	outage := errors.Unavailable("executing request", nil)
+
+	call(b, outage)
+	call(b, errors.NotFound("item not found", nil))
+	call(b, outage)
+	if got := b.currentState(); got != breakerClosed {
+		t.Errorf("state after failures that were not consecutive = %v, want closed", got)
+	}
+}
+
+func TestCircuitBreakerReleaseFreesProbe(t *testing.T) {
+	b, advance := newTestBreaker(1)
+	outage := errors.Unavailable("executing request", nil)
+	call(b, outage)
+	call(b, outage)
+	advance(time.Minute)
+
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow() after the open timeout = %v, want a probe", err)
+	}
+	// The probe was cancelled, so another may take its place.
+	b.release()
+	if b.currentState() != breakerHalfOpen {
+		t.Fatalf("state after a released probe = %v, want half-open", b.currentState())
+	}
+	if err := b.allow(); err != nil {
+		t.Errorf("allow() after a released probe = %v, want a probe", err)
+	}
+}
@@ -2,14 +2,13 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
 	"shenanigigs/common/cache"
-	"shenanigigs/common/cache/redis"
 	"shenanigigs/common/telemetry"
 	"shenanigigs/ingestion/internal/config"
 	"shenanigigs/ingestion/internal/errors"
@@ -20,17 +19,31 @@ import (
 
 var tracer = telemetry.GetTracer("shenanigigs/ingestion/api")
 
+const (
+	defaultSearchHitsPerPage = 100
+	defaultSearchMaxPages    = 10
+)
+
 type JobSourceClient interface {
 	GetItem(ctx context.Context, id int) (*models.SourcePost, error)
+	// GetFreshItem fetches the item from the API even when it is cached, so
+	// that new replies and edits are seen, and refreshes the cached copy.
+	// changed reports whether the item's kids or time differ from the cached
+	// copy, or it was not cached, so callers can read its children from the
+	// cache when it did not change.
+	GetFreshItem(ctx context.Context, id int) (post *models.SourcePost, changed bool, err error)
 	GetTopStories(ctx context.Context) (models.IntSlice, error)
 	SearchHiringThreads(ctx context.Context) (models.IntSlice, error)
+	SearchHiringThreadsBetween(ctx context.Context, from, to time.Time) (models.IntSlice, error)
 }
 
 type jobSourceClient struct {
-	client *http.Client
-	logger *zap.Logger
-	config *config.Config
-	cache  cache.Cache
+	client   *http.Client
+	logger   *zap.Logger
+	config   *config.Config
+	cache    cache.Cache
+	firebase *backend
+	algolia  *backend
 }
 
 func (c *jobSourceClient) SearchHiringThreads(ctx context.Context) (models.IntSlice, error) {
@@ -40,6 +53,34 @@ func (c *jobSourceClient) SearchHiringThreads(ctx context.Context) (models.IntSl
 	timeThreshold := time.Now().AddDate(0, -6, 0).Unix()
 	span.SetAttributes(telemetry.Int("search.time_threshold", int(timeThreshold)))
 	cacheKey := fmt.Sprintf("hn:search:hiring:%d", timeThreshold)
+	numericFilters := fmt.Sprintf("created_at_i>%d", timeThreshold)
+
+	return c.searchHiringThreads(ctx, cacheKey, numericFilters)
+}
+
+func (c *jobSourceClient) SearchHiringThreadsBetween(ctx context.Context, from, to time.Time) (models.IntSlice, error) {
+	ctx, span := tracer.Start(ctx, "SearchHiringThreadsBetween")
+	defer span.End()
+
+	if !to.After(from) {
+		return nil, errors.InvalidInput(fmt.Sprintf("invalid search window: %s - %s", from, to), nil)
+	}
+
+	span.SetAttributes(
+		telemetry.Int("search.from", int(from.Unix())),
+		telemetry.Int("search.to", int(to.Unix())),
+	)
+	cacheKey := fmt.Sprintf("hn:search:hiring:%d:%d", from.Unix(), to.Unix())
+	numericFilters := fmt.Sprintf("created_at_i>=%d,created_at_i<%d", from.Unix(), to.Unix())
+
+	return c.searchHiringThreads(ctx, cacheKey, numericFilters)
+}
+
+func (c *jobSourceClient) searchHiringThreads(ctx context.Context, cacheKey, numericFilters string) (models.IntSlice, error) {
+	ctx, span := tracer.Start(ctx, "searchHiringThreads")
+	defer span.End()
+
+	span.SetAttributes(telemetry.String("search.numeric_filters", numericFilters))
 
 	var cachedIDs models.IntSlice
 	err := c.cache.Get(ctx, cacheKey, &cachedIDs)
@@ -55,69 +96,70 @@ func (c *jobSourceClient) SearchHiringThreads(ctx context.Context) (models.IntSl
 		span.SetAttributes(telemetry.String("cache.result", "miss"))
 	}
 
-	url := fmt.Sprintf("%s/search?tags=story,author_whoishiring&query=Ask+HN:+Who+is+hiring?&numericFilters=created_at_i>%d",
-		c.config.HNSearchAPIBaseURL,
-		timeThreshold)
-	c.logger.Debug("cache miss, searching for hiring threads", zap.String("url", url))
-	span.SetAttributes(telemetry.String("http.url", url))
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		span.RecordError(err)
-		return nil, errors.Internal("creating request", err)
+	hitsPerPage := c.config.HNSearchHitsPerPage
+	if hitsPerPage <= 0 {
+		hitsPerPage = defaultSearchHitsPerPage
 	}
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		span.RecordError(err)
-		c.logger.Error("failed to execute request", zap.Error(err))
-		return nil, errors.Internal("executing request", err)
+	maxPages := c.config.HNSearchMaxPages
+	if maxPages <= 0 {
+		maxPages = defaultSearchMaxPages
 	}
-	defer func() {
-		if cerr := resp.Body.Close(); cerr != nil {
-			c.logger.Warn("failed to close response body", zap.Error(cerr))
-		}
-	}()
-
 	span.SetAttributes(
-		telemetry.Int("http.status_code", resp.StatusCode),
-		telemetry.String("http.method", http.MethodGet),
+		telemetry.Int("search.hits_per_page", hitsPerPage),
+		telemetry.Int("search.max_pages", maxPages),
 	)
+	c.logger.Debug("cache miss, searching for hiring threads",
+		zap.Int("hits_per_page", hitsPerPage),
+		zap.Int("max_pages", maxPages))
 
-	if resp.StatusCode != http.StatusOK {
-		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
-		return nil, errors.Internal(fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
-	}
+	ids := make(models.IntSlice, 0, hitsPerPage)
+	seen := make(map[int]bool)
+	pagesFetched := 0
+	totalHits := 0
 
-	var searchResult struct {
-		Hits []struct {
-			ObjectID string `json:"objectID"`
-			Title    string `json:"title"`
-			Author   string `json:"author"`
-		} `json:"hits"`
-		NbHits int `json:"nbHits"`
-	}
+	for page := 0; page < maxPages; page++ {
+		result, err := c.searchHiringPage(ctx, numericFilters, page, hitsPerPage)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		pagesFetched++
+		totalHits = result.NbHits
+
+		for _, hit := range result.Hits {
+			id, err := strconv.Atoi(hit.ObjectID)
+			if err != nil {
+				c.logger.Warn("invalid story ID",
+					zap.String("id", hit.ObjectID),
+					zap.String("title", hit.Title),
+					zap.String("author", hit.Author))
+				continue
+			}
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+		}
 
-	if err := json.NewDecoder(resp.Body).Decode(&searchResult); err != nil {
-		c.logger.Error("failed to decode response", zap.Error(err))
-		return nil, errors.Internal("decoding response", err)
+		if len(result.Hits) == 0 || page+1 >= result.NbPages {
+			break
+		}
+		if page+1 >= maxPages {
+			c.logger.Warn("search page limit reached, older hiring threads were not fetched",
+				zap.Int("max_pages", maxPages),
+				zap.Int("total_pages", result.NbPages),
+				zap.Int("total_hits", result.NbHits))
+		}
 	}
 
+	span.SetAttributes(
+		telemetry.Int("search.pages_fetched", pagesFetched),
+		telemetry.Int("search.total_hits", totalHits),
+	)
 	c.logger.Info("search response stats",
-		zap.Int("total_hits", searchResult.NbHits))
-
-	ids := make(models.IntSlice, 0, len(searchResult.Hits))
-	for _, hit := range searchResult.Hits {
-		id, err := strconv.Atoi(hit.ObjectID)
-		if err != nil {
-			c.logger.Warn("invalid story ID",
-				zap.String("id", hit.ObjectID),
-				zap.String("title", hit.Title),
-				zap.String("author", hit.Author))
-			continue
-		}
-		ids = append(ids, id)
-	}
+		zap.Int("total_hits", totalHits),
+		zap.Int("pages_fetched", pagesFetched))
 
 	c.logger.Debug("successfully fetched hiring threads",
 		zap.Int("count", len(ids)))
@@ -129,21 +171,54 @@ func (c *jobSourceClient) SearchHiringThreads(ctx context.Context) (models.IntSl
 	return ids, nil
 }
 
-func NewJobSourceClient(logger *zap.Logger, config *config.Config) JobSourceClient {
-	cacheOpts := cache.Options{
-		RedisURL:      config.RedisAddr,
-		RedisPassword: config.RedisPassword,
-		RedisDB:       config.RedisDB,
-		DefaultTTL:    config.CacheTTL,
+type searchResult struct {
+	Hits []struct {
+		ObjectID string `json:"objectID"`
+		Title    string `json:"title"`
+		Author   string `json:"author"`
+	} `json:"hits"`
+	NbHits      int `json:"nbHits"`
+	NbPages     int `json:"nbPages"`
+	Page        int `json:"page"`
+	HitsPerPage int `json:"hitsPerPage"`
+}
+
+func (c *jobSourceClient) searchHiringPage(ctx context.Context, numericFilters string, page, hitsPerPage int) (*searchResult, error) {
+	ctx, span := tracer.Start(ctx, "searchHiringPage")
+	defer span.End()
+
+	// No query: the hiring, wants-to-be-hired and freelancer threads are all
+	// posted by whoishiring and are told apart by their titles.
+	url := fmt.Sprintf("%s/search?tags=story,author_whoishiring&numericFilters=%s&hitsPerPage=%d&page=%d",
+		c.config.HNSearchAPIBaseURL,
+		numericFilters,
+		hitsPerPage,
+		page)
+	c.logger.Debug("fetching hiring threads search page", zap.String("url", url), zap.Int("page", page))
+	span.SetAttributes(
+		telemetry.String("http.url", url),
+		telemetry.Int("search.page", page),
+	)
+
+	var result searchResult
+	if err := c.getJSON(ctx, c.algolia, url, &result); err != nil {
+		span.RecordError(err)
+		return nil, err
 	}
 
+	return &result, nil
+}
+
+func NewJobSourceClient(logger *zap.Logger, config *config.Config, cache cache.Cache, limits Limiters) JobSourceClient {
 	return &jobSourceClient{
 		client: &http.Client{
 			Timeout: config.HNAPITimeout,
 		},
-		logger: logger,
-		config: config,
-		cache:  redis.New(cacheOpts),
+		logger:   logger,
+		config:   config,
+		cache:    cache,
+		firebase: newBackend("firebase", limits.Firebase, config, logger),
+		algolia:  newBackend("algolia", limits.Algolia, config, logger),
 	}
 }
 
@@ -168,41 +243,52 @@ func (c *jobSourceClient) GetItem(ctx context.Context, id int) (*models.SourcePo
 		span.SetAttributes(telemetry.String("cache.result", "miss"))
 	}
 
-	url := fmt.Sprintf("%s/item/%d.json", c.config.HNAPIBaseURL, id)
-	c.logger.Debug("cache miss, fetching item", zap.Int("id", id), zap.String("url", url))
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	post, err := c.fetchItem(ctx, id)
 	if err != nil {
-		return nil, errors.Internal("creating request", err)
+		span.RecordError(err)
 	}
+	return post, err
+}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		c.logger.Error("failed to execute request", zap.Int("id", id), zap.Error(err))
-		return nil, errors.Internal("executing request", err)
-	}
-	defer func() {
-		if cerr := resp.Body.Close(); cerr != nil {
-			c.logger.Warn("failed to close response body", zap.Error(cerr))
-		}
-	}()
+func (c *jobSourceClient) GetFreshItem(ctx context.Context, id int) (*models.SourcePost, bool, error) {
+	ctx, span := tracer.Start(ctx, "GetFreshItem")
+	defer span.End()
+	span.SetAttributes(
+		telemetry.Int("hn.item.id", id),
+		telemetry.String("cache.result", "bypass"),
+	)
 
-	if resp.StatusCode == http.StatusNotFound {
-		c.logger.Warn("item not found", zap.Int("id", id))
-		return nil, errors.NotFound("item not found", nil)
+	var cachedPost models.SourcePost
+	cacheErr := c.cache.Get(ctx, fmt.Sprintf("hn:item:%d", id), &cachedPost)
+	if cacheErr != nil && cacheErr != cache.ErrNotFound {
+		c.logger.Warn("cache error", zap.Error(cacheErr))
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		c.logger.Error("unexpected status code",
-			zap.Int("id", id),
-			zap.Int("status_code", resp.StatusCode))
-		return nil, errors.Internal(fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
+	post, err := c.fetchItem(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, false, err
 	}
 
+	changed := cacheErr != nil || cachedPost.Time != post.Time || !slices.Equal(cachedPost.Kids, post.Kids)
+	span.SetAttributes(telemetry.Bool("hn.item.changed", changed))
+	return post, changed, nil
+}
+
+// fetchItem fetches an item from the API and caches it.
+func (c *jobSourceClient) fetchItem(ctx context.Context, id int) (*models.SourcePost, error) {
+	cacheKey := fmt.Sprintf("hn:item:%d", id)
+
+	url := fmt.Sprintf("%s/item/%d.json", c.config.HNAPIBaseURL, id)
+	c.logger.Debug("fetching item", zap.Int("id", id), zap.String("url", url))
+
 	var post models.SourcePost
-	if err := json.NewDecoder(resp.Body).Decode(&post); err != nil {
-		c.logger.Error("failed to decode response", zap.Int("id", id), zap.Error(err))
-		return nil, errors.Internal("decoding response", err)
+	if err := c.getJSON(ctx, c.firebase, url, &post); err != nil {
+		if errors.HasType(err, errors.ErrTypeNotFound) {
+			c.logger.Warn("item not found", zap.Int("id", id))
+			return nil, errors.NotFound("item not found", err)
+		}
+		return nil, err
 	}
 
 	c.logger.Debug("successfully fetched item",
@@ -224,31 +310,10 @@ func (c *jobSourceClient) GetTopStories(ctx context.Context) (models.IntSlice, e
 	c.logger.Debug("fetching top stories", zap.String("url", url))
 	span.SetAttributes(telemetry.String("http.url", url))
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, errors.Internal("creating request", err)
-	}
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		c.logger.Error("failed to execute request", zap.Error(err))
-		return nil, errors.Internal("executing request", err)
-	}
-	defer func() {
-		if cerr := resp.Body.Close(); cerr != nil {
-			c.logger.Warn("failed to close response body", zap.Error(cerr))
-		}
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
-		return nil, errors.Internal(fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
-	}
-
 	var ids models.IntSlice
-	if err := json.NewDecoder(resp.Body).Decode(&ids); err != nil {
-		c.logger.Error("failed to decode response", zap.Error(err))
-		return nil, errors.Internal("decoding response", err)
+	if err := c.getJSON(ctx, c.firebase, url, &ids); err != nil {
+		span.RecordError(err)
+		return nil, err
 	}
 
 	c.logger.Debug("successfully fetched top stories", zap.Int("count", len(ids)))
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"shenanigigs/common/cache"
+	"shenanigigs/common/cache/memory"
+	"shenanigigs/ingestion/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// newTestClient returns a client for both HN APIs served by handler,
+// without rate limits and with short retry delays.
+func newTestClient(t *testing.T, handler http.Handler) *jobSourceClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		HNAPIBaseURL:            server.URL,
+		HNSearchAPIBaseURL:      server.URL,
+		HNAPITimeout:            time.Second,
+		CacheTTL:                time.Hour,
+		MaxRetries:              2,
+		RetryDelay:              time.Millisecond,
+		RetryMaxDelay:           10 * time.Millisecond,
+		BreakerFailureThreshold: 100,
+		BreakerOpenTimeout:      time.Minute,
+		BreakerHalfOpenMaxCalls: 1,
+	}
+	limits, err := NewLimiters(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewJobSourceClient(zap.NewNop(), cfg, memory.New(cache.Options{}), limits).(*jobSourceClient)
+}
+
+func TestGetFreshItemReportsChanges(t *testing.T) {
+	ctx := context.Background()
+	var body atomic.Value
+	var requests atomic.Int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(body.Load().(string)))
+	}))
+
+	steps := []struct {
+		name        string
+		body        string
+		wantChanged bool
+	}{
+		{"not cached", `{"id": 1, "type": "story", "time": 100, "kids": [2, 3]}`, true},
+		{"unchanged", `{"id": 1, "type": "story", "time": 100, "kids": [2, 3]}`, false},
+		{"text edited", `{"id": 1, "type": "story", "time": 100, "kids": [2, 3], "text": "edited"}`, false},
+		{"new kid", `{"id": 1, "type": "story", "time": 100, "kids": [4, 2, 3]}`, true},
+		{"time changed", `{"id": 1, "type": "story", "time": 200, "kids": [4, 2, 3]}`, true},
+	}
+	for i, step := range steps {
+		body.Store(step.body)
+		post, changed, err := client.GetFreshItem(ctx, 1)
+		if err != nil {
+			t.Fatalf("%s: GetFreshItem() error = %v", step.name, err)
+		}
+		if changed != step.wantChanged {
+			t.Errorf("%s: GetFreshItem() changed = %v, want %v", step.name, changed, step.wantChanged)
+		}
+		if got := int(requests.Load()); got != i+1 {
+			t.Errorf("%s: %d requests, want %d", step.name, got, i+1)
+		}
+
+		cached, err := client.GetItem(ctx, 1)
+		if err != nil {
+			t.Fatalf("%s: GetItem() error = %v", step.name, err)
+		}
+		if cached.Time != post.Time || len(cached.Kids) != len(post.Kids) {
+			t.Errorf("%s: cached copy %+v not refreshed to %+v", step.name, cached, post)
+		}
+	}
+	if got := int(requests.Load()); got != len(steps) {
+		t.Errorf("GetItem() made %d requests in total, want only the %d fresh fetches", got, len(steps))
+	}
+}
+
+// searchPages serves the given pages of story IDs as Algolia search results
+// and records the pages requested.
+func searchPages(t *testing.T, pages [][]string, requested *[]int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/search" || query.Get("tags") != "story,author_whoishiring" || query.Get("hitsPerPage") != "2" {
+			t.Errorf("unexpected search request %s", r.URL)
+		}
+		page, _ := strconv.Atoi(query.Get("page"))
+		*requested = append(*requested, page)
+
+		result := map[string]any{"nbPages": len(pages), "page": page, "hitsPerPage": 2}
+		hits := []map[string]string{}
+		if page < len(pages) {
+			for _, id := range pages[page] {
+				hits = append(hits, map[string]string{"objectID": id, "author": "whoishiring"})
+			}
+		}
+		result["hits"] = hits
+		json.NewEncoder(w).Encode(result)
+	})
+}
+
+func TestSearchHiringThreadsPaginates(t *testing.T) {
+	tests := []struct {
+		name          string
+		pages         [][]string
+		maxPages      int
+		wantIDs       []int
+		wantRequested []int
+	}{
+		{
+			name:          "single page",
+			pages:         [][]string{{"300", "200"}},
+			maxPages:      10,
+			wantIDs:       []int{300, 200},
+			wantRequested: []int{0},
+		},
+		{
+			// A thread posted while paging shifts the results, so page 1
+			// repeats the last hit of page 0.
+			name:          "duplicates and invalid IDs dropped",
+			pages:         [][]string{{"300", "200"}, {"200", "100"}, {"bogus", "50"}},
+			maxPages:      10,
+			wantIDs:       []int{300, 200, 100, 50},
+			wantRequested: []int{0, 1, 2},
+		},
+		{
+			name:          "stops at max pages",
+			pages:         [][]string{{"500", "400"}, {"300", "200"}, {"100", "50"}},
+			maxPages:      2,
+			wantIDs:       []int{500, 400, 300, 200},
+			wantRequested: []int{0, 1},
+		},
+		{
+			name:          "stops at an empty page",
+			pages:         [][]string{{"300", "200"}, {}, {"100", "50"}},
+			maxPages:      10,
+			wantIDs:       []int{300, 200},
+			wantRequested: []int{0, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested []int
+			client := newTestClient(t, searchPages(t, tt.pages, &requested))
+			client.config.HNSearchHitsPerPage = 2
+			client.config.HNSearchMaxPages = tt.maxPages
+
+			ctx := context.Background()
+			from := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+			ids, err := client.SearchHiringThreadsBetween(ctx, from, from.AddDate(0, 6, 0))
+			if err != nil {
+				t.Fatalf("SearchHiringThreadsBetween() error = %v", err)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("SearchHiringThreadsBetween() = %v, want %v", ids, tt.wantIDs)
+			}
+			if !slices.Equal(requested, tt.wantRequested) {
+				t.Errorf("requested pages %v, want %v", requested, tt.wantRequested)
+			}
+
+			// The result is cached for the same window.
+			requested = nil
+			if _, err := client.SearchHiringThreadsBetween(ctx, from, from.AddDate(0, 6, 0)); err != nil {
+				t.Fatalf("cached SearchHiringThreadsBetween() error = %v", err)
+			}
+			if len(requested) != 0 {
+				t.Errorf("cached search requested pages %v", requested)
+			}
+		})
+	}
+}
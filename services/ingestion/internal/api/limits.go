@@ -0,0 +1,53 @@
+package api
+
+import (
+	"shenanigigs/ingestion/internal/config"
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/ratelimit"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	firebaseLimiterKey = "ratelimit:hn:firebase"
+	algoliaLimiterKey  = "ratelimit:hn:algolia"
+)
+
+// Limiters holds the token buckets for the two HN backends, which publish
+// different usage limits.
+type Limiters struct {
+	Firebase ratelimit.Limiter
+	Algolia  ratelimit.Limiter
+}
+
+// NewLimiters builds the limiters described by config. The client is only
+// used by the redis backend and may be nil otherwise.
+func NewLimiters(config *config.Config, client *redis.Client) (Limiters, error) {
+	firebase, err := ratelimit.New(config.RateLimitBackend, client, firebaseLimiterKey, config.HNAPIRateLimit, config.HNAPIRateBurst)
+	if err != nil {
+		return Limiters{}, errors.InvalidInput("creating firebase rate limiter", err)
+	}
+
+	algolia, err := ratelimit.New(config.RateLimitBackend, client, algoliaLimiterKey, config.HNSearchRateLimit, config.HNSearchRateBurst)
+	if err != nil {
+		return Limiters{}, errors.InvalidInput("creating algolia rate limiter", err)
+	}
+
+	return Limiters{Firebase: firebase, Algolia: algolia}, nil
+}
+
+// backend groups the protections applied to every call made to one HN API.
+type backend struct {
+	name    string
+	limiter ratelimit.Limiter
+	breaker *circuitBreaker
+}
+
+func newBackend(name string, limiter ratelimit.Limiter, config *config.Config, logger *zap.Logger) *backend {
+	return &backend{
+		name:    name,
+		limiter: limiter,
+		breaker: newCircuitBreaker(name, config.BreakerFailureThreshold, config.BreakerOpenTimeout, config.BreakerHalfOpenMaxCalls, logger),
+	}
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shenanigigs/common/telemetry"
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/ratelimit"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRetryDelay    = 500 * time.Millisecond
+	defaultRetryMaxDelay = 2 * time.Minute
+)
+
+// getJSON fetches url from the backend and decodes the JSON body into out.
+// The call fails fast while the backend's circuit breaker is open, and the
+// outcome after all retries is reported back to the breaker.
+func (c *jobSourceClient) getJSON(ctx context.Context, b *backend, url string, out interface{}) error {
+	ctx, span := tracer.Start(ctx, "getJSON")
+	defer span.End()
+	span.SetAttributes(telemetry.String("backend", b.name))
+
+	if err := b.breaker.allow(); err != nil {
+		span.SetAttributes(telemetry.String("breaker.state", breakerOpen.String()))
+		span.RecordError(err)
+		return err
+	}
+
+	err := c.getJSONWithRetry(ctx, b.limiter, url, out)
+	if ctx.Err() != nil {
+		b.breaker.release()
+	} else {
+		b.breaker.done(err)
+	}
+	span.SetAttributes(telemetry.String("breaker.state", b.breaker.currentState().String()))
+	return err
+}
+
+// getJSONWithRetry waits on limiter before every attempt. Requests that fail
+// with errors.Unavailable or errors.RateLimit are retried up to
+// config.MaxRetries times with jittered exponential backoff, waiting at least
+// as long as any Retry-After header asks for.
+func (c *jobSourceClient) getJSONWithRetry(ctx context.Context, limiter ratelimit.Limiter, url string, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.tryGetJSON(ctx, limiter, url, attempt, out)
+		if err == nil {
+			return nil
+		}
+
+		if !isRetryable(err) || attempt >= c.config.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		delay := c.retryDelay(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if delay > c.retryMaxDelay() {
+			c.logger.Warn("server asked to wait longer than the maximum retry delay, giving up",
+				zap.String("url", url),
+				zap.Duration("retry_after", retryAfter))
+			return err
+		}
+
+		c.logger.Warn("request failed, retrying",
+			zap.String("url", url),
+			zap.Int("attempt", attempt+1),
+			zap.Int("max_retries", c.config.MaxRetries),
+			zap.Duration("delay", delay),
+			zap.Error(err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// tryGetJSON performs a single attempt. Alongside any error it returns the
+// delay requested by the server's Retry-After header, if there was one.
+func (c *jobSourceClient) tryGetJSON(ctx context.Context, limiter ratelimit.Limiter, url string, attempt int, out interface{}) (time.Duration, error) {
+	ctx, span := tracer.Start(ctx, "httpGet")
+	defer span.End()
+
+	span.SetAttributes(
+		telemetry.String("http.url", url),
+		telemetry.String("http.method", http.MethodGet),
+		telemetry.Int("http.attempt", attempt),
+	)
+
+	waited, err := limiter.Wait(ctx)
+	span.SetAttributes(telemetry.Int("ratelimit.wait_ms", int(waited.Milliseconds())))
+	if err != nil {
+		span.RecordError(err)
+		if ctx.Err() != nil {
+			return 0, errors.Internal("waiting for rate limiter", err)
+		}
+		return 0, errors.Unavailable("waiting for rate limiter", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		span.RecordError(err)
+		return 0, errors.Internal("creating request", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		c.logger.Error("failed to execute request", zap.String("url", url), zap.Error(err))
+		if ctx.Err() == nil || isTimeout(err) {
+			return 0, errors.Unavailable("executing request", err)
+		}
+		return 0, errors.Internal("executing request", err)
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			c.logger.Warn("failed to close response body", zap.Error(cerr))
+		}
+	}()
+
+	span.SetAttributes(telemetry.Int("http.status_code", resp.StatusCode))
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusNotFound:
+		return 0, errors.NotFound(fmt.Sprintf("not found: %s", url), nil)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		c.logger.Warn("rate limited", zap.String("url", url))
+		return parseRetryAfter(resp.Header.Get("Retry-After")),
+			errors.RateLimit(fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		c.logger.Error("unexpected status code", zap.String("url", url), zap.Int("status_code", resp.StatusCode))
+		return parseRetryAfter(resp.Header.Get("Retry-After")),
+			errors.Unavailable(fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
+	default:
+		c.logger.Error("unexpected status code", zap.String("url", url), zap.Int("status_code", resp.StatusCode))
+		return 0, errors.Internal(fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		span.RecordError(err)
+		c.logger.Error("failed to decode response", zap.String("url", url), zap.Error(err))
+		if isTimeout(err) {
+			return 0, errors.Unavailable("reading response", err)
+		}
+		return 0, errors.Internal("decoding response", err)
+	}
+
+	return 0, nil
+}
+
+func (c *jobSourceClient) retryDelay(attempt int) time.Duration {
+	base := c.config.RetryDelay
+	if base <= 0 {
+		base = defaultRetryDelay
+	}
+
+	delay := base << attempt
+	if delay <= 0 || delay > c.retryMaxDelay() {
+		delay = c.retryMaxDelay()
+	}
+
+	// Equal jitter: half of the delay is fixed, the other half random.
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (c *jobSourceClient) retryMaxDelay() time.Duration {
+	if c.config.RetryMaxDelay <= 0 {
+		return defaultRetryMaxDelay
+	}
+	return c.config.RetryMaxDelay
+}
+
+func isRetryable(err error) bool {
+	return errors.HasType(err, errors.ErrTypeUnavailable) || errors.HasType(err, errors.ErrTypeRateLimit)
+}
+
+func isTimeout(err error) bool {
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return stderrors.As(err, &netErr) && netErr.Timeout()
+}
+
+// parseRetryAfter accepts both forms of the Retry-After header: a number of
+// seconds or an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(at); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"shenanigigs/ingestion/internal/config"
+	"shenanigigs/ingestion/internal/errors"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    time.Duration
+		max     time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{"first attempt", 100 * time.Millisecond, time.Second, 0, 100 * time.Millisecond},
+		{"doubles", 100 * time.Millisecond, time.Second, 2, 400 * time.Millisecond},
+		{"capped", 100 * time.Millisecond, time.Second, 5, time.Second},
+		{"overflow capped", 100 * time.Millisecond, time.Second, 70, time.Second},
+		{"defaults", 0, 0, 1, 2 * defaultRetryDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &jobSourceClient{config: &config.Config{RetryDelay: tt.base, RetryMaxDelay: tt.max}}
+			for i := 0; i < 20; i++ {
+				// Equal jitter keeps the delay between half and all of it.
+				if got := c.retryDelay(tt.attempt); got < tt.want/2 || got > tt.want {
+					t.Fatalf("retryDelay(%d) = %v, want between %v and %v", tt.attempt, got, tt.want/2, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"missing", "", 0, 0},
+		{"seconds", "3", 3 * time.Second, 3 * time.Second},
+		{"negative", "-1", 0, 0},
+		{"garbage", "soon", 0, 0},
+		{"future date", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute},
+		{"past date", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value); got < tt.min || got > tt.max {
+				t.Errorf("parseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+// respond answers the nth request, counting from 0.
+type respond func(w http.ResponseWriter, n int)
+
+func status(code int) respond {
+	return func(w http.ResponseWriter, n int) { w.WriteHeader(code) }
+}
+
+func TestGetJSONRetries(t *testing.T) {
+	ok := func(w http.ResponseWriter, n int) { w.Write([]byte(`{"id": 1}`)) }
+
+	tests := []struct {
+		name         string
+		respond      respond
+		wantType     errors.ErrorType
+		wantRequests int
+	}{
+		{"ok", ok, "", 1},
+		{"not found", status(http.StatusNotFound), errors.ErrTypeNotFound, 1},
+		{"bad request", status(http.StatusBadRequest), errors.ErrTypeInternal, 1},
+		{"bad body", func(w http.ResponseWriter, n int) { w.Write([]byte("<html>")) }, errors.ErrTypeInternal, 1},
+		{"rate limited", status(http.StatusTooManyRequests), errors.ErrTypeRateLimit, 3},
+		{"server error", status(http.StatusBadGateway), errors.ErrTypeUnavailable, 3},
+		{"recovers", func(w http.ResponseWriter, n int) {
+			if n == 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			ok(w, n)
+		}, "", 2},
+		{"retry-after beyond the maximum delay", func(w http.ResponseWriter, n int) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, errors.ErrTypeRateLimit, 1},
+		{"connection dropped", func(w http.ResponseWriter, n int) {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}, errors.ErrTypeUnavailable, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.respond(w, int(requests.Add(1))-1)
+			}))
+
+			var out struct{ ID int }
+			err := c.getJSON(context.Background(), c.firebase, c.config.HNAPIBaseURL+"/item/1.json", &out)
+			if tt.wantType == "" {
+				if err != nil || out.ID != 1 {
+					t.Fatalf("getJSON() = %+v, %v, want the item", out, err)
+				}
+			} else if !errors.HasType(err, tt.wantType) {
+				t.Fatalf("getJSON() error = %v, want type %s", err, tt.wantType)
+			}
+			// The transport may itself retry a dropped connection, so the
+			// requests are not counted then.
+			if got := int(requests.Load()); tt.wantRequests > 0 && got != tt.wantRequests {
+				t.Errorf("%d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestGetJSONWaitsForRetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	c.config.RetryMaxDelay = 5 * time.Second
+
+	start := time.Now()
+	var out struct{ ID int }
+	if err := c.getJSON(context.Background(), c.firebase, c.config.HNAPIBaseURL+"/item/1.json", &out); err != nil {
+		t.Fatalf("getJSON() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least the 1s asked for", elapsed)
+	}
+}
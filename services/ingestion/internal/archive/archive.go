@@ -0,0 +1,232 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxSegmentBytes = 64 << 20
+
+	threadDirPrefix = "thread-"
+	segmentSuffix   = ".jsonl.gz"
+)
+
+// Record is one line of a segment: an item as it was fetched.
+type Record struct {
+	ThreadID  int                `json:"thread_id"`
+	FetchedAt time.Time          `json:"fetched_at"`
+	Item      *models.SourcePost `json:"item"`
+}
+
+// Archive appends every fetched item to gzip-compressed JSONL segments under
+// dir, one directory per thread. A segment stays open until Flush, or until
+// it grows past the size limit, so each run writes a new segment per thread
+// and existing segments are never modified. A nil *Archive discards
+// everything.
+type Archive struct {
+	dir             string
+	maxSegmentBytes int64
+	logger          *zap.Logger
+
+	mutex    sync.Mutex
+	segments map[int]*segment
+}
+
+type segment struct {
+	file    *os.File
+	gz      *gzip.Writer
+	written int64
+}
+
+func New(dir string, maxSegmentBytes int64, logger *zap.Logger) (*Archive, error) {
+	if maxSegmentBytes <= 0 {
+		maxSegmentBytes = defaultMaxSegmentBytes
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating archive directory: %w", err)
+	}
+	return &Archive{
+		dir:             dir,
+		maxSegmentBytes: maxSegmentBytes,
+		logger:          logger,
+		segments:        make(map[int]*segment),
+	}, nil
+}
+
+func (a *Archive) Append(threadID int, item *models.SourcePost) error {
+	if a == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(Record{ThreadID: threadID, FetchedAt: time.Now().UTC(), Item: item})
+	if err != nil {
+		return fmt.Errorf("encoding archive record: %w", err)
+	}
+	line = append(line, '\n')
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	seg, ok := a.segments[threadID]
+	if !ok {
+		if seg, err = a.openSegment(threadID); err != nil {
+			return err
+		}
+		a.segments[threadID] = seg
+	}
+
+	n, err := seg.gz.Write(line)
+	seg.written += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing archive record: %w", err)
+	}
+
+	if seg.written >= a.maxSegmentBytes {
+		delete(a.segments, threadID)
+		return seg.close()
+	}
+	return nil
+}
+
+// Flush closes every open segment, making them complete gzip files.
+func (a *Archive) Flush() error {
+	if a == nil {
+		return nil
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	var firstErr error
+	for threadID, seg := range a.segments {
+		if err := seg.close(); err != nil {
+			a.logger.Error("failed to close archive segment", zap.Int("thread_id", threadID), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		delete(a.segments, threadID)
+	}
+	return firstErr
+}
+
+func (a *Archive) Close() error {
+	return a.Flush()
+}
+
+func (a *Archive) openSegment(threadID int) (*segment, error) {
+	dir := filepath.Join(a.dir, threadDirPrefix+strconv.Itoa(threadID))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating thread directory: %w", err)
+	}
+
+	// Segment names sort in write order.
+	name := fmt.Sprintf("%020d%s", time.Now().UnixNano(), segmentSuffix)
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("creating archive segment: %w", err)
+	}
+	return &segment{file: file, gz: gzip.NewWriter(file)}, nil
+}
+
+func (s *segment) close() error {
+	if err := s.gz.Close(); err != nil {
+		_ = s.file.Close()
+		return fmt.Errorf("finishing archive segment: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		_ = s.file.Close()
+		return fmt.Errorf("syncing archive segment: %w", err)
+	}
+	return s.file.Close()
+}
+
+// Threads lists the IDs of the threads with archived items.
+func Threads(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading archive directory: %w", err)
+	}
+
+	var threads []int
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), threadDirPrefix) {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), threadDirPrefix))
+		if err != nil {
+			continue
+		}
+		threads = append(threads, id)
+	}
+	sort.Ints(threads)
+	return threads, nil
+}
+
+// ReadThread calls fn for every record archived for the thread, oldest
+// segment first. A truncated final segment, left by a crash, ends that
+// segment early instead of failing the read.
+func ReadThread(dir string, threadID int, fn func(Record) error) error {
+	threadDir := filepath.Join(dir, threadDirPrefix+strconv.Itoa(threadID))
+	names, err := filepath.Glob(filepath.Join(threadDir, "*"+segmentSuffix))
+	if err != nil {
+		return fmt.Errorf("listing archive segments: %w", err)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := readSegment(name, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSegment(name string, fn func(Record) error) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("opening archive segment: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading archive segment %s: %w", name, err)
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// A partial last line of an unfinished segment.
+			break
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("reading archive segment %s: %w", name, err)
+	}
+	return nil
+}
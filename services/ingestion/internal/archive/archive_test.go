@@ -0,0 +1,164 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+func newTestArchive(t *testing.T, maxSegmentBytes int64) (*Archive, string) {
+	t.Helper()
+	dir := t.TempDir()
+	a, err := New(dir, maxSegmentBytes, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a, dir
+}
+
+func appendItems(t *testing.T, a *Archive, threadID int, ids ...int) {
+	t.Helper()
+	for _, id := range ids {
+		if err := a.Append(threadID, &models.SourcePost{ID: id, Parent: threadID, Text: "Acme | Berlin"}); err != nil {
+			t.Fatalf("Append(%d) error = %v", id, err)
+		}
+	}
+}
+
+func segments(t *testing.T, dir string, threadID int) []string {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join(dir, threadDirPrefix+strconv.Itoa(threadID), "*"+segmentSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// readIDs returns the item IDs archived for the thread, in read order.
+func readIDs(t *testing.T, dir string, threadID int) []int {
+	t.Helper()
+	var ids []int
+	err := ReadThread(dir, threadID, func(record Record) error {
+		if record.ThreadID != threadID {
+			t.Errorf("record of thread %d read for thread %d", record.ThreadID, threadID)
+		}
+		ids = append(ids, record.Item.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadThread() error = %v", err)
+	}
+	return ids
+}
+
+func TestArchiveRotatesSegments(t *testing.T) {
+	// Every record is larger than a byte, so each one fills a segment.
+	a, dir := newTestArchive(t, 1)
+	appendItems(t, a, 1, 10, 11, 12)
+
+	if got := len(segments(t, dir, 1)); got != 3 {
+		t.Errorf("%d segments, want one per record", got)
+	}
+	if got := readIDs(t, dir, 1); !slices.Equal(got, []int{10, 11, 12}) {
+		t.Errorf("ReadThread() read %v, want the records in write order", got)
+	}
+}
+
+func TestArchiveFlushStartsNewSegment(t *testing.T) {
+	a, dir := newTestArchive(t, 0)
+	appendItems(t, a, 1, 10, 11)
+	appendItems(t, a, 2, 20)
+	if err := a.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	// The next run writes new segments next to the finished ones.
+	appendItems(t, a, 1, 10, 12)
+	if err := a.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if got := len(segments(t, dir, 1)); got != 2 {
+		t.Errorf("%d segments for thread 1, want one per run", got)
+	}
+	if got := readIDs(t, dir, 1); !slices.Equal(got, []int{10, 11, 10, 12}) {
+		t.Errorf("ReadThread(1) read %v", got)
+	}
+	if got := readIDs(t, dir, 2); !slices.Equal(got, []int{20}) {
+		t.Errorf("ReadThread(2) read %v", got)
+	}
+
+	// Anything else in the directory is not a thread.
+	if err := os.Mkdir(filepath.Join(dir, "thread-bogus"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "lost+found"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	threads, err := Threads(dir)
+	if err != nil || !slices.Equal(threads, []int{1, 2}) {
+		t.Errorf("Threads() = %v, %v, want [1 2]", threads, err)
+	}
+}
+
+func TestReadThreadToleratesTruncatedSegment(t *testing.T) {
+	a, dir := newTestArchive(t, 0)
+	appendItems(t, a, 1, 10, 11)
+	if err := a.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	ids := make([]int, 200)
+	for i := range ids {
+		ids[i] = 100 + i
+	}
+	appendItems(t, a, 1, ids...)
+	if err := a.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	// A crash cut the last segment short.
+	names := segments(t, dir, 1)
+	last := names[len(names)-1]
+	info, err := os.Stat(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(last, info.Size()/2); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readIDs(t, dir, 1)
+	if len(got) < 2 || len(got) >= 2+len(ids) {
+		t.Fatalf("ReadThread() read %d records, want the first segment and part of the last", len(got))
+	}
+	if want := append([]int{10, 11}, ids[:len(got)-2]...); !slices.Equal(got, want) {
+		t.Errorf("ReadThread() read %v, want a prefix of what was written", got)
+	}
+}
+
+func TestReadThreadSkipsUnflushedSegment(t *testing.T) {
+	a, dir := newTestArchive(t, 0)
+	appendItems(t, a, 1, 10)
+
+	// The segment still being written holds no complete gzip data yet.
+	if got := readIDs(t, dir, 1); len(got) != 0 {
+		t.Errorf("ReadThread() read %v from an unflushed segment", got)
+	}
+}
+
+func TestNilArchiveDiscards(t *testing.T) {
+	var a *Archive
+	if err := a.Append(1, &models.SourcePost{ID: 10}); err != nil {
+		t.Errorf("Append() on a nil archive = %v", err)
+	}
+	if err := a.Flush(); err != nil {
+		t.Errorf("Flush() on a nil archive = %v", err)
+	}
+}
@@ -1,70 +1,201 @@
 package config
 
 import (
+	"flag"
+	"fmt"
+	"net"
 	"os"
-	"strconv"
 	"time"
+
+	commonconfig "shenanigigs/common/config"
 )
 
 type Config struct {
-	HNAPIBaseURL       string
-	HNSearchAPIBaseURL string
-	HNAPITimeout       time.Duration
+	HNAPIBaseURL       string        `config:"hn_api_base_url" default:"https://hacker-news.firebaseio.com/v0" validate:"required"`
+	HNSearchAPIBaseURL string        `config:"hn_search_api_base_url" default:"https://hn.algolia.com/api/v1" validate:"required"`
+	HNAPITimeout       time.Duration `config:"hn_api_timeout" default:"10s" validate:"min=1ms"`
+
+	HNSearchHitsPerPage int      `config:"hn_search_hits_per_page" default:"100" validate:"min=1,max=1000"`
+	HNSearchMaxPages    int      `config:"hn_search_max_pages" default:"10" validate:"min=1"`
+	HNThreadTypes       []string `config:"hn_thread_types" default:"hiring,candidates,freelance" validate:"required,oneof=hiring|candidates|freelance"`
+	FetchReplies        bool     `config:"fetch_replies" default:"false"`
+	MaxReplyDepth       int      `config:"max_reply_depth" default:"3" validate:"min=0"`
+
+	RateLimitBackend  string  `config:"rate_limit_backend" default:"local" validate:"oneof=local|redis"`
+	HNAPIRateLimit    float64 `config:"hn_api_rate_limit" default:"10"`
+	HNAPIRateBurst    int     `config:"hn_api_rate_burst" default:"10"`
+	HNSearchRateLimit float64 `config:"hn_search_rate_limit" default:"1"`
+	HNSearchRateBurst int     `config:"hn_search_rate_burst" default:"2"`
+
+	PollingInterval time.Duration            `config:"polling_interval" default:"15m" validate:"min=1s"`
+	Sources         []string                 `config:"sources" default:"hackernews" validate:"required"`
+	SourceIntervals map[string]time.Duration `config:"source_intervals"`
+	// Schedules may be cron expressions, which contain commas.
+	SourceSchedules map[string]string `config:"source_schedules" sep:";"`
+
+	AdaptiveMinInterval    time.Duration `config:"adaptive_min_interval" default:"2m" validate:"min=1s"`
+	AdaptiveMaxInterval    time.Duration `config:"adaptive_max_interval" default:"6h" validate:"min=1s"`
+	AdaptiveFreshFor       time.Duration `config:"adaptive_fresh_for" default:"48h"`
+	AdaptiveTargetComments int           `config:"adaptive_target_comments" default:"25" validate:"min=1"`
+	MaxRetries             int           `config:"max_retries" default:"3" validate:"min=0"`
+	RetryDelay             time.Duration `config:"retry_delay" default:"30s" validate:"min=0s"`
+	RetryMaxDelay          time.Duration `config:"retry_max_delay" default:"2m" validate:"min=0s"`
+
+	BreakerFailureThreshold int           `config:"breaker_failure_threshold" default:"5" validate:"min=1"`
+	BreakerOpenTimeout      time.Duration `config:"breaker_open_timeout" default:"30s" validate:"min=1ms"`
+	BreakerHalfOpenMaxCalls int           `config:"breaker_half_open_max_calls" default:"1" validate:"min=1"`
+
+	NATSURL         string        `config:"nats_url" default:"nats://localhost:4222" validate:"required"`
+	NATSConnTimeout time.Duration `config:"nats_conn_timeout" default:"10s" validate:"min=1ms"`
+	// NATSPublishTimeout bounds the wait for the publish ack of a message.
+	NATSPublishTimeout time.Duration `config:"nats_publish_timeout" default:"5s" validate:"min=1ms"`
+
+	JetStreamStream          string        `config:"jetstream_stream" default:"JOBS" validate:"required"`
+	JetStreamMaxAge          time.Duration `config:"jetstream_max_age" default:"168h" validate:"min=1m"`
+	JetStreamReplicas        int           `config:"jetstream_replicas" default:"1" validate:"min=1,max=5"`
+	JetStreamDuplicateWindow time.Duration `config:"jetstream_duplicate_window" default:"2m" validate:"min=1s"`
+
+	PublisherBackend string `config:"publisher_backend" default:"nats" validate:"oneof=nats|jsonl|count|tee"`
+	PublisherOutput  string `config:"publisher_output" default:"-"`
+
+	RedisAddr     string        `config:"redis_addr" default:"localhost:6379" validate:"required"`
+	RedisPassword string        `config:"redis_password" secret:"true"`
+	RedisDB       int           `config:"redis_db" default:"0" validate:"min=0"`
+	CacheTTL      time.Duration `config:"cache_ttl" default:"24h" validate:"min=1s"`
+
+	ThreadStateTTL time.Duration `config:"thread_state_ttl" default:"2160h" validate:"min=1h"`
+
+	CheckpointTTL      time.Duration `config:"checkpoint_ttl" default:"24h" validate:"min=1m"`
+	CheckpointInterval time.Duration `config:"checkpoint_interval" default:"5s" validate:"min=100ms"`
+
+	ArchiveDir             string `config:"archive_dir"`
+	ArchiveMaxSegmentBytes int64  `config:"archive_max_segment_bytes" default:"67108864" validate:"min=1024"`
+
+	FeedURLs    []string      `config:"feed_urls"`
+	FeedTimeout time.Duration `config:"feed_timeout" default:"15s" validate:"min=1ms"`
+	FeedSeenTTL time.Duration `config:"feed_seen_ttl" default:"720h" validate:"min=1s"`
+
+	ATSBoards            []string      `config:"ats_boards"`
+	ATSTimeout           time.Duration `config:"ats_timeout" default:"15s" validate:"min=1ms"`
+	ATSSeenTTL           time.Duration `config:"ats_seen_ttl" default:"720h" validate:"min=1s"`
+	GreenhouseAPIBaseURL string        `config:"greenhouse_api_base_url" default:"https://boards-api.greenhouse.io/v1" validate:"required"`
+	LeverAPIBaseURL      string        `config:"lever_api_base_url" default:"https://api.lever.co/v0" validate:"required"`
+
+	// The control API listens on the loopback interface by default; other
+	// addresses require APIToken. When set, every request but health checks
+	// must carry the token as a bearer token.
+	APIAddr         string        `config:"api_addr" default:"127.0.0.1:8080" validate:"required"`
+	APIToken        string        `config:"api_token" secret:"true"`
+	ShutdownTimeout time.Duration `config:"shutdown_timeout" default:"30s" validate:"min=1s"`
 
-	PollingInterval time.Duration
-	MaxRetries      int
-	RetryDelay      time.Duration
+	WorkQueueBackend     string        `config:"work_queue_backend" default:"memory" validate:"oneof=memory|redis"`
+	WorkQueueStream      string        `config:"work_queue_stream" default:"ingestion:comments" validate:"required"`
+	WorkQueueGroup       string        `config:"work_queue_group" default:"ingestion-workers" validate:"required"`
+	WorkQueueClaimIdle   time.Duration `config:"work_queue_claim_idle" default:"2m" validate:"min=1s"`
+	WorkQueueMaxAttempts int           `config:"work_queue_max_attempts" default:"5" validate:"min=1"`
+	WorkQueueMaxLen      int64         `config:"work_queue_max_len" default:"1000000" validate:"min=0"`
+	CommentWorkers       int           `config:"comment_workers" default:"10" validate:"min=1"`
 
-	NATSURL         string
-	NATSConnTimeout time.Duration
+	// InstanceID defaults to the host name and process ID.
+	InstanceID     string        `config:"instance_id"`
+	LeaderElection bool          `config:"leader_election" default:"false"`
+	LeaderKey      string        `config:"leader_key" default:"ingestion:leader" validate:"required"`
+	LeaderLease    time.Duration `config:"leader_lease" default:"15s" validate:"min=1s"`
 
-	RedisAddr     string
-	RedisPassword string
-	RedisDB       int
-	CacheTTL      time.Duration
+	// PauseKey holds the polling pause flag shared by all replicas. A pause
+	// lapses after PauseTTL, so a forgotten one does not stop ingestion for
+	// good.
+	PauseKey string        `config:"pause_key" default:"ingestion:polling:paused" validate:"required"`
+	PauseTTL time.Duration `config:"pause_ttl" default:"168h" validate:"min=1m"`
+
+	ClickHouseDSN          string        `config:"clickhouse_dsn"`
+	ClickHouseMaxOpenConns int           `config:"clickhouse_max_open_conns" default:"2" validate:"min=1"`
+	ClickHouseMaxIdleConns int           `config:"clickhouse_max_idle_conns" default:"1" validate:"min=0"`
+	ClickHouseConnMaxLife  time.Duration `config:"clickhouse_conn_max_life" default:"1h"`
+	ClickHouseUsername     string        `config:"clickhouse_username" default:"default"`
+	ClickHousePassword     string        `config:"clickhouse_password" secret:"true"`
+	ClickHouseDatabase     string        `config:"clickhouse_database" default:"shenanigigs"`
 }
 
+// LoadConfig reads the config from the file named by CONFIG_FILE, if any,
+// and the environment.
 func LoadConfig() (*Config, error) {
-	config := &Config{
-		HNAPIBaseURL:       getEnvString("HN_API_BASE_URL", "https://hacker-news.firebaseio.com/v0"),
-		HNSearchAPIBaseURL: getEnvString("HN_SEARCH_API_BASE_URL", "https://hn.algolia.com/api/v1"),
-		HNAPITimeout:       getEnvDuration("HN_API_TIMEOUT", 10*time.Second),
-		PollingInterval:    getEnvDuration("POLLING_INTERVAL", 15*time.Minute),
-		MaxRetries:         getEnvInt("MAX_RETRIES", 3),
-		RetryDelay:         getEnvDuration("RETRY_DELAY", 30*time.Second),
-		NATSURL:            getEnvString("NATS_URL", "nats://localhost:4222"),
-		NATSConnTimeout:    getEnvDuration("NATS_CONN_TIMEOUT", 10*time.Second),
-
-		RedisAddr:     getEnvString("REDIS_ADDR", "localhost:6379"),
-		RedisPassword: getEnvString("REDIS_PASSWORD", ""),
-		RedisDB:       getEnvInt("REDIS_DB", 0),
-		CacheTTL:      getEnvDuration("CACHE_TTL", 24*time.Hour),
-	}
+	return Load(nil, nil)
+}
 
+// Load reads the config like LoadConfig, and then from command line flags
+// registered on fs and parsed from args. fs may be nil.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	config := &Config{}
+	if err := commonconfig.Load(config, commonconfig.Options{FlagSet: fs, Args: args}); err != nil {
+		return nil, err
+	}
+	if config.InstanceID == "" {
+		config.InstanceID = defaultInstanceID()
+	}
 	return config, nil
 }
 
-func getEnvString(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// Validate checks the rules that involve more than one field.
+func (c *Config) Validate() error {
+	if c.AdaptiveMinInterval > c.AdaptiveMaxInterval {
+		return fmt.Errorf("adaptive_min_interval %s is above adaptive_max_interval %s", c.AdaptiveMinInterval, c.AdaptiveMaxInterval)
+	}
+	if c.RetryDelay > c.RetryMaxDelay {
+		return fmt.Errorf("retry_delay %s is above retry_max_delay %s", c.RetryDelay, c.RetryMaxDelay)
+	}
+	if c.APIToken == "" && !isLoopback(c.APIAddr) {
+		return fmt.Errorf("api_addr %s is reachable from other hosts, set api_token to protect the control API", c.APIAddr)
+	}
+	return nil
+}
+
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// SourceInterval returns the polling interval for the named job source,
+// falling back to PollingInterval.
+func (c *Config) SourceInterval(name string) time.Duration {
+	if interval, ok := c.SourceIntervals[name]; ok && interval > 0 {
+		return interval
 	}
-	return defaultValue
+	return c.PollingInterval
+}
+
+// SourceSchedule returns the schedule spec for the named job source: a cron
+// expression, "@every <duration>" or "adaptive". An empty spec means the
+// source is polled every SourceInterval.
+func (c *Config) SourceSchedule(name string) string {
+	return c.SourceSchedules[name]
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if value, exists := os.LookupEnv(key); exists {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
+// SharesState reports whether runs keep their state in Redis, shared with
+// every replica and later runs. Only publishers that deliver to NATS do; a
+// dry or JSONL run recording comments as published would make the next NATS
+// run skip them.
+func (c *Config) SharesState() bool {
+	switch c.PublisherBackend {
+	case "", "nats", "tee":
+		return true
+	default:
+		return false
 	}
-	return defaultValue
 }
 
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value, exists := os.LookupEnv(key); exists {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
-		}
+// defaultInstanceID identifies this replica by host name and process ID.
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
 	}
-	return defaultValue
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
 }
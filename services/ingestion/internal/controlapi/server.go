@@ -0,0 +1,314 @@
+package controlapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/models"
+	"shenanigigs/ingestion/internal/runstore"
+	"shenanigigs/ingestion/internal/scheduler"
+
+	"go.uber.org/zap"
+)
+
+const defaultRunLimit = 20
+
+// Scheduler is the part of the job scheduler the control plane drives.
+type Scheduler interface {
+	TriggerRun(ctx context.Context, source string) (scheduler.RunStatus, error)
+	IngestThread(ctx context.Context, threadID int) (scheduler.RunStatus, error)
+	IngestItem(ctx context.Context, itemID int) (scheduler.RunStatus, error)
+	CurrentRuns() []scheduler.RunStatus
+	RecentRuns(limit int) []scheduler.RunStatus
+	Run(id string) (scheduler.RunStatus, bool)
+	Pause(ctx context.Context) error
+	Resume(ctx context.Context) error
+	Paused(ctx context.Context) bool
+}
+
+// Leadership tells whether this replica is the elected leader.
+type Leadership interface {
+	IsLeader() bool
+	Leader() string
+}
+
+// History is the persisted run history.
+type History interface {
+	Runs(ctx context.Context, q runstore.Query) ([]scheduler.RunStatus, error)
+}
+
+// Server exposes the scheduler over HTTP so runs can be forced, inspected
+// and paused without restarting the service. On-demand runs outlive the
+// request that started them and are bound to runCtx instead. With leader
+// election, runs are only started on the leader; other replicas answer 409
+// with the leader's identity. With a token, every request but health checks
+// must carry it as a bearer token.
+type Server struct {
+	scheduler  Scheduler
+	history    History
+	leadership Leadership
+	token      string
+	runCtx     context.Context
+	logger     *zap.Logger
+	mux        *http.ServeMux
+}
+
+type pollingStatus struct {
+	Paused bool `json:"paused"`
+}
+
+type errorResponse struct {
+	Error  string `json:"error"`
+	Leader string `json:"leader,omitempty"`
+}
+
+// NewServer creates the control API. history may be nil when no run history
+// is configured, leadership nil when leader election is disabled, and token
+// empty when the API is only reachable locally.
+func NewServer(runCtx context.Context, scheduler Scheduler, history History, leadership Leadership, token string, logger *zap.Logger) *Server {
+	s := &Server{
+		scheduler:  scheduler,
+		history:    history,
+		leadership: leadership,
+		token:      token,
+		runCtx:     runCtx,
+		logger:     logger,
+		mux:        http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("POST /runs", s.triggerRun)
+	s.mux.HandleFunc("GET /runs", s.recentRuns)
+	s.mux.HandleFunc("GET /runs/current", s.currentRuns)
+	s.mux.HandleFunc("GET /runs/history", s.runHistory)
+	s.mux.HandleFunc("GET /runs/{id}", s.run)
+	s.mux.HandleFunc("POST /threads/{id}", s.ingestThread)
+	s.mux.HandleFunc("POST /items/{id}", s.ingestItem)
+	s.mux.HandleFunc("GET /polling", s.polling)
+	s.mux.HandleFunc("POST /polling/pause", s.pausePolling)
+	s.mux.HandleFunc("POST /polling/resume", s.resumePolling)
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/healthz" && !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		s.writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// triggerRun starts a fetch of ?source=, defaulting to Hacker News.
+func (s *Server) triggerRun(w http.ResponseWriter, r *http.Request) {
+	if !s.leading(w) {
+		return
+	}
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		source = models.SourceHackerNews
+	}
+	status, err := s.scheduler.TriggerRun(s.runCtx, source)
+	s.writeRun(w, status, err)
+}
+
+func (s *Server) ingestThread(w http.ResponseWriter, r *http.Request) {
+	if !s.leading(w) {
+		return
+	}
+	id, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+	status, err := s.scheduler.IngestThread(s.runCtx, id)
+	s.writeRun(w, status, err)
+}
+
+func (s *Server) ingestItem(w http.ResponseWriter, r *http.Request) {
+	if !s.leading(w) {
+		return
+	}
+	id, ok := s.pathID(w, r)
+	if !ok {
+		return
+	}
+	status, err := s.scheduler.IngestItem(s.runCtx, id)
+	s.writeRun(w, status, err)
+}
+
+func (s *Server) currentRuns(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.scheduler.CurrentRuns())
+}
+
+func (s *Server) recentRuns(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRunLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			s.writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = parsed
+	}
+	s.writeJSON(w, http.StatusOK, s.scheduler.RecentRuns(limit))
+}
+
+// runHistory queries persisted runs, filtered by source, thread_id,
+// thread_month and an RFC 3339 since/until window.
+func (s *Server) runHistory(w http.ResponseWriter, r *http.Request) {
+	if s.history == nil {
+		s.writeError(w, http.StatusNotFound, "run history is not configured")
+		return
+	}
+
+	params := r.URL.Query()
+	q := runstore.Query{
+		Source:      params.Get("source"),
+		ThreadMonth: params.Get("thread_month"),
+	}
+	var err error
+	if value := params.Get("thread_id"); value != "" {
+		if q.ThreadID, err = strconv.Atoi(value); err != nil {
+			s.writeError(w, http.StatusBadRequest, "thread_id must be an integer")
+			return
+		}
+	}
+	if value := params.Get("limit"); value != "" {
+		if q.Limit, err = strconv.Atoi(value); err != nil || q.Limit <= 0 {
+			s.writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+	}
+	if value := params.Get("since"); value != "" {
+		if q.Since, err = time.Parse(time.RFC3339, value); err != nil {
+			s.writeError(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp")
+			return
+		}
+	}
+	if value := params.Get("until"); value != "" {
+		if q.Until, err = time.Parse(time.RFC3339, value); err != nil {
+			s.writeError(w, http.StatusBadRequest, "until must be an RFC 3339 timestamp")
+			return
+		}
+	}
+
+	runs, err := s.history.Runs(r.Context(), q)
+	if err != nil {
+		s.logger.Error("failed to query run history", zap.Error(err))
+		s.writeError(w, statusCode(err), "failed to query run history")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, runs)
+}
+
+func (s *Server) run(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.scheduler.Run(r.PathValue("id"))
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "run not found")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) polling(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, pollingStatus{Paused: s.scheduler.Paused(r.Context())})
+}
+
+func (s *Server) pausePolling(w http.ResponseWriter, r *http.Request) {
+	if err := s.scheduler.Pause(r.Context()); err != nil {
+		s.logger.Error("failed to pause polling", zap.Error(err))
+		s.writeError(w, statusCode(err), err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, pollingStatus{Paused: true})
+}
+
+func (s *Server) resumePolling(w http.ResponseWriter, r *http.Request) {
+	if err := s.scheduler.Resume(r.Context()); err != nil {
+		s.logger.Error("failed to resume polling", zap.Error(err))
+		s.writeError(w, statusCode(err), err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, pollingStatus{Paused: false})
+}
+
+// leading reports whether runs may be started on this replica, and answers
+// 409 with the current leader when they may not.
+func (s *Server) leading(w http.ResponseWriter) bool {
+	if s.leadership == nil || s.leadership.IsLeader() {
+		return true
+	}
+	s.writeJSON(w, http.StatusConflict, errorResponse{
+		Error:  "this replica is not the leader",
+		Leader: s.leadership.Leader(),
+	})
+	return false
+}
+
+func (s *Server) pathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		s.writeError(w, http.StatusBadRequest, "id must be a positive integer")
+		return 0, false
+	}
+	return id, true
+}
+
+func (s *Server) writeRun(w http.ResponseWriter, status scheduler.RunStatus, err error) {
+	if err != nil {
+		s.writeError(w, statusCode(err), err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusAccepted, status)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.logger.Warn("failed to write response", zap.Error(err))
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, code int, message string) {
+	s.writeJSON(w, code, errorResponse{Error: message})
+}
+
+func statusCode(err error) int {
+	if stderrors.Is(err, scheduler.ErrRunInProgress) {
+		return http.StatusConflict
+	}
+	if stderrors.Is(err, scheduler.ErrStopping) {
+		return http.StatusServiceUnavailable
+	}
+	var domainErr *errors.DomainError
+	if stderrors.As(err, &domainErr) {
+		switch domainErr.Type {
+		case errors.ErrTypeInvalidInput:
+			return http.StatusBadRequest
+		case errors.ErrTypeNotFound:
+			return http.StatusNotFound
+		case errors.ErrTypeUnavailable:
+			return http.StatusServiceUnavailable
+		}
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,175 @@
+package controlapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/scheduler"
+
+	"go.uber.org/zap"
+)
+
+type fakeScheduler struct {
+	triggered []string
+	paused    bool
+	pauseErr  error
+}
+
+func (f *fakeScheduler) TriggerRun(ctx context.Context, source string) (scheduler.RunStatus, error) {
+	f.triggered = append(f.triggered, source)
+	return scheduler.RunStatus{ID: "run-1", Source: source}, nil
+}
+
+func (f *fakeScheduler) IngestThread(ctx context.Context, threadID int) (scheduler.RunStatus, error) {
+	f.triggered = append(f.triggered, "thread")
+	return scheduler.RunStatus{ID: "run-2"}, nil
+}
+
+func (f *fakeScheduler) IngestItem(ctx context.Context, itemID int) (scheduler.RunStatus, error) {
+	f.triggered = append(f.triggered, "item")
+	return scheduler.RunStatus{ID: "run-3"}, nil
+}
+
+func (f *fakeScheduler) CurrentRuns() []scheduler.RunStatus         { return nil }
+func (f *fakeScheduler) RecentRuns(limit int) []scheduler.RunStatus { return nil }
+func (f *fakeScheduler) Run(id string) (scheduler.RunStatus, bool) {
+	return scheduler.RunStatus{}, false
+}
+
+func (f *fakeScheduler) Pause(ctx context.Context) error {
+	if f.pauseErr != nil {
+		return f.pauseErr
+	}
+	f.paused = true
+	return nil
+}
+
+func (f *fakeScheduler) Resume(ctx context.Context) error {
+	f.paused = false
+	return nil
+}
+
+func (f *fakeScheduler) Paused(ctx context.Context) bool {
+	return f.paused
+}
+
+type fakeLeadership struct {
+	leader string
+	self   string
+}
+
+func (f fakeLeadership) IsLeader() bool { return f.leader == f.self }
+func (f fakeLeadership) Leader() string { return f.leader }
+
+func serve(t *testing.T, server *Server, method, path string) (*httptest.ResponseRecorder, errorResponse) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+	var body errorResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &body)
+	return recorder, body
+}
+
+func TestRunsOnlyStartOnLeader(t *testing.T) {
+	paths := []string{"/runs", "/runs?source=feed", "/threads/41000000", "/items/41000001"}
+
+	tests := []struct {
+		name       string
+		leadership Leadership
+		want       int
+	}{
+		{"no leader election", nil, http.StatusAccepted},
+		{"leader", fakeLeadership{leader: "a", self: "a"}, http.StatusAccepted},
+		{"follower", fakeLeadership{leader: "a", self: "b"}, http.StatusConflict},
+		{"no leader yet", fakeLeadership{leader: "", self: "b"}, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched := &fakeScheduler{}
+			server := NewServer(context.Background(), sched, nil, tt.leadership, "", zap.NewNop())
+
+			for _, path := range paths {
+				recorder, body := serve(t, server, http.MethodPost, path)
+				if recorder.Code != tt.want {
+					t.Errorf("POST %s = %d, want %d", path, recorder.Code, tt.want)
+				}
+				if tt.want == http.StatusConflict && body.Leader != tt.leadership.Leader() {
+					t.Errorf("POST %s leader = %q, want %q", path, body.Leader, tt.leadership.Leader())
+				}
+			}
+
+			wantRuns := len(paths)
+			if tt.want == http.StatusConflict {
+				wantRuns = 0
+			}
+			if len(sched.triggered) != wantRuns {
+				t.Errorf("started %d runs, want %d", len(sched.triggered), wantRuns)
+			}
+		})
+	}
+}
+
+func TestPollingPause(t *testing.T) {
+	sched := &fakeScheduler{}
+	// Pausing is allowed on any replica; the flag is shared.
+	server := NewServer(context.Background(), sched, nil, fakeLeadership{leader: "a", self: "b"}, "", zap.NewNop())
+
+	if recorder, _ := serve(t, server, http.MethodPost, "/polling/pause"); recorder.Code != http.StatusOK {
+		t.Fatalf("POST /polling/pause = %d, want 200", recorder.Code)
+	}
+	if !sched.paused {
+		t.Fatal("scheduler not paused")
+	}
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/polling", nil))
+	var status pollingStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil || !status.Paused {
+		t.Errorf("GET /polling = %s, want paused", recorder.Body.String())
+	}
+
+	if recorder, _ := serve(t, server, http.MethodPost, "/polling/resume"); recorder.Code != http.StatusOK || sched.paused {
+		t.Errorf("POST /polling/resume = %d, paused = %v", recorder.Code, sched.paused)
+	}
+
+	sched.pauseErr = errors.Unavailable("failed to store pause flag", nil)
+	if recorder, _ := serve(t, server, http.MethodPost, "/polling/pause"); recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("POST /polling/pause with the flag store down = %d, want 503", recorder.Code)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	server := NewServer(context.Background(), &fakeScheduler{}, nil, nil, "s3cret", zap.NewNop())
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		want          int
+	}{
+		{"missing", "/polling", "", http.StatusUnauthorized},
+		{"wrong token", "/polling", "Bearer guess", http.StatusUnauthorized},
+		{"wrong scheme", "/polling", "Basic s3cret", http.StatusUnauthorized},
+		{"valid", "/polling", "Bearer s3cret", http.StatusOK},
+		{"health check", "/healthz", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+			if recorder.Code != tt.want {
+				t.Errorf("GET %s = %d, want %d", tt.path, recorder.Code, tt.want)
+			}
+		})
+	}
+}
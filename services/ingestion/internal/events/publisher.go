@@ -20,7 +20,7 @@ const (
 )
 
 type JobPostingEvent struct {
-	ID          int       `json:"id"`
+	ID          string    `json:"id"`
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
 	PostedAt    time.Time `json:"posted_at"`
@@ -76,13 +76,13 @@ func (p *Publisher) PublishJobPosting(ctx context.Context, post *models.JobPosti
 	if err := p.nc.Publish(jobPostingSubject, data); err != nil {
 		span.RecordError(err)
 		p.logger.Error("failed to publish job posting",
-			zap.Int("id", event.ID),
+			zap.String("id", event.ID),
 			zap.Error(err))
 		return errors.Internal("publishing event", err)
 	}
 
 	p.logger.Debug("published job posting event",
-		zap.Int("id", event.ID),
+		zap.String("id", event.ID),
 		zap.String("title", event.Title),
 		zap.Time("posted_at", event.PostedAt))
 
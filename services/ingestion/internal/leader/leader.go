@@ -0,0 +1,251 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"shenanigigs/common/telemetry"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var tracer = telemetry.GetTracer("shenanigigs/ingestion/leader")
+
+// maxLeadBackoff caps the wait before campaigning again after lead keeps
+// returning right away.
+const maxLeadBackoff = 5 * time.Minute
+
+// renewScript extends the lease at KEYS[1] if it is still held by ARGV[1].
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lease at KEYS[1] if it is still held by ARGV[1].
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Elector elects one leader among the replicas sharing a Redis key. The
+// leader holds the key with a lease it renews every third of the lease
+// period; when it stops renewing, the key expires and a standby, which
+// waits out the remaining lease, takes over within one lease period.
+type Elector struct {
+	client *redis.Client
+	key    string
+	id     string
+	lease  time.Duration
+	logger *zap.Logger
+
+	mutex  sync.Mutex
+	leader string
+}
+
+func New(client *redis.Client, key, id string, lease time.Duration, logger *zap.Logger) *Elector {
+	return &Elector{
+		client: client,
+		key:    key,
+		id:     id,
+		lease:  lease,
+		logger: logger.With(zap.String("instance_id", id)),
+	}
+}
+
+// ID is this replica's identity in the election.
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// Leader returns the identity of the last known leader, which is empty
+// until the first campaign.
+func (e *Elector) Leader() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.leader
+}
+
+// IsLeader reports whether this replica currently holds the lease.
+func (e *Elector) IsLeader() bool {
+	return e.Leader() == e.id
+}
+
+// Run campaigns for leadership until ctx is cancelled. Each time this
+// replica becomes leader, lead is called with a context that is cancelled
+// when leadership is lost; Run waits for lead to return before campaigning
+// again. When lead returns within a lease period, e.g. because it fails to
+// start, the wait before the next campaign doubles each time up to
+// maxLeadBackoff, so the replicas do not keep passing the lease around.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	backoff := e.lease / 3
+	for {
+		acquired, wait := e.campaign(ctx)
+		if acquired {
+			started := time.Now()
+			e.lead(ctx, lead)
+			if time.Since(started) < e.lease {
+				backoff = min(backoff*2, maxLeadBackoff)
+			} else {
+				backoff = e.lease / 3
+			}
+			wait = backoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// campaign tries to take the lease once. When it is held by another
+// replica, it returns how long to wait before trying again.
+func (e *Elector) campaign(ctx context.Context) (bool, time.Duration) {
+	ctx, span := tracer.Start(ctx, "Elector.campaign")
+	defer span.End()
+	retry := e.lease / 3
+
+	acquired, err := e.client.SetNX(ctx, e.key, e.id, e.lease).Result()
+	if err != nil {
+		span.RecordError(err)
+		e.logger.Warn("leader election failed", zap.Error(err))
+		return false, retry
+	}
+	if acquired {
+		e.setLeader(e.id)
+		span.SetAttributes(telemetry.String("leader.id", e.id), telemetry.Bool("leader.self", true))
+		return true, 0
+	}
+
+	pipe := e.client.Pipeline()
+	holder := pipe.Get(ctx, e.key)
+	ttl := pipe.PTTL(ctx, e.key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		span.RecordError(err)
+		return false, retry
+	}
+
+	leader := holder.Val()
+	e.setLeader(leader)
+	span.SetAttributes(telemetry.String("leader.id", leader), telemetry.Bool("leader.self", leader == e.id))
+	if leader == e.id {
+		// A lease left behind by this replica, e.g. before a restart.
+		return true, 0
+	}
+
+	if remaining := ttl.Val(); remaining > 0 && remaining < retry {
+		return false, remaining
+	}
+	return false, retry
+}
+
+// lead runs lead while renewing the lease, and steps down once the lease
+// is lost or can no longer be renewed before it expires.
+func (e *Elector) lead(ctx context.Context, lead func(ctx context.Context)) {
+	e.logger.Info("became leader", zap.String("key", e.key))
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leaderCtx)
+	}()
+
+	renewed := time.Now()
+	ticker := time.NewTicker(e.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			e.setLeader("")
+			e.wait(done)
+			e.release()
+			e.logger.Info("stepped down as leader")
+			return
+		case <-done:
+			cancel()
+			e.release()
+			e.logger.Info("leader finished, released lease")
+			return
+		case <-ticker.C:
+			ok, err := e.renew(ctx)
+			if ok {
+				renewed = time.Now()
+				continue
+			}
+			if err != nil && time.Since(renewed) < e.lease-e.lease/3 {
+				e.logger.Warn("failed to renew leader lease, retrying", zap.Error(err))
+				continue
+			}
+			e.logger.Warn("lost leadership", zap.Error(err))
+			cancel()
+			e.setLeader("")
+			e.wait(done)
+			return
+		}
+	}
+}
+
+// wait blocks until lead returns after its context was cancelled. The
+// replica no longer reports itself as leader meanwhile, but lead may still
+// be working; that is logged every lease period.
+func (e *Elector) wait(done <-chan struct{}) {
+	ticker := time.NewTicker(e.lease)
+	defer ticker.Stop()
+
+	started := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			e.logger.Warn("still waiting for leader work to stop",
+				zap.Duration("waited", time.Since(started)))
+		}
+	}
+}
+
+func (e *Elector) renew(ctx context.Context) (bool, error) {
+	ctx, span := tracer.Start(ctx, "Elector.renew")
+	defer span.End()
+	span.SetAttributes(telemetry.String("leader.id", e.id))
+
+	renewed, err := renewScript.Run(ctx, e.client, []string{e.key}, e.id, e.lease.Milliseconds()).Int()
+	if err != nil {
+		span.RecordError(err)
+		return false, err
+	}
+	return renewed == 1, nil
+}
+
+// release gives up the lease so a standby can take over without waiting
+// for it to expire.
+func (e *Elector) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.lease/3)
+	defer cancel()
+
+	if err := releaseScript.Run(ctx, e.client, []string{e.key}, e.id).Err(); err != nil {
+		e.logger.Warn("failed to release leader lease", zap.Error(err))
+	}
+	e.setLeader("")
+}
+
+func (e *Elector) setLeader(leader string) {
+	e.mutex.Lock()
+	changed := e.leader != leader
+	e.leader = leader
+	e.mutex.Unlock()
+
+	if changed && leader != "" && leader != e.id {
+		e.logger.Info("following leader", zap.String("leader_id", leader))
+	}
+}
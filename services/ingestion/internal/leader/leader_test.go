@@ -0,0 +1,150 @@
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	testKey   = "ingestion:leader"
+	testLease = 300 * time.Millisecond
+)
+
+func newTestElector(t *testing.T, server *miniredis.Miniredis, id string) *Elector {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return New(client, testKey, id, testLease, zap.NewNop())
+}
+
+// run starts e.Run in the background and returns a function that stops it
+// and waits for it to return.
+func run(e *Elector, lead func(ctx context.Context)) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		e.Run(ctx, lead)
+	}()
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
+
+// eventually fails unless cond holds within a few lease periods.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * testLease)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// blockUntilCancelled is a lead function that works until it is stopped.
+func blockUntilCancelled(ctx context.Context) { <-ctx.Done() }
+
+func TestElectorAcquiresAndReleases(t *testing.T) {
+	server := miniredis.RunT(t)
+	a := newTestElector(t, server, "a")
+	b := newTestElector(t, server, "b")
+
+	stopA := run(a, blockUntilCancelled)
+	eventually(t, "a leads", a.IsLeader)
+
+	stopB := run(b, blockUntilCancelled)
+	defer stopB()
+	eventually(t, "b follows a", func() bool { return b.Leader() == "a" })
+	if b.IsLeader() {
+		t.Fatal("b.IsLeader() = true while a holds the lease")
+	}
+	if ttl := server.TTL(testKey); ttl <= 0 || ttl > testLease {
+		t.Errorf("lease TTL = %v, want up to %v", ttl, testLease)
+	}
+
+	// Stepping down releases the lease rather than letting it expire.
+	stopA()
+	if a.IsLeader() {
+		t.Error("a.IsLeader() = true after stepping down")
+	}
+	eventually(t, "b takes over", b.IsLeader)
+}
+
+func TestElectorRenewsLease(t *testing.T) {
+	server := miniredis.RunT(t)
+	a := newTestElector(t, server, "a")
+	stop := run(a, blockUntilCancelled)
+	defer stop()
+	eventually(t, "a leads", a.IsLeader)
+
+	// miniredis only expires keys when told to; the renewals keep
+	// resetting the TTL that is fast-forwarded here.
+	for i := 0; i < 5; i++ {
+		server.FastForward(testLease / 2)
+		time.Sleep(testLease / 2)
+	}
+	if !a.IsLeader() {
+		t.Error("a.IsLeader() = false, want the lease renewed")
+	}
+	if got, _ := server.Get(testKey); got != "a" {
+		t.Errorf("lease held by %q, want a", got)
+	}
+}
+
+func TestElectorStepsDownWhenLeaseIsLost(t *testing.T) {
+	server := miniredis.RunT(t)
+	a := newTestElector(t, server, "a")
+
+	leadStopped := make(chan struct{})
+	var notLeaderWhileStopping atomic.Bool
+	stop := run(a, func(ctx context.Context) {
+		<-ctx.Done()
+		// Leader work that takes a while to stop must not be reported as
+		// leading anymore.
+		time.Sleep(testLease)
+		notLeaderWhileStopping.Store(!a.IsLeader())
+		close(leadStopped)
+	})
+	defer stop()
+	eventually(t, "a leads", a.IsLeader)
+
+	// Another replica took the lease, e.g. after a pause longer than the
+	// lease.
+	server.Set(testKey, "b")
+	select {
+	case <-leadStopped:
+	case <-time.After(10 * testLease):
+		t.Fatal("lead not cancelled after the lease was lost")
+	}
+	if !notLeaderWhileStopping.Load() {
+		t.Error("a.IsLeader() = true while its lead was stopping")
+	}
+	if got, _ := server.Get(testKey); got != "b" {
+		t.Errorf("lease held by %q, want b's lease left alone", got)
+	}
+}
+
+func TestElectorBacksOffWhenLeadReturns(t *testing.T) {
+	server := miniredis.RunT(t)
+	a := newTestElector(t, server, "a")
+
+	var calls atomic.Int32
+	stop := run(a, func(ctx context.Context) { calls.Add(1) })
+	time.Sleep(10 * testLease / 3)
+	stop()
+
+	// Without backoff lead would be called every third of a lease, ten
+	// times; doubling the wait leaves time for three.
+	if n := calls.Load(); n < 2 || n > 4 {
+		t.Errorf("lead called %d times, want 2 to 4", n)
+	}
+}
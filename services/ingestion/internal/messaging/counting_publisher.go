@@ -0,0 +1,80 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// CountingPublisher is a dry run publisher: it drops every message and only
+// counts them by subject. The counts are logged on Close.
+type CountingPublisher struct {
+	mutex  sync.Mutex
+	counts map[string]int
+	logger *zap.Logger
+}
+
+func NewCountingPublisher(logger *zap.Logger) *CountingPublisher {
+	return &CountingPublisher{
+		counts: make(map[string]int),
+		logger: logger,
+	}
+}
+
+func (p *CountingPublisher) PublishJobPosting(ctx context.Context, posting *models.JobPosting) error {
+	p.count(JobPostingsSubject)
+	return nil
+}
+
+func (p *CountingPublisher) PublishCandidate(ctx context.Context, post *models.ThreadPost) error {
+	p.count(CandidatesSubject)
+	return nil
+}
+
+func (p *CountingPublisher) PublishFreelance(ctx context.Context, post *models.ThreadPost) error {
+	p.count(FreelanceSubject)
+	return nil
+}
+
+func (p *CountingPublisher) PublishJobStatus(ctx context.Context, change *models.JobStatusChange) error {
+	p.count(JobStatusSubject)
+	return nil
+}
+
+func (p *CountingPublisher) PublishTombstone(ctx context.Context, tombstone *models.Tombstone) error {
+	p.count(JobRemovedSubject)
+	return nil
+}
+
+func (p *CountingPublisher) count(subject string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.counts[subject]++
+}
+
+// Counts returns the number of messages published so far by subject.
+func (p *CountingPublisher) Counts() map[string]int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	counts := make(map[string]int, len(p.counts))
+	for subject, n := range p.counts {
+		counts[subject] = n
+	}
+	return counts
+}
+
+func (p *CountingPublisher) Flush(ctx context.Context) error {
+	return nil
+}
+
+func (p *CountingPublisher) Close() {
+	counts := p.Counts()
+	fields := make([]zap.Field, 0, len(counts))
+	for subject, n := range counts {
+		fields = append(fields, zap.Int(subject, n))
+	}
+	p.logger.Info("dry run publisher counts", fields...)
+}
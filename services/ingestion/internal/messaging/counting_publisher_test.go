@@ -0,0 +1,50 @@
+package messaging
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"testing"
+
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+func TestCountingPublisherCountsBySubject(t *testing.T) {
+	publisher := NewCountingPublisher(zap.NewNop())
+	defer publisher.Close()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			publisher.PublishJobPosting(ctx, &models.JobPosting{})
+			publisher.PublishTombstone(ctx, &models.Tombstone{})
+		}()
+	}
+	wg.Wait()
+	publisher.PublishCandidate(ctx, &models.ThreadPost{})
+	publisher.PublishFreelance(ctx, &models.ThreadPost{})
+	publisher.PublishJobStatus(ctx, &models.JobStatusChange{})
+
+	want := map[string]int{
+		JobPostingsSubject: 10,
+		JobRemovedSubject:  10,
+		CandidatesSubject:  1,
+		FreelanceSubject:   1,
+		JobStatusSubject:   1,
+	}
+	counts := publisher.Counts()
+	if !maps.Equal(counts, want) {
+		t.Errorf("Counts() = %v, want %v", counts, want)
+	}
+
+	// The returned counts are a copy.
+	counts[JobPostingsSubject] = 0
+	if got := publisher.Counts()[JobPostingsSubject]; got != 10 {
+		t.Errorf("Counts() changed by its caller to %d", got)
+	}
+}
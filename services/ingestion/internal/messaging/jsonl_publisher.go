@@ -0,0 +1,111 @@
+package messaging
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// jsonlRecord is one line written by the JSONL publisher.
+type jsonlRecord struct {
+	Subject     string          `json:"subject"`
+	PublishedAt time.Time       `json:"published_at"`
+	Message     json.RawMessage `json:"message"`
+}
+
+type jsonlPublisher struct {
+	mutex  sync.Mutex
+	out    *bufio.Writer
+	closer io.Closer
+	logger *zap.Logger
+}
+
+// NewJSONLPublisher writes every message as a line of JSON to the file at
+// path, which is appended to, or to stdout when path is "-" or empty.
+func NewJSONLPublisher(path string, logger *zap.Logger) (Publisher, error) {
+	if path == "" || path == "-" {
+		return &jsonlPublisher{out: bufio.NewWriter(os.Stdout), logger: logger}, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, errors.Internal("opening publisher output", err)
+	}
+	return &jsonlPublisher{out: bufio.NewWriter(file), closer: file, logger: logger}, nil
+}
+
+func (p *jsonlPublisher) PublishJobPosting(ctx context.Context, posting *models.JobPosting) error {
+	return p.publish(ctx, "PublishJobPosting", JobPostingsSubject, posting)
+}
+
+func (p *jsonlPublisher) PublishCandidate(ctx context.Context, post *models.ThreadPost) error {
+	return p.publish(ctx, "PublishCandidate", CandidatesSubject, post)
+}
+
+func (p *jsonlPublisher) PublishFreelance(ctx context.Context, post *models.ThreadPost) error {
+	return p.publish(ctx, "PublishFreelance", FreelanceSubject, post)
+}
+
+func (p *jsonlPublisher) PublishJobStatus(ctx context.Context, change *models.JobStatusChange) error {
+	return p.publish(ctx, "PublishJobStatus", JobStatusSubject, change)
+}
+
+func (p *jsonlPublisher) PublishTombstone(ctx context.Context, tombstone *models.Tombstone) error {
+	return p.publish(ctx, "PublishTombstone", JobRemovedSubject, tombstone)
+}
+
+func (p *jsonlPublisher) publish(ctx context.Context, spanName, subject string, message interface{}) error {
+	_, span := tracer.Start(ctx, spanName)
+	defer span.End()
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		span.RecordError(err)
+		return errors.Internal("marshaling message", err)
+	}
+	line, err := json.Marshal(jsonlRecord{
+		Subject:     subject,
+		PublishedAt: time.Now().UTC(),
+		Message:     data,
+	})
+	if err != nil {
+		span.RecordError(err)
+		return errors.Internal("marshaling message", err)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if _, err := p.out.Write(append(line, '\n')); err != nil {
+		span.RecordError(err)
+		return errors.Internal("writing message", err)
+	}
+	return nil
+}
+
+func (p *jsonlPublisher) Flush(ctx context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if err := p.out.Flush(); err != nil {
+		return errors.Internal("flushing publisher output", err)
+	}
+	return nil
+}
+
+func (p *jsonlPublisher) Close() {
+	if err := p.Flush(context.Background()); err != nil {
+		p.logger.Error("failed to flush publisher output", zap.Error(err))
+	}
+	if p.closer != nil {
+		if err := p.closer.Close(); err != nil {
+			p.logger.Error("failed to close publisher output", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package messaging
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// readRecords returns the lines of a JSONL publisher's output.
+func readRecords(t *testing.T, path string) []jsonlRecord {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var records []jsonlRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record jsonlRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %q is not a record: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestJSONLPublisherWritesRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+	publisher, err := NewJSONLPublisher(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewJSONLPublisher() error = %v", err)
+	}
+
+	ctx := context.Background()
+	if err := publisher.PublishJobPosting(ctx, &models.JobPosting{ID: "41000001", RawText: "Acme | Berlin"}); err != nil {
+		t.Fatalf("PublishJobPosting() error = %v", err)
+	}
+	if err := publisher.PublishTombstone(ctx, &models.Tombstone{ID: "41000002", Reason: "deleted"}); err != nil {
+		t.Fatalf("PublishTombstone() error = %v", err)
+	}
+	// Output is buffered until flushed.
+	if records := readRecords(t, path); len(records) != 0 {
+		t.Errorf("%d records written before Flush", len(records))
+	}
+	if err := publisher.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	records := readRecords(t, path)
+	if len(records) != 2 {
+		t.Fatalf("%d records written, want 2", len(records))
+	}
+	if records[0].Subject != JobPostingsSubject || records[1].Subject != JobRemovedSubject {
+		t.Errorf("subjects = %s, %s", records[0].Subject, records[1].Subject)
+	}
+	var posting models.JobPosting
+	if err := json.Unmarshal(records[0].Message, &posting); err != nil || posting.ID != "41000001" || posting.RawText != "Acme | Berlin" {
+		t.Errorf("message = %s, want the posting", records[0].Message)
+	}
+	if records[0].PublishedAt.IsZero() {
+		t.Error("PublishedAt not set")
+	}
+
+	// Another run appends to the same file.
+	publisher.Close()
+	publisher, err = NewJSONLPublisher(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewJSONLPublisher() error = %v", err)
+	}
+	if err := publisher.PublishCandidate(ctx, &models.ThreadPost{ID: "41000003"}); err != nil {
+		t.Fatalf("PublishCandidate() error = %v", err)
+	}
+	publisher.Close()
+
+	records = readRecords(t, path)
+	if len(records) != 3 || records[2].Subject != CandidatesSubject {
+		t.Errorf("%d records after a second run, want the candidate appended", len(records))
+	}
+}
+
+func TestJSONLPublisherOutputError(t *testing.T) {
+	_, err := NewJSONLPublisher(filepath.Join(t.TempDir(), "missing", "messages.jsonl"), zap.NewNop())
+	if !errors.HasType(err, errors.ErrTypeInternal) {
+		t.Errorf("NewJSONLPublisher() error = %v, want internal", err)
+	}
+}
@@ -5,29 +5,47 @@ import (
 	"encoding/json"
 	"time"
 
+	"shenanigigs/common/stream"
 	"shenanigigs/common/telemetry"
 	"shenanigigs/ingestion/internal/config"
 	"shenanigigs/ingestion/internal/errors"
 	"shenanigigs/ingestion/internal/models"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"go.uber.org/zap"
 )
 
 var tracer = telemetry.GetTracer("shenanigigs/ingestion/messaging")
 
 const (
-	JobPostingsSubject = "jobs.new"
+	JobPostingsSubject = stream.JobPostingsSubject
+	CandidatesSubject  = stream.CandidatesSubject
+	FreelanceSubject   = stream.FreelanceSubject
+	JobStatusSubject   = stream.JobStatusSubject
+	JobRemovedSubject  = stream.JobRemovedSubject
 )
 
 type Publisher interface {
 	PublishJobPosting(ctx context.Context, posting *models.JobPosting) error
+	PublishCandidate(ctx context.Context, post *models.ThreadPost) error
+	PublishFreelance(ctx context.Context, post *models.ThreadPost) error
+	PublishJobStatus(ctx context.Context, change *models.JobStatusChange) error
+	PublishTombstone(ctx context.Context, tombstone *models.Tombstone) error
+	// Flush blocks until every message published so far has been handed to
+	// the server.
+	Flush(ctx context.Context) error
 	Close()
 }
 
+// natsPublisher publishes to the JetStream stream of the job pipeline and
+// waits for the publish ack of each message, so that a message reported as
+// published has been stored by the server.
 type natsPublisher struct {
-	conn   *nats.Conn
-	logger *zap.Logger
+	conn           *nats.Conn
+	js             jetstream.JetStream
+	publishTimeout time.Duration
+	logger         *zap.Logger
 }
 
 func NewPublisher(logger *zap.Logger, config *config.Config) (Publisher, error) {
@@ -42,38 +60,104 @@ func NewPublisher(logger *zap.Logger, config *config.Config) (Publisher, error)
 		return nil, errors.Internal("connecting to NATS", err)
 	}
 
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Internal("creating JetStream context", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.NATSConnTimeout)
+	defer cancel()
+	if _, err := stream.Ensure(ctx, js, stream.Options{
+		Name:            config.JetStreamStream,
+		MaxAge:          config.JetStreamMaxAge,
+		Replicas:        config.JetStreamReplicas,
+		DuplicateWindow: config.JetStreamDuplicateWindow,
+	}); err != nil {
+		conn.Close()
+		return nil, errors.Unavailable("provisioning JetStream stream", err)
+	}
+
 	return &natsPublisher{
-		conn:   conn,
-		logger: logger,
+		conn:           conn,
+		js:             js,
+		publishTimeout: config.NATSPublishTimeout,
+		logger:         logger,
 	}, nil
 }
 
 func (p *natsPublisher) PublishJobPosting(ctx context.Context, posting *models.JobPosting) error {
-	_, span := tracer.Start(ctx, "PublishJobPosting")
+	return p.publish(ctx, "PublishJobPosting", JobPostingsSubject, posting.ID, posting)
+}
+
+func (p *natsPublisher) PublishCandidate(ctx context.Context, post *models.ThreadPost) error {
+	return p.publish(ctx, "PublishCandidate", CandidatesSubject, post.ID, post)
+}
+
+func (p *natsPublisher) PublishFreelance(ctx context.Context, post *models.ThreadPost) error {
+	return p.publish(ctx, "PublishFreelance", FreelanceSubject, post.ID, post)
+}
+
+func (p *natsPublisher) PublishJobStatus(ctx context.Context, change *models.JobStatusChange) error {
+	return p.publish(ctx, "PublishJobStatus", JobStatusSubject, change.ID, change)
+}
+
+func (p *natsPublisher) PublishTombstone(ctx context.Context, tombstone *models.Tombstone) error {
+	return p.publish(ctx, "PublishTombstone", JobRemovedSubject, tombstone.ID, tombstone)
+}
+
+func (p *natsPublisher) publish(ctx context.Context, spanName, subject, id string, message interface{}) error {
+	ctx, span := tracer.Start(ctx, spanName)
 	defer span.End()
 
-	data, err := json.Marshal(posting)
+	data, err := json.Marshal(message)
 	if err != nil {
 		span.RecordError(err)
-		return errors.Internal("marshaling job posting", err)
+		return errors.Internal("marshaling message", err)
 	}
 
 	span.SetAttributes(
-		telemetry.String("nats.subject", JobPostingsSubject),
+		telemetry.String("nats.subject", subject),
 		telemetry.Int("message.size", len(data)),
 	)
 
-	if err := p.conn.Publish(JobPostingsSubject, data); err != nil {
+	if p.publishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.publishTimeout)
+		defer cancel()
+	}
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	msg.Header.Set(nats.MsgIdHdr, stream.MsgID(subject, data))
+	ack, err := p.js.PublishMsg(ctx, msg)
+	if err != nil {
 		span.RecordError(err)
-		p.logger.Error("failed to publish job posting",
-			zap.String("id", posting.ID),
+		p.logger.Error("failed to publish message",
+			zap.String("id", id),
+			zap.String("subject", subject),
 			zap.Error(err))
-		return errors.Internal("publishing to NATS", err)
+		return errors.Unavailable("publishing to JetStream", err)
 	}
 
-	p.logger.Debug("published job posting",
-		zap.String("id", posting.ID),
-		zap.String("subject", JobPostingsSubject))
+	span.SetAttributes(
+		telemetry.String("jetstream.stream", ack.Stream),
+		telemetry.Int("jetstream.sequence", int(ack.Sequence)),
+		telemetry.Bool("jetstream.duplicate", ack.Duplicate),
+	)
+	p.logger.Debug("published message",
+		zap.String("id", id),
+		zap.String("subject", subject),
+		zap.Uint64("sequence", ack.Sequence),
+		zap.Bool("duplicate", ack.Duplicate))
+	return nil
+}
+
+func (p *natsPublisher) Flush(ctx context.Context) error {
+	if p.conn == nil {
+		return nil
+	}
+	if err := p.conn.FlushWithContext(ctx); err != nil {
+		return errors.Unavailable("flushing NATS publisher", err)
+	}
 	return nil
 }
 
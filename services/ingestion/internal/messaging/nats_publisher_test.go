@@ -0,0 +1,122 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shenanigigs/ingestion/internal/config"
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/models"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+func runServer(t *testing.T, jetStream bool) *server.Server {
+	t.Helper()
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = jetStream
+	opts.StoreDir = t.TempDir()
+	s := natsserver.RunServer(&opts)
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func testConfig(url string) *config.Config {
+	return &config.Config{
+		NATSURL:                  url,
+		NATSConnTimeout:          5 * time.Second,
+		NATSPublishTimeout:       time.Second,
+		JetStreamStream:          "JOBS",
+		JetStreamMaxAge:          time.Hour,
+		JetStreamReplicas:        1,
+		JetStreamDuplicateWindow: time.Minute,
+	}
+}
+
+func TestNATSPublisherDeduplicates(t *testing.T) {
+	s := runServer(t, true)
+	cfg := testConfig(s.ClientURL())
+	publisher, err := NewPublisher(zap.NewNop(), cfg)
+	if err != nil {
+		t.Fatalf("NewPublisher() error = %v", err)
+	}
+	t.Cleanup(publisher.Close)
+
+	ctx := context.Background()
+	posting := &models.JobPosting{ID: "41000001", Source: models.SourceHackerNews, RawText: "Acme | Berlin"}
+	// A run retried after a crash publishes the same posting again.
+	for i := 0; i < 2; i++ {
+		if err := publisher.PublishJobPosting(ctx, posting); err != nil {
+			t.Fatalf("PublishJobPosting() error = %v", err)
+		}
+	}
+	edited := *posting
+	edited.RawText = "Acme | Berlin | Remote"
+	if err := publisher.PublishJobPosting(ctx, &edited); err != nil {
+		t.Fatalf("PublishJobPosting() error = %v", err)
+	}
+	// The same ID on another subject is another message.
+	if err := publisher.PublishTombstone(ctx, &models.Tombstone{ID: posting.ID, Source: posting.Source}); err != nil {
+		t.Fatalf("PublishTombstone() error = %v", err)
+	}
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := js.Stream(ctx, cfg.JetStreamStream)
+	if err != nil {
+		t.Fatalf("looking up stream: %v", err)
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 3 {
+		t.Errorf("stream holds %d messages, want 3", info.State.Msgs)
+	}
+}
+
+func TestNATSPublisherAckTimeout(t *testing.T) {
+	// Without JetStream, a subscriber that never answers stands in for a
+	// server that stored nothing.
+	s := runServer(t, false)
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	sub, err := nc.SubscribeSync(JobPostingsSubject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sub.Unsubscribe() })
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := &natsPublisher{js: js, publishTimeout: 100 * time.Millisecond, logger: zap.NewNop()}
+	start := time.Now()
+	err = publisher.PublishJobPosting(context.Background(), &models.JobPosting{ID: "41000001"})
+	if !errors.HasType(err, errors.ErrTypeUnavailable) {
+		t.Fatalf("PublishJobPosting() error = %v, want unavailable", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("gave up after %v, want the 100ms publish timeout", elapsed)
+	}
+	if _, err := sub.NextMsg(time.Second); err != nil {
+		t.Errorf("message not sent: %v", err)
+	}
+}
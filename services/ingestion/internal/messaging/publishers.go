@@ -0,0 +1,44 @@
+package messaging
+
+import (
+	"fmt"
+
+	"shenanigigs/ingestion/internal/config"
+	"shenanigigs/ingestion/internal/errors"
+
+	"go.uber.org/zap"
+)
+
+const (
+	BackendNATS  = "nats"
+	BackendJSONL = "jsonl"
+	BackendCount = "count"
+	BackendTee   = "tee"
+)
+
+// New returns the publisher selected by config.PublisherBackend: NATS, JSONL
+// written to config.PublisherOutput, a count-only dry run, or NATS teed to
+// JSONL.
+func New(logger *zap.Logger, config *config.Config) (Publisher, error) {
+	switch config.PublisherBackend {
+	case "", BackendNATS:
+		return NewPublisher(logger, config)
+	case BackendJSONL:
+		return NewJSONLPublisher(config.PublisherOutput, logger)
+	case BackendCount:
+		return NewCountingPublisher(logger), nil
+	case BackendTee:
+		natsPublisher, err := NewPublisher(logger, config)
+		if err != nil {
+			return nil, err
+		}
+		filePublisher, err := NewJSONLPublisher(config.PublisherOutput, logger)
+		if err != nil {
+			natsPublisher.Close()
+			return nil, err
+		}
+		return Tee(natsPublisher, filePublisher), nil
+	default:
+		return nil, errors.InvalidInput(fmt.Sprintf("unknown publisher backend %q", config.PublisherBackend), nil)
+	}
+}
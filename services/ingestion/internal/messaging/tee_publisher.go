@@ -0,0 +1,58 @@
+package messaging
+
+import (
+	"context"
+
+	"shenanigigs/ingestion/internal/models"
+)
+
+type teePublisher struct {
+	publishers []Publisher
+}
+
+// Tee publishes every message to each of publishers in turn. A failed
+// publish still reaches the remaining publishers; the first error is
+// returned.
+func Tee(publishers ...Publisher) Publisher {
+	return &teePublisher{publishers: publishers}
+}
+
+func (p *teePublisher) PublishJobPosting(ctx context.Context, posting *models.JobPosting) error {
+	return p.each(func(publisher Publisher) error { return publisher.PublishJobPosting(ctx, posting) })
+}
+
+func (p *teePublisher) PublishCandidate(ctx context.Context, post *models.ThreadPost) error {
+	return p.each(func(publisher Publisher) error { return publisher.PublishCandidate(ctx, post) })
+}
+
+func (p *teePublisher) PublishFreelance(ctx context.Context, post *models.ThreadPost) error {
+	return p.each(func(publisher Publisher) error { return publisher.PublishFreelance(ctx, post) })
+}
+
+func (p *teePublisher) PublishJobStatus(ctx context.Context, change *models.JobStatusChange) error {
+	return p.each(func(publisher Publisher) error { return publisher.PublishJobStatus(ctx, change) })
+}
+
+func (p *teePublisher) PublishTombstone(ctx context.Context, tombstone *models.Tombstone) error {
+	return p.each(func(publisher Publisher) error { return publisher.PublishTombstone(ctx, tombstone) })
+}
+
+func (p *teePublisher) Flush(ctx context.Context) error {
+	return p.each(func(publisher Publisher) error { return publisher.Flush(ctx) })
+}
+
+func (p *teePublisher) Close() {
+	for _, publisher := range p.publishers {
+		publisher.Close()
+	}
+}
+
+func (p *teePublisher) each(fn func(Publisher) error) error {
+	var firstErr error
+	for _, publisher := range p.publishers {
+		if err := fn(publisher); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
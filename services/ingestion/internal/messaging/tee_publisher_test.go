@@ -0,0 +1,90 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// failingPublisher fails every publish and flush, like NATS during an
+// outage, and counts the calls.
+type failingPublisher struct {
+	Publisher
+	calls  int
+	closed bool
+}
+
+func (p *failingPublisher) PublishJobPosting(ctx context.Context, posting *models.JobPosting) error {
+	p.calls++
+	return errors.Unavailable("publishing message", nil)
+}
+
+func (p *failingPublisher) Flush(ctx context.Context) error {
+	p.calls++
+	return errors.Unavailable("flushing messages", nil)
+}
+
+func (p *failingPublisher) Close() {
+	p.closed = true
+}
+
+func TestTeePublishesToEach(t *testing.T) {
+	first := NewCountingPublisher(zap.NewNop())
+	second := NewCountingPublisher(zap.NewNop())
+	publisher := Tee(first, second)
+	defer publisher.Close()
+
+	ctx := context.Background()
+	if err := publisher.PublishJobPosting(ctx, &models.JobPosting{}); err != nil {
+		t.Fatalf("PublishJobPosting() error = %v", err)
+	}
+	if err := publisher.PublishJobStatus(ctx, &models.JobStatusChange{}); err != nil {
+		t.Fatalf("PublishJobStatus() error = %v", err)
+	}
+	if err := publisher.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	for name, p := range map[string]*CountingPublisher{"first": first, "second": second} {
+		counts := p.Counts()
+		if counts[JobPostingsSubject] != 1 || counts[JobStatusSubject] != 1 {
+			t.Errorf("%s publisher counts = %v, want each message once", name, counts)
+		}
+	}
+}
+
+func TestTeeKeepsPublishingWhenOneSideFails(t *testing.T) {
+	for _, failFirst := range []bool{true, false} {
+		failing := &failingPublisher{}
+		counting := NewCountingPublisher(zap.NewNop())
+		publisher := Tee(failing, counting)
+		if !failFirst {
+			publisher = Tee(counting, failing)
+		}
+
+		ctx := context.Background()
+		err := publisher.PublishJobPosting(ctx, &models.JobPosting{})
+		if !errors.HasType(err, errors.ErrTypeUnavailable) {
+			t.Errorf("PublishJobPosting() error = %v, want the failing side's error", err)
+		}
+		if err := publisher.Flush(ctx); !errors.HasType(err, errors.ErrTypeUnavailable) {
+			t.Errorf("Flush() error = %v, want the failing side's error", err)
+		}
+		if failing.calls != 2 {
+			t.Errorf("failing publisher called %d times, want 2", failing.calls)
+		}
+		// The file still gets every message while NATS is down.
+		if got := counting.Counts()[JobPostingsSubject]; got != 1 {
+			t.Errorf("other publisher got %d postings (failing first: %v), want 1", got, failFirst)
+		}
+
+		publisher.Close()
+		if !failing.closed {
+			t.Error("Close() did not close the failing publisher")
+		}
+	}
+}
@@ -3,8 +3,12 @@ package models
 import (
 	"encoding/json"
 	"time"
+
+	"shenanigigs/common/htmltext"
 )
 
+const SourceHackerNews = "hackernews"
+
 type JobPosting struct {
 	ID          string    `json:"id"`
 	Title       string    `json:"title"`
@@ -12,12 +16,55 @@ type JobPosting struct {
 	PostedAt    time.Time `json:"posted_at"`
 	RawText     string    `json:"raw_text"`
 	ParentID    int       `json:"parent_id"`
+	Source      string    `json:"source"`
+	URL         string    `json:"url,omitempty"`
+
+	// Provenance of postings taken from an HN thread. ParentID is the
+	// thread's item ID and URL the permalink of the comment.
+	Author      string `json:"author,omitempty"`
+	ItemID      int    `json:"item_id,omitempty"`
+	ThreadMonth string `json:"thread_month,omitempty"`
+
+	// HTML is the original markup; Description and RawText hold it as plain
+	// text and Markdown holds it converted for display.
+	HTML     string          `json:"html,omitempty"`
+	Markdown string          `json:"markdown,omitempty"`
+	Links    []htmltext.Link `json:"links,omitempty"`
+
+	// Structured fields supplied by sources that know them, such as ATS
+	// boards. Processing uses them instead of parsing the text.
+	Company        string `json:"company,omitempty"`
+	Location       string `json:"location,omitempty"`
+	Department     string `json:"department,omitempty"`
+	EmploymentType string `json:"employment_type,omitempty"`
+	Board          string `json:"board,omitempty"`
+
+	// Updates are replies the original poster left under the posting, and
+	// Status is set once one of them says the position is filled or closed.
+	Updates []PostingUpdate `json:"updates,omitempty"`
+	Status  PostingStatus   `json:"status,omitempty"`
+}
+
+type PostingUpdate struct {
+	ID       string    `json:"id"`
+	Text     string    `json:"text"`
+	PostedAt time.Time `json:"posted_at"`
+}
+
+// SetHTML fills the content fields of the posting from an HTML fragment.
+func (p *JobPosting) SetHTML(fragment string) {
+	doc := htmltext.Convert(fragment)
+	p.HTML = doc.HTML
+	p.Description = doc.Text
+	p.RawText = doc.Text
+	p.Markdown = doc.Markdown
+	p.Links = doc.Links
 }
 
 func (p JobPosting) MarshalBinary() ([]byte, error) {
 	return json.Marshal(p)
 }
 
-func (p JobPosting) UnmarshalBinary(data []byte) error {
+func (p *JobPosting) UnmarshalBinary(data []byte) error {
 	return json.Unmarshal(data, &p)
 }
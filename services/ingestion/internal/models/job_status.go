@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type PostingStatus string
+
+const (
+	PostingStatusFilled PostingStatus = "filled"
+	PostingStatusClosed PostingStatus = "closed"
+)
+
+// JobStatusChange tells processing that a posting is no longer open. ReplyID
+// and Text identify the reply the status was taken from, if any.
+type JobStatusChange struct {
+	ID        string        `json:"id"`
+	Source    string        `json:"source"`
+	Status    PostingStatus `json:"status"`
+	ReplyID   string        `json:"reply_id,omitempty"`
+	Text      string        `json:"text,omitempty"`
+	ChangedAt time.Time     `json:"changed_at"`
+}
+
+func (c JobStatusChange) MarshalBinary() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func (c *JobStatusChange) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, c)
+}
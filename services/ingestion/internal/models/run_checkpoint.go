@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RunCheckpoint records the progress of an unfinished pass over a set of
+// stories: the stories whose comments were all handled, and the handled
+// comments of the others keyed by story ID.
+type RunCheckpoint struct {
+	Scope     string        `json:"scope"`
+	RunID     string        `json:"run_id,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Stories   []int         `json:"stories"`
+	Comments  map[int][]int `json:"comments"`
+}
+
+func NewRunCheckpoint(scope, runID string) *RunCheckpoint {
+	return &RunCheckpoint{
+		Scope:     scope,
+		RunID:     runID,
+		StartedAt: time.Now(),
+		Comments:  make(map[int][]int),
+	}
+}
+
+func (c RunCheckpoint) MarshalBinary() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func (c *RunCheckpoint) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, c)
+}
@@ -11,6 +11,7 @@ type SourcePost struct {
 	Title       string   `json:"title"`
 	Text        string   `json:"text"`
 	By          string   `json:"by"`
+	Parent      int      `json:"parent"`
 	Time        int64    `json:"time"`
 	Kids        IntSlice `json:"kids"`
 	Type        string   `json:"type"`
@@ -35,16 +36,50 @@ func (p SourcePost) MarshalBinary() ([]byte, error) {
 	return json.Marshal(p)
 }
 
-func (p SourcePost) UnmarshalBinary(data []byte) error {
+func (p *SourcePost) UnmarshalBinary(data []byte) error {
 	return json.Unmarshal(data, &p)
 }
 
-func (p *SourcePost) ToJobPosting() *JobPosting {
-	return &JobPosting{
+const hnItemURL = "https://news.ycombinator.com/item?id="
+
+// Permalink is the HN page of the item.
+func (p *SourcePost) Permalink() string {
+	return hnItemURL + strconv.Itoa(p.ID)
+}
+
+// ThreadMonth is the month a whoishiring thread covers, as YYYY-MM. The
+// threads are posted on the first weekday of their month.
+func (p *SourcePost) ThreadMonth() string {
+	return time.Unix(p.Time, 0).UTC().Format("2006-01")
+}
+
+// ToJobPosting converts a top-level comment of the given thread.
+func (p *SourcePost) ToJobPosting(threadID int, threadMonth string) *JobPosting {
+	posting := &JobPosting{
 		ID:          strconv.Itoa(p.ID),
 		Title:       p.Title,
-		Description: p.Text,
 		PostedAt:    time.Unix(p.Time, 0),
-		RawText:     p.Text,
+		ParentID:    threadID,
+		Source:      SourceHackerNews,
+		URL:         p.Permalink(),
+		Author:      p.By,
+		ItemID:      p.ID,
+		ThreadMonth: threadMonth,
+	}
+	posting.SetHTML(p.Text)
+	return posting
+}
+
+func (p *SourcePost) ToThreadPost(threadID int, threadType ThreadType, threadMonth string) *ThreadPost {
+	return &ThreadPost{
+		ID:          strconv.Itoa(p.ID),
+		ThreadID:    threadID,
+		ThreadType:  threadType,
+		ThreadMonth: threadMonth,
+		Text:        p.Text,
+		PostedAt:    time.Unix(p.Time, 0),
+		Source:      SourceHackerNews,
+		Author:      p.By,
+		URL:         p.Permalink(),
 	}
 }
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ThreadPost is a top-level comment from a "Who wants to be hired?" or
+// "Freelancer? Seeking freelancer?" thread. Unlike a JobPosting it describes
+// a candidate or a freelance offer rather than an opening.
+type ThreadPost struct {
+	ID          string     `json:"id"`
+	ThreadID    int        `json:"thread_id"`
+	ThreadType  ThreadType `json:"thread_type"`
+	ThreadMonth string     `json:"thread_month,omitempty"`
+	Text        string     `json:"text"`
+	PostedAt    time.Time  `json:"posted_at"`
+	Source      string     `json:"source"`
+	Author      string     `json:"author,omitempty"`
+	URL         string     `json:"url,omitempty"`
+}
+
+func (p ThreadPost) MarshalBinary() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func (p *ThreadPost) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, p)
+}
@@ -0,0 +1,15 @@
+package models
+
+// ThreadState records the comments of a thread that have already been
+// published, keyed by comment ID, along with a hash of their content.
+type ThreadState struct {
+	ThreadID int
+	Comments map[int]string
+}
+
+func NewThreadState(threadID int) *ThreadState {
+	return &ThreadState{
+		ThreadID: threadID,
+		Comments: make(map[int]string),
+	}
+}
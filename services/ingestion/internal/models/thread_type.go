@@ -0,0 +1,35 @@
+package models
+
+import "strings"
+
+// ThreadType identifies which of the monthly threads posted by the
+// whoishiring account a story is.
+type ThreadType string
+
+const (
+	ThreadTypeHiring     ThreadType = "hiring"
+	ThreadTypeCandidates ThreadType = "candidates"
+	ThreadTypeFreelance  ThreadType = "freelance"
+
+	whoIsHiringAuthor = "whoishiring"
+)
+
+// ClassifyThread reports the type of a whoishiring thread. Stories by anyone
+// else, and whoishiring stories with an unknown title, are not threads.
+func ClassifyThread(post *SourcePost) (ThreadType, bool) {
+	if post.By != whoIsHiringAuthor {
+		return "", false
+	}
+
+	title := strings.ToLower(post.Title)
+	switch {
+	case strings.Contains(title, "who is hiring?"):
+		return ThreadTypeHiring, true
+	case strings.Contains(title, "who wants to be hired?"):
+		return ThreadTypeCandidates, true
+	case strings.Contains(title, "freelancer?") && strings.Contains(title, "seeking freelancer?"):
+		return ThreadTypeFreelance, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	RemovalReasonDeleted = "deleted"
+	RemovalReasonDead    = "dead"
+)
+
+// Tombstone tells processing that a previously published post was deleted
+// or killed at the source. ThreadType says which kind of post it was.
+type Tombstone struct {
+	ID         string     `json:"id"`
+	Source     string     `json:"source"`
+	ThreadID   int        `json:"thread_id,omitempty"`
+	ThreadType ThreadType `json:"thread_type,omitempty"`
+	Reason     string     `json:"reason"`
+	RemovedAt  time.Time  `json:"removed_at"`
+}
+
+func (t Tombstone) MarshalBinary() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+func (t *Tombstone) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, t)
+}
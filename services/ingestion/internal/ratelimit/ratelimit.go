@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+const (
+	BackendLocal = "local"
+	BackendRedis = "redis"
+)
+
+// Limiter is a token bucket that callers wait on before each request.
+type Limiter interface {
+	// Wait blocks until a token is available and returns how long it waited.
+	Wait(ctx context.Context) (time.Duration, error)
+}
+
+// New builds a limiter allowing ratePerSecond requests per second with the
+// given burst. A non-positive rate disables limiting. The redis backend
+// shares one bucket, identified by key, between every process using it.
+func New(backend string, client *redis.Client, key string, ratePerSecond float64, burst int) (Limiter, error) {
+	if ratePerSecond <= 0 {
+		return unlimited{}, nil
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+
+	switch backend {
+	case "", BackendLocal:
+		return &localLimiter{limiter: rate.NewLimiter(rate.Limit(ratePerSecond), burst)}, nil
+	case BackendRedis:
+		if client == nil {
+			return nil, fmt.Errorf("redis rate limiter %q requires a redis client", key)
+		}
+		return &redisLimiter{client: client, key: key, rate: ratePerSecond, burst: burst}, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", backend)
+	}
+}
+
+type unlimited struct{}
+
+func (unlimited) Wait(ctx context.Context) (time.Duration, error) {
+	return 0, ctx.Err()
+}
+
+type localLimiter struct {
+	limiter *rate.Limiter
+}
+
+func (l *localLimiter) Wait(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	err := l.limiter.Wait(ctx)
+	return time.Since(start), err
+}
+
+// reserveScript takes one token from the bucket stored at KEYS[1], letting the
+// balance go negative, and returns how many milliseconds the caller has to
+// wait before using it. The server clock is used so that replicas with skewed
+// clocks still share a consistent bucket.
+var reserveScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = redis.call('TIME')
+local now_ms = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now_ms
+end
+
+tokens = math.min(burst, tokens + (math.max(0, now_ms - ts) / 1000) * rate)
+tokens = tokens - 1
+
+local wait_ms = 0
+if tokens < 0 then
+	wait_ms = math.ceil((-tokens / rate) * 1000)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now_ms)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst / rate) * 1000) + wait_ms + 1000)
+return wait_ms
+`)
+
+type redisLimiter struct {
+	client *redis.Client
+	key    string
+	rate   float64
+	burst  int
+}
+
+func (l *redisLimiter) Wait(ctx context.Context) (time.Duration, error) {
+	waitMS, err := reserveScript.Run(ctx, l.client, []string{l.key}, l.rate, l.burst).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("reserving rate limit token: %w", err)
+	}
+	if waitMS <= 0 {
+		return 0, nil
+	}
+
+	wait := time.Duration(waitMS) * time.Millisecond
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return wait, ctx.Err()
+	case <-timer.C:
+		return wait, nil
+	}
+}
@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+// reserve takes a token from the limiter's bucket without sleeping and
+// returns how long the caller would have to wait for it.
+func reserve(t *testing.T, l Limiter) time.Duration {
+	t.Helper()
+	r := l.(*redisLimiter)
+	waitMS, err := reserveScript.Run(context.Background(), r.client, []string{r.key}, r.rate, r.burst).Int64()
+	if err != nil {
+		t.Fatalf("reserving token: %v", err)
+	}
+	return time.Duration(waitMS) * time.Millisecond
+}
+
+func TestRedisLimiterBurstAndRefill(t *testing.T) {
+	server, client := newTestRedis(t)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	server.SetTime(now)
+
+	limiter, err := New(BackendRedis, client, "ratelimit:test", 10, 3)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// A full bucket lets the burst through, then callers queue up one
+	// interval apart.
+	for i, want := range []time.Duration{0, 0, 0, 100 * time.Millisecond, 200 * time.Millisecond} {
+		if got := reserve(t, limiter); got != want {
+			t.Errorf("reservation %d waits %v, want %v", i, got, want)
+		}
+	}
+
+	// The debt of two tokens is paid off after 200ms, and the bucket then
+	// refills at the rate.
+	server.SetTime(now.Add(300 * time.Millisecond))
+	if got := reserve(t, limiter); got != 0 {
+		t.Errorf("reservation after 300ms waits %v, want 0", got)
+	}
+	if got := reserve(t, limiter); got != 100*time.Millisecond {
+		t.Errorf("next reservation waits %v, want 100ms", got)
+	}
+
+	// It never refills beyond the burst.
+	server.SetTime(now.Add(time.Hour))
+	for i, want := range []time.Duration{0, 0, 0, 100 * time.Millisecond} {
+		if got := reserve(t, limiter); got != want {
+			t.Errorf("reservation %d after an hour waits %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestRedisLimiterWait(t *testing.T) {
+	_, client := newTestRedis(t)
+	limiter, err := New(BackendRedis, client, "ratelimit:test", 20, 1)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+	if waited, err := limiter.Wait(ctx); err != nil || waited != 0 {
+		t.Errorf("first Wait() = %v, %v, want no wait", waited, err)
+	}
+	if waited, err := limiter.Wait(ctx); err != nil || waited <= 0 {
+		t.Errorf("second Wait() = %v, %v, want a wait", waited, err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := limiter.Wait(cancelled); err == nil {
+		t.Error("Wait() with a cancelled context succeeded")
+	}
+}
+
+func TestRedisLimitersShareBucketsByKey(t *testing.T) {
+	server, client := newTestRedis(t)
+	server.SetTime(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+
+	firebase, _ := New(BackendRedis, client, "ratelimit:hn:firebase", 1, 1)
+	algolia, _ := New(BackendRedis, client, "ratelimit:hn:algolia", 1, 1)
+	// Another replica limiting the same backend.
+	firebaseReplica, _ := New(BackendRedis, client, "ratelimit:hn:firebase", 1, 1)
+
+	if got := reserve(t, firebase); got != 0 {
+		t.Errorf("firebase reservation waits %v, want 0", got)
+	}
+	if got := reserve(t, algolia); got != 0 {
+		t.Errorf("algolia reservation after firebase drained its bucket waits %v, want 0", got)
+	}
+	if got := reserve(t, firebaseReplica); got != time.Second {
+		t.Errorf("firebase reservation on another replica waits %v, want 1s", got)
+	}
+}
+
+func TestLocalLimiter(t *testing.T) {
+	limiter, err := New(BackendLocal, nil, "unused", 20, 2)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if waited, err := limiter.Wait(ctx); err != nil || waited > 10*time.Millisecond {
+			t.Errorf("Wait() %d within the burst = %v, %v, want no wait", i, waited, err)
+		}
+	}
+	if waited, err := limiter.Wait(ctx); err != nil || waited < 30*time.Millisecond {
+		t.Errorf("Wait() beyond the burst = %v, %v, want about 50ms", waited, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, client := newTestRedis(t)
+
+	tests := []struct {
+		name     string
+		backend  string
+		client   *redis.Client
+		rate     float64
+		wantType string
+	}{
+		{"no rate", BackendRedis, nil, 0, "ratelimit.unlimited"},
+		{"default backend", "", nil, 1, "*ratelimit.localLimiter"},
+		{"local", BackendLocal, nil, 1, "*ratelimit.localLimiter"},
+		{"redis", BackendRedis, client, 1, "*ratelimit.redisLimiter"},
+		{"redis without client", BackendRedis, nil, 1, ""},
+		{"unknown backend", "memcached", nil, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.backend, tt.client, "ratelimit:test", tt.rate, 0)
+			if (err != nil) != (tt.wantType == "") {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err == nil && fmt.Sprintf("%T", got) != tt.wantType {
+				t.Errorf("New() = %T, want %s", got, tt.wantType)
+			}
+		})
+	}
+}
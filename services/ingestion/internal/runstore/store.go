@@ -0,0 +1,239 @@
+package runstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"shenanigigs/common/telemetry"
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/scheduler"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"go.uber.org/zap"
+)
+
+const defaultLimit = 50
+
+var tracer = telemetry.GetTracer("shenanigigs/ingestion/runstore")
+
+// Store keeps the history of scheduler runs in the ingestion_runs table.
+type Store struct {
+	conn   clickhouse.Conn
+	logger *zap.Logger
+}
+
+// Query selects runs from the history. Zero fields match everything.
+type Query struct {
+	Source      string
+	ThreadID    int
+	ThreadMonth string
+	Since       time.Time
+	Until       time.Time
+	Limit       int
+}
+
+func New(conn clickhouse.Conn, logger *zap.Logger) *Store {
+	return &Store{
+		conn:   conn,
+		logger: logger,
+	}
+}
+
+func (s *Store) RecordRun(ctx context.Context, run scheduler.RunStatus) error {
+	ctx, span := tracer.Start(ctx, "Store.RecordRun")
+	defer span.End()
+	span.SetAttributes(telemetry.String("run_id", run.ID))
+
+	finishedAt := run.StartedAt
+	if run.FinishedAt != nil {
+		finishedAt = *run.FinishedAt
+	}
+
+	threadIDs := make([]uint64, len(run.Threads))
+	threadTypes := make([]string, len(run.Threads))
+	threadMonths := make([]string, len(run.Threads))
+	for i, thread := range run.Threads {
+		threadIDs[i] = uint64(thread.ID)
+		threadTypes[i] = thread.Type
+		threadMonths[i] = thread.Month
+	}
+
+	errorSamples := run.ErrorSamples
+	if errorSamples == nil {
+		errorSamples = []string{}
+	}
+
+	query := `
+		INSERT INTO ingestion_runs (
+			id, source, trigger, target, state, started_at, finished_at,
+			thread_ids, thread_types, thread_months,
+			hiring_threads_found, candidate_threads_found, freelance_threads_found,
+			comments_queued, comments_fetched, comments_published, comments_skipped, comments_removed,
+			stories_failed, comments_failed, error, error_samples
+		) VALUES (
+			?, ?, ?, ?, ?, ?, ?,
+			?, ?, ?,
+			?, ?, ?,
+			?, ?, ?, ?, ?,
+			?, ?, ?, ?
+		)
+	`
+
+	if err := s.conn.Exec(ctx, query,
+		run.ID,
+		run.Source,
+		run.Trigger,
+		run.Target,
+		run.State,
+		run.StartedAt,
+		finishedAt,
+		threadIDs,
+		threadTypes,
+		threadMonths,
+		uint32(run.Stats.HiringThreadsFound),
+		uint32(run.Stats.CandidateThreadsFound),
+		uint32(run.Stats.FreelanceThreadsFound),
+		uint32(run.Stats.CommentsQueued),
+		uint32(run.Stats.CommentsFetched),
+		uint32(run.Stats.CommentsProcessed),
+		uint32(run.Stats.CommentsSkipped),
+		uint32(run.Stats.CommentsRemoved),
+		uint32(run.Stats.StoriesFailed),
+		uint32(run.Stats.CommentsFailed),
+		run.Error,
+		errorSamples,
+	); err != nil {
+		span.RecordError(err)
+		return errors.Unavailable("failed to record ingestion run", err)
+	}
+
+	return nil
+}
+
+// Runs returns the runs matching q, newest first. Use ThreadMonth to check
+// whether a month's thread has been picked up, e.g. "2024-06".
+func (s *Store) Runs(ctx context.Context, q Query) ([]scheduler.RunStatus, error) {
+	ctx, span := tracer.Start(ctx, "Store.Runs")
+	defer span.End()
+
+	query, args := runsQuery(q)
+	rows, err := s.conn.Query(ctx, query, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, errors.Unavailable("failed to query ingestion runs", err)
+	}
+	defer rows.Close()
+
+	var runs []scheduler.RunStatus
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, errors.Internal("failed to scan ingestion run", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, errors.Unavailable("failed to read ingestion runs", err)
+	}
+
+	return runs, nil
+}
+
+// runsQuery builds the SELECT for q and its arguments.
+func runsQuery(q Query) (string, []any) {
+	var conditions []string
+	var args []any
+	if q.Source != "" {
+		conditions = append(conditions, "source = ?")
+		args = append(args, q.Source)
+	}
+	if q.ThreadID != 0 {
+		conditions = append(conditions, "has(thread_ids, ?)")
+		args = append(args, uint64(q.ThreadID))
+	}
+	if q.ThreadMonth != "" {
+		conditions = append(conditions, "has(thread_months, ?)")
+		args = append(args, q.ThreadMonth)
+	}
+	if !q.Since.IsZero() {
+		conditions = append(conditions, "started_at >= ?")
+		args = append(args, q.Since)
+	}
+	if !q.Until.IsZero() {
+		conditions = append(conditions, "started_at < ?")
+		args = append(args, q.Until)
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	query := `
+		SELECT
+			id, source, trigger, target, state, started_at, finished_at,
+			thread_ids, thread_types, thread_months,
+			hiring_threads_found, candidate_threads_found, freelance_threads_found,
+			comments_queued, comments_fetched, comments_published, comments_skipped, comments_removed,
+			stories_failed, comments_failed, error, error_samples
+		FROM ingestion_runs
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY started_at DESC LIMIT %d", limit)
+
+	return query, args
+}
+
+func scanRun(rows driver.Rows) (scheduler.RunStatus, error) {
+	var (
+		run                                          scheduler.RunStatus
+		finishedAt                                   time.Time
+		threadIDs                                    []uint64
+		threadTypes, threadMonths                    []string
+		hiring, candidates, freelance                uint32
+		queued, fetched, published, skipped, removed uint32
+		storiesFailed, commentsFailed                uint32
+	)
+	if err := rows.Scan(
+		&run.ID, &run.Source, &run.Trigger, &run.Target, &run.State, &run.StartedAt, &finishedAt,
+		&threadIDs, &threadTypes, &threadMonths,
+		&hiring, &candidates, &freelance,
+		&queued, &fetched, &published, &skipped, &removed,
+		&storiesFailed, &commentsFailed, &run.Error, &run.ErrorSamples,
+	); err != nil {
+		return scheduler.RunStatus{}, err
+	}
+
+	run.FinishedAt = &finishedAt
+	for i, id := range threadIDs {
+		thread := scheduler.ThreadRef{ID: int(id)}
+		if i < len(threadTypes) {
+			thread.Type = threadTypes[i]
+		}
+		if i < len(threadMonths) {
+			thread.Month = threadMonths[i]
+		}
+		run.Threads = append(run.Threads, thread)
+	}
+	run.Stats = scheduler.RunStats{
+		HiringThreadsFound:    int(hiring),
+		CandidateThreadsFound: int(candidates),
+		FreelanceThreadsFound: int(freelance),
+		CommentsQueued:        int(queued),
+		CommentsFetched:       int(fetched),
+		CommentsProcessed:     int(published),
+		CommentsSkipped:       int(skipped),
+		CommentsRemoved:       int(removed),
+		StoriesFailed:         int(storiesFailed),
+		CommentsFailed:        int(commentsFailed),
+	}
+
+	return run, nil
+}
@@ -0,0 +1,63 @@
+package runstore
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunsQuery(t *testing.T) {
+	since := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    Query
+		wantTail string
+		wantArgs []any
+	}{
+		{
+			name:     "everything",
+			query:    Query{},
+			wantTail: "FROM ingestion_runs ORDER BY started_at DESC LIMIT 50",
+		},
+		{
+			name:     "thread",
+			query:    Query{ThreadID: 41000000, Limit: 5},
+			wantTail: "FROM ingestion_runs WHERE has(thread_ids, ?) ORDER BY started_at DESC LIMIT 5",
+			wantArgs: []any{uint64(41000000)},
+		},
+		{
+			name:     "month",
+			query:    Query{ThreadMonth: "2026-10", Limit: -1},
+			wantTail: "FROM ingestion_runs WHERE has(thread_months, ?) ORDER BY started_at DESC LIMIT 50",
+			wantArgs: []any{"2026-10"},
+		},
+		{
+			name:     "window",
+			query:    Query{Since: since, Until: until},
+			wantTail: "FROM ingestion_runs WHERE started_at >= ? AND started_at < ? ORDER BY started_at DESC LIMIT 50",
+			wantArgs: []any{since, until},
+		},
+		{
+			name:  "all filters",
+			query: Query{Source: "hackernews", ThreadID: 41000000, ThreadMonth: "2026-10", Since: since, Until: until, Limit: 10},
+			wantTail: "FROM ingestion_runs WHERE source = ? AND has(thread_ids, ?) AND has(thread_months, ?)" +
+				" AND started_at >= ? AND started_at < ? ORDER BY started_at DESC LIMIT 10",
+			wantArgs: []any{"hackernews", uint64(41000000), "2026-10", since, until},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := runsQuery(tt.query)
+			if got := strings.Join(strings.Fields(query), " "); !strings.HasSuffix(got, tt.wantTail) {
+				t.Errorf("runsQuery() = %q, want it to end with %q", got, tt.wantTail)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("runsQuery() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package schedule
+
+import (
+	"sync"
+	"time"
+)
+
+// AdaptiveOptions tune an Adaptive schedule. Zero values take the defaults.
+type AdaptiveOptions struct {
+	// MinInterval is used while a thread is fresh or overdue.
+	MinInterval time.Duration
+	// MaxInterval bounds the back-off once a thread has gone quiet.
+	MaxInterval time.Duration
+	// FreshFor is how long after posting a thread is polled at MinInterval.
+	FreshFor time.Duration
+	// TargetComments is the number of new comments a poll aims to pick up
+	// once a thread is no longer fresh.
+	TargetComments int
+}
+
+const (
+	defaultMinInterval    = 2 * time.Minute
+	defaultMaxInterval    = 6 * time.Hour
+	defaultFreshFor       = 48 * time.Hour
+	defaultTargetComments = 25
+)
+
+// Adaptive follows the life of the monthly "Who is hiring?" threads. New
+// threads appear on the first weekday of the month and get most of their
+// comments in the first two days, so the current thread is polled at
+// MinInterval while it is fresh, and while it is due but not yet found.
+// After that the interval follows comment velocity, aiming for
+// TargetComments new comments per poll and doubling while nothing changes.
+type Adaptive struct {
+	options AdaptiveOptions
+
+	mutex          sync.Mutex
+	interval       time.Duration
+	threadMonth    string
+	threadPostedAt time.Time
+	lastObserved   time.Time
+}
+
+func NewAdaptive(options AdaptiveOptions) *Adaptive {
+	if options.MinInterval <= 0 {
+		options.MinInterval = defaultMinInterval
+	}
+	if options.MaxInterval < options.MinInterval {
+		options.MaxInterval = defaultMaxInterval
+		if options.MaxInterval < options.MinInterval {
+			options.MaxInterval = options.MinInterval
+		}
+	}
+	if options.FreshFor <= 0 {
+		options.FreshFor = defaultFreshFor
+	}
+	if options.TargetComments <= 0 {
+		options.TargetComments = defaultTargetComments
+	}
+	return &Adaptive{
+		options:  options,
+		interval: options.MinInterval,
+	}
+}
+
+// Observe records the outcome of a poll at now: the newest thread seen, if
+// any, and how many comments were new or changed.
+func (a *Adaptive) Observe(now time.Time, threadPostedAt time.Time, newComments int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if threadPostedAt.After(a.threadPostedAt) {
+		a.threadPostedAt = threadPostedAt
+		a.threadMonth = threadPostedAt.UTC().Format("2006-01")
+	}
+
+	switch {
+	case a.lastObserved.IsZero():
+		a.interval = a.options.MinInterval
+	case newComments == 0:
+		a.interval *= 2
+	default:
+		elapsed := now.Sub(a.lastObserved)
+		a.interval = elapsed * time.Duration(a.options.TargetComments) / time.Duration(newComments)
+	}
+	a.interval = a.clamp(a.interval)
+	a.lastObserved = now
+}
+
+func (a *Adaptive) Next(now time.Time) time.Time {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.threadPostedAt.IsZero() && now.Sub(a.threadPostedAt) < a.options.FreshFor {
+		return now.Add(a.options.MinInterval)
+	}
+
+	due := FirstWeekday(now.UTC().Year(), now.UTC().Month())
+	if a.threadMonth != due.Format("2006-01") && !now.Before(due) {
+		return now.Add(a.options.MinInterval)
+	}
+
+	next := now.Add(a.interval)
+	if now.Before(due) && next.After(due) {
+		next = due
+	}
+	return next
+}
+
+func (a *Adaptive) clamp(interval time.Duration) time.Duration {
+	if interval < a.options.MinInterval {
+		return a.options.MinInterval
+	}
+	if interval > a.options.MaxInterval {
+		return a.options.MaxInterval
+	}
+	return interval
+}
+
+// FirstWeekday returns midnight UTC of the first Monday to Friday of the
+// month, the day a new "Who is hiring?" thread is posted.
+func FirstWeekday(year int, month time.Month) time.Time {
+	day := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
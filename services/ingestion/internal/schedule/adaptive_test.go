@@ -0,0 +1,161 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves when a timer is waited on.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestFirstWeekday(t *testing.T) {
+	tests := []struct {
+		year  int
+		month time.Month
+		want  int
+	}{
+		{2024, time.October, 1},   // Tuesday
+		{2024, time.September, 2}, // the 1st is a Sunday
+		{2024, time.June, 3},      // the 1st is a Saturday
+		{2025, time.February, 3},  // the 1st is a Saturday
+	}
+	for _, tt := range tests {
+		want := time.Date(tt.year, tt.month, tt.want, 0, 0, 0, 0, time.UTC)
+		if got := FirstWeekday(tt.year, tt.month); !got.Equal(want) {
+			t.Errorf("FirstWeekday(%d, %s) = %v, want %v", tt.year, tt.month, got, want)
+		}
+	}
+}
+
+func TestNewAdaptiveDefaults(t *testing.T) {
+	a := NewAdaptive(AdaptiveOptions{})
+	if a.options.MinInterval != defaultMinInterval || a.options.MaxInterval != defaultMaxInterval ||
+		a.options.FreshFor != defaultFreshFor || a.options.TargetComments != defaultTargetComments {
+		t.Errorf("NewAdaptive() options = %+v, want the defaults", a.options)
+	}
+
+	a = NewAdaptive(AdaptiveOptions{MinInterval: 8 * time.Hour})
+	if a.options.MaxInterval != 8*time.Hour {
+		t.Errorf("MaxInterval = %v, want it raised to MinInterval", a.options.MaxInterval)
+	}
+}
+
+func TestAdaptiveObserve(t *testing.T) {
+	options := AdaptiveOptions{
+		MinInterval:    2 * time.Minute,
+		MaxInterval:    6 * time.Hour,
+		FreshFor:       48 * time.Hour,
+		TargetComments: 25,
+	}
+	// Mid-month, with this month's thread posted well before.
+	posted := time.Date(2024, 10, 1, 15, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: time.Date(2024, 10, 10, 12, 0, 0, 0, time.UTC)}
+	a := NewAdaptive(options)
+
+	steps := []struct {
+		name        string
+		wait        time.Duration
+		newComments int
+		want        time.Duration
+	}{
+		{"first poll starts at the minimum", 0, 40, 2 * time.Minute},
+		{"velocity sets the interval", 10 * time.Minute, 50, 5 * time.Minute},
+		{"slower thread polls less often", 5 * time.Minute, 5, 25 * time.Minute},
+		{"quiet poll doubles", 25 * time.Minute, 0, 50 * time.Minute},
+		{"quiet poll doubles again", 50 * time.Minute, 0, 100 * time.Minute},
+		{"back-off is bounded", 100 * time.Minute, 0, 200 * time.Minute},
+		{"back-off reaches the maximum", 200 * time.Minute, 0, 6 * time.Hour},
+		{"and stays there", 6 * time.Hour, 0, 6 * time.Hour},
+		{"busy thread drops to the minimum", 6 * time.Hour, 10000, 2 * time.Minute},
+	}
+
+	for _, step := range steps {
+		<-clock.After(step.wait)
+		a.Observe(clock.Now(), posted, step.newComments)
+		if got := a.Next(clock.Now()).Sub(clock.Now()); got != step.want {
+			t.Errorf("%s: interval = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestAdaptiveFreshThread(t *testing.T) {
+	options := AdaptiveOptions{MinInterval: 2 * time.Minute, MaxInterval: 6 * time.Hour, FreshFor: 48 * time.Hour}
+	posted := time.Date(2024, 10, 1, 15, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: posted.Add(time.Hour)}
+	a := NewAdaptive(options)
+
+	// Quiet polls would back off, but a fresh thread keeps the minimum.
+	for i := 0; i < 10; i++ {
+		a.Observe(clock.Now(), posted, 0)
+		next := a.Next(clock.Now())
+		if got := next.Sub(clock.Now()); got != options.MinInterval {
+			t.Fatalf("poll %d: interval = %v while fresh, want %v", i, got, options.MinInterval)
+		}
+		<-clock.After(next.Sub(clock.Now()))
+	}
+
+	// Once FreshFor has passed, the backed off interval applies.
+	<-clock.After(48 * time.Hour)
+	a.Observe(clock.Now(), posted, 0)
+	if got := a.Next(clock.Now()).Sub(clock.Now()); got <= options.MinInterval {
+		t.Errorf("interval = %v after the thread went stale, want a back-off", got)
+	}
+}
+
+func TestAdaptiveWaitsForNextThread(t *testing.T) {
+	options := AdaptiveOptions{MinInterval: 2 * time.Minute, MaxInterval: 48 * time.Hour}
+	a := NewAdaptive(options)
+
+	// The May thread has gone quiet and the interval is at its maximum.
+	mayThread := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)}
+	for i := 0; i < 20; i++ {
+		a.Observe(clock.Now(), mayThread, 0)
+		<-clock.After(time.Hour)
+	}
+
+	// June 1st 2024 is a Saturday, so the thread is due on Monday the 3rd.
+	// A long interval is cut short to poll when it is due.
+	due := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	clock.now = time.Date(2024, 6, 2, 10, 0, 0, 0, time.UTC)
+	if got := a.Next(clock.Now()); !got.Equal(due) {
+		t.Errorf("Next() before the thread is due = %v, want %v", got, due)
+	}
+
+	// While due and not found, polls are at the minimum interval.
+	clock.now = due
+	for i := 0; i < 5; i++ {
+		a.Observe(clock.Now(), mayThread, 0)
+		next := a.Next(clock.Now())
+		if got := next.Sub(clock.Now()); got != options.MinInterval {
+			t.Fatalf("poll %d: interval = %v while the thread is overdue, want %v", i, got, options.MinInterval)
+		}
+		<-clock.After(next.Sub(clock.Now()))
+	}
+
+	// The June thread shows up and is fresh.
+	juneThread := clock.Now().Add(-time.Minute)
+	a.Observe(clock.Now(), juneThread, 30)
+	if got := a.Next(clock.Now()).Sub(clock.Now()); got != options.MinInterval {
+		t.Errorf("interval = %v for a fresh thread, want %v", got, options.MinInterval)
+	}
+
+	// An older thread does not replace the newest one seen.
+	a.Observe(clock.Now(), mayThread, 0)
+	if a.threadMonth != "2024-06" {
+		t.Errorf("threadMonth = %q, want 2024-06", a.threadMonth)
+	}
+}
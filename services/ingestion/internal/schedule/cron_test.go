@@ -0,0 +1,120 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	// 2024-10-04 is a Friday.
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		now  time.Time
+		want time.Time
+	}{
+		{"every minute", "* * * * *", at(10, 4, 10, 7).Add(30 * time.Second), at(10, 4, 10, 8)},
+		{"exact minute is skipped", "30 10 * * *", at(10, 4, 10, 30), at(10, 5, 10, 30)},
+		{"minute step", "*/15 * * * *", at(10, 4, 10, 7), at(10, 4, 10, 15)},
+		{"minute step wraps the hour", "*/15 * * * *", at(10, 4, 10, 45), at(10, 4, 11, 0)},
+		{"step from a value", "5/20 * * * *", at(10, 4, 10, 26), at(10, 4, 10, 45)},
+		{"list", "0 6,18 * * *", at(10, 4, 7, 0), at(10, 4, 18, 0)},
+		{"range with step", "0 9-17/4 * * *", at(10, 4, 13, 30), at(10, 4, 17, 0)},
+		{"weekday range skips the weekend", "0 9 * * 1-5", at(10, 4, 10, 0), at(10, 7, 9, 0)},
+		{"day of week 0 is Sunday", "0 0 * * 0", at(10, 4, 12, 0), at(10, 6, 0, 0)},
+		{"day of week 7 is Sunday", "0 0 * * 7", at(10, 4, 12, 0), at(10, 6, 0, 0)},
+		{"day of month", "0 12 15 * *", at(10, 16, 0, 0), at(11, 15, 12, 0)},
+		{"month rolls over the year", "0 0 1 1 *", at(10, 4, 0, 0), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"31st skips short months", "0 0 31 * *", at(10, 31, 1, 0), at(12, 31, 0, 0)},
+		{"leap day", "0 0 29 2 *", at(10, 4, 0, 0), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"restricted day fields fire on either: weekday first", "0 0 13 * 5", at(10, 5, 0, 0), at(10, 11, 0, 0)},
+		{"restricted day fields fire on either: date first", "0 0 13 * 5", at(10, 11, 0, 0), at(10, 13, 0, 0)},
+		{"wildcard day of month requires day of week", "0 0 * * 5", at(10, 5, 0, 0), at(10, 11, 0, 0)},
+		{"wildcard day of week requires day of month", "0 0 13 * *", at(10, 5, 0, 0), at(10, 13, 0, 0)},
+		{"stepped wildcard counts as wildcard", "0 0 */2 * 5", at(10, 5, 0, 0), at(10, 11, 0, 0)},
+		{"descriptor", "@hourly", at(10, 4, 10, 7), at(10, 4, 11, 0)},
+		{"weekly descriptor", "@weekly", at(10, 4, 10, 7), at(10, 6, 0, 0)},
+		{"matched in the location of now", "0 9 * * *", time.Date(2024, 10, 4, 10, 0, 0, 0, berlin), time.Date(2024, 10, 5, 9, 0, 0, 0, berlin)},
+		{"never fires on February 30th", "0 0 30 2 *", at(10, 4, 0, 0), time.Time{}},
+		{"never fires on April 31st", "0 0 31 4 *", at(10, 4, 0, 0), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", tt.expr, err)
+			}
+			if got := cron.Next(tt.now); !got.Equal(tt.want) {
+				t.Errorf("ParseCron(%q).Next(%v) = %v, want %v", tt.expr, tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-b * * * *",
+		"@reboot",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) error = nil, want an error", expr)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 10, 4, 10, 7, 0, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"", now.Add(time.Hour)},
+		{"@every 90s", now.Add(90 * time.Second)},
+		{"*/15 * * * *", time.Date(2024, 10, 4, 10, 15, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec, time.Hour, AdaptiveOptions{})
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+		}
+		if got := s.Next(now); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next() = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	if s, err := Parse("adaptive", time.Hour, AdaptiveOptions{}); err != nil {
+		t.Errorf("Parse(adaptive) error = %v", err)
+	} else if _, ok := s.(*Adaptive); !ok {
+		t.Errorf("Parse(adaptive) = %T, want *Adaptive", s)
+	}
+
+	for _, spec := range []string{"@every", "@every -1m", "@every soon", "not a cron"} {
+		if _, err := Parse(spec, time.Hour, AdaptiveOptions{}); err == nil {
+			t.Errorf("Parse(%q) error = nil, want an error", spec)
+		}
+	}
+}
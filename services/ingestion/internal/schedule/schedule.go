@@ -0,0 +1,57 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Clock is the source of time for scheduling. It is replaced in tests to
+// drive schedules without waiting.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RealClock is the wall clock.
+var RealClock Clock = realClock{}
+
+// Schedule decides when a source is fetched next.
+type Schedule interface {
+	// Next returns the first fetch time after now.
+	Next(now time.Time) time.Time
+}
+
+// Every fetches at a fixed interval.
+type Every time.Duration
+
+func (e Every) Next(now time.Time) time.Time {
+	return now.Add(time.Duration(e))
+}
+
+// Parse builds a schedule from spec. An empty spec falls back to a fixed
+// interval; "adaptive" selects an Adaptive schedule built from options;
+// "@every <duration>" is a fixed interval; anything else is read as a cron
+// expression.
+func Parse(spec string, interval time.Duration, options AdaptiveOptions) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	switch {
+	case spec == "":
+		return Every(interval), nil
+	case spec == "adaptive":
+		return NewAdaptive(options), nil
+	case strings.HasPrefix(spec, "@every "):
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid interval in %q", spec)
+		}
+		return Every(d), nil
+	default:
+		return ParseCron(spec)
+	}
+}
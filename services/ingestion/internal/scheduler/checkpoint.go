@@ -0,0 +1,364 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"shenanigigs/common/cache"
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultCheckpointTTL      = 24 * time.Hour
+	defaultCheckpointInterval = 5 * time.Second
+)
+
+// CheckpointStatus shows the progress saved by a run, and how much of an
+// interrupted earlier run it picked up.
+type CheckpointStatus struct {
+	Scope             string     `json:"scope"`
+	Resumed           bool       `json:"resumed"`
+	ResumedFrom       string     `json:"resumed_from,omitempty"`
+	StoriesCompleted  int        `json:"stories_completed"`
+	CommentsCompleted int        `json:"comments_completed"`
+	StoriesSkipped    int        `json:"stories_skipped"`
+	CommentsSkipped   int        `json:"comments_skipped"`
+	SavedAt           *time.Time `json:"saved_at,omitempty"`
+}
+
+// runCheckpoint tracks which stories and comments of a pass have been
+// handled so that a pass cut short by a crash or redeploy can be resumed by
+// the next run over the same scope. A story is completed once all of its
+// comments were handled without error. The checkpoint is dropped when a
+// pass completes; the next pass starts from scratch.
+//
+// All methods are safe to call on a nil checkpoint, which tracks nothing.
+type runCheckpoint struct {
+	cache  cache.Cache
+	logger *zap.Logger
+	key    string
+	ttl    time.Duration
+
+	mutex       sync.Mutex
+	state       *models.RunCheckpoint
+	resumed     bool
+	resumedFrom string
+	stories     map[int]bool
+	comments    map[int]map[int]bool
+	progress    map[int]*storyProgress
+	skipped     struct{ stories, comments int }
+	dirty       bool
+	savedAt     time.Time
+}
+
+type storyProgress struct {
+	outstanding int
+	failed      bool
+	dispatched  bool
+}
+
+func checkpointKey(scope string) string {
+	return fmt.Sprintf("hn:checkpoint:%s", scope)
+}
+
+// storiesScope names the pass over an explicit list of stories. The list is
+// hashed so that the key stays short for large backfills.
+func storiesScope(stories []int) string {
+	if len(stories) == 1 {
+		return fmt.Sprintf("stories:%d", stories[0])
+	}
+	sorted := append([]int{}, stories...)
+	sort.Ints(sorted)
+	sum := sha256.Sum256([]byte(fmt.Sprint(sorted)))
+	return "stories:" + hex.EncodeToString(sum[:8])
+}
+
+// loadCheckpoint returns the checkpoint of scope, resuming the one left by
+// an earlier run if there is one.
+func (s *JobScheduler) loadCheckpoint(ctx context.Context, scope string) *runCheckpoint {
+	ttl := s.config.CheckpointTTL
+	if ttl <= 0 {
+		ttl = defaultCheckpointTTL
+	}
+	c := &runCheckpoint{
+		cache:    s.cache,
+		logger:   s.logger,
+		key:      checkpointKey(scope),
+		ttl:      ttl,
+		state:    models.NewRunCheckpoint(scope, runIDFrom(ctx)),
+		stories:  make(map[int]bool),
+		comments: make(map[int]map[int]bool),
+		progress: make(map[int]*storyProgress),
+	}
+
+	var saved models.RunCheckpoint
+	err := s.cache.Get(ctx, c.key, &saved)
+	if err != nil {
+		if err != cache.ErrNotFound {
+			s.logger.Warn("failed to load run checkpoint, starting from scratch",
+				zap.String("scope", scope),
+				zap.Error(err))
+		}
+		return c
+	}
+
+	c.resumed = true
+	c.resumedFrom = saved.RunID
+	c.state.StartedAt = saved.StartedAt
+	for _, id := range saved.Stories {
+		c.stories[id] = true
+	}
+	for storyID, ids := range saved.Comments {
+		c.comments[storyID] = make(map[int]bool, len(ids))
+		for _, id := range ids {
+			c.comments[storyID][id] = true
+		}
+	}
+	s.logger.Info("resuming run from checkpoint",
+		zap.String("scope", scope),
+		zap.String("resumed_from", saved.RunID),
+		zap.Int("stories_completed", len(saved.Stories)),
+		zap.Time("updated_at", saved.UpdatedAt))
+	return c
+}
+
+// skipStory reports whether every comment of the story was handled by an
+// earlier run.
+func (c *runCheckpoint) skipStory(storyID int) bool {
+	if c == nil {
+		return false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.resumed || !c.stories[storyID] {
+		return false
+	}
+	c.skipped.stories++
+	return true
+}
+
+// skipComment reports whether the comment was handled by an earlier run.
+func (c *runCheckpoint) skipComment(storyID, commentID int) bool {
+	if c == nil {
+		return false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.resumed || !c.comments[storyID][commentID] {
+		return false
+	}
+	c.skipped.comments++
+	return true
+}
+
+// commentDispatched must be called before the comment is handed to a
+// worker, so that the story cannot complete before the comment does.
+func (c *runCheckpoint) commentDispatched(storyID int) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.story(storyID).outstanding++
+}
+
+// storyDispatched marks every comment of the story as handed to workers.
+func (c *runCheckpoint) storyDispatched(storyID int) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.story(storyID).dispatched = true
+	c.completeStory(storyID)
+}
+
+func (c *runCheckpoint) commentCompleted(storyID, commentID int) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.comments[storyID] == nil {
+		c.comments[storyID] = make(map[int]bool)
+	}
+	c.comments[storyID][commentID] = true
+	c.story(storyID).outstanding--
+	c.dirty = true
+	c.completeStory(storyID)
+}
+
+// commentFailed keeps the story from completing so that a resumed run
+// retries the comment.
+func (c *runCheckpoint) commentFailed(storyID int) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	progress := c.story(storyID)
+	progress.outstanding--
+	progress.failed = true
+}
+
+func (c *runCheckpoint) story(storyID int) *storyProgress {
+	progress, ok := c.progress[storyID]
+	if !ok {
+		progress = &storyProgress{}
+		c.progress[storyID] = progress
+	}
+	return progress
+}
+
+func (c *runCheckpoint) completeStory(storyID int) {
+	progress := c.progress[storyID]
+	if !progress.dispatched || progress.outstanding > 0 || progress.failed {
+		return
+	}
+	c.stories[storyID] = true
+	delete(c.comments, storyID)
+	c.dirty = true
+}
+
+// snapshot returns the state to save, or nil when nothing changed since the
+// last save.
+func (c *runCheckpoint) snapshot() *models.RunCheckpoint {
+	if c == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	c.dirty = false
+
+	state := *c.state
+	state.UpdatedAt = time.Now()
+	state.Stories = make([]int, 0, len(c.stories))
+	for id := range c.stories {
+		state.Stories = append(state.Stories, id)
+	}
+	sort.Ints(state.Stories)
+	state.Comments = make(map[int][]int, len(c.comments))
+	for storyID, comments := range c.comments {
+		ids := make([]int, 0, len(comments))
+		for id := range comments {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		state.Comments[storyID] = ids
+	}
+	return &state
+}
+
+func (c *runCheckpoint) save(ctx context.Context, state *models.RunCheckpoint) error {
+	if err := c.cache.Set(ctx, c.key, state, c.ttl); err != nil {
+		c.unsaved()
+		return err
+	}
+	c.mutex.Lock()
+	c.savedAt = state.UpdatedAt
+	c.mutex.Unlock()
+	return nil
+}
+
+// unsaved marks a snapshot that could not be saved, so that the next save
+// retries it.
+func (c *runCheckpoint) unsaved() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.dirty = true
+}
+
+// clear drops the checkpoint once the pass has completed.
+func (c *runCheckpoint) clear(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	if err := c.cache.Delete(ctx, c.key); err != nil && err != cache.ErrNotFound {
+		c.logger.Warn("failed to clear run checkpoint",
+			zap.String("key", c.key),
+			zap.Error(err))
+	}
+}
+
+func (c *runCheckpoint) status() CheckpointStatus {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	status := CheckpointStatus{
+		Scope:            c.state.Scope,
+		Resumed:          c.resumed,
+		ResumedFrom:      c.resumedFrom,
+		StoriesCompleted: len(c.stories),
+		StoriesSkipped:   c.skipped.stories,
+		CommentsSkipped:  c.skipped.comments,
+	}
+	for _, comments := range c.comments {
+		status.CommentsCompleted += len(comments)
+	}
+	if !c.savedAt.IsZero() {
+		savedAt := c.savedAt.UTC()
+		status.SavedAt = &savedAt
+	}
+	return status
+}
+
+// saveCheckpoint writes the progress of the run. The thread state is
+// flushed first, so that a comment is never recorded as handled while the
+// state of its publication could still be lost.
+func (s *JobScheduler) saveCheckpoint(ctx context.Context, run *processingRun) error {
+	state := run.checkpoint.snapshot()
+	if err := run.threads.flush(ctx); err != nil {
+		if state != nil {
+			run.checkpoint.unsaved()
+		}
+		return err
+	}
+	if state == nil {
+		return nil
+	}
+	return run.checkpoint.save(ctx, state)
+}
+
+// saveCheckpoints saves the progress of the run periodically until the
+// returned function is called.
+func (s *JobScheduler) saveCheckpoints(ctx context.Context, run *processingRun) func() {
+	if run.checkpoint == nil {
+		return func() {}
+	}
+	interval := s.config.CheckpointInterval
+	if interval <= 0 {
+		interval = defaultCheckpointInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.saveCheckpoint(ctx, run); err != nil && ctx.Err() == nil {
+					s.logger.Warn("failed to save run checkpoint", zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,292 @@
+package scheduler
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"shenanigigs/common/cache"
+	"shenanigigs/common/telemetry"
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/models"
+	"shenanigigs/ingestion/internal/sources"
+
+	"go.uber.org/zap"
+)
+
+const maxParentDepth = 64
+
+// ErrRunInProgress is returned when a run is requested for a source that is
+// already being fetched.
+var ErrRunInProgress = stderrors.New("a run for this source is already in progress")
+
+// pausedFlag is the value stored under config.PauseKey while paused.
+const pausedFlag = "1"
+
+// Pause stops scheduled fetches on every replica. The flag is kept in the
+// cache under config.PauseKey so it reaches the leader whichever replica
+// served the request, and survives restarts.
+func (s *JobScheduler) Pause(ctx context.Context) error {
+	if err := s.cache.Set(ctx, s.config.PauseKey, pausedFlag, s.config.PauseTTL); err != nil {
+		return errors.Unavailable("failed to store pause flag", err)
+	}
+	if !s.paused.Swap(true) {
+		s.logger.Info("polling paused")
+	}
+	return nil
+}
+
+func (s *JobScheduler) Resume(ctx context.Context) error {
+	if err := s.cache.Delete(ctx, s.config.PauseKey); err != nil {
+		return errors.Unavailable("failed to clear pause flag", err)
+	}
+	if s.paused.Swap(false) {
+		s.logger.Info("polling resumed")
+	}
+	return nil
+}
+
+// Paused reports whether scheduled fetches are skipped. On-demand runs are
+// allowed while paused. When the flag cannot be read, the last value seen
+// by this replica is used.
+func (s *JobScheduler) Paused(ctx context.Context) bool {
+	var flag string
+	err := s.cache.Get(ctx, s.config.PauseKey, &flag)
+	paused := flag == pausedFlag
+	switch {
+	case err == nil:
+	case stderrors.Is(err, cache.ErrNotFound):
+		paused = false
+	default:
+		s.logger.Warn("failed to read pause flag, using last known value", zap.Error(err))
+		return s.paused.Load()
+	}
+
+	if s.paused.Swap(paused) != paused {
+		if paused {
+			s.logger.Info("polling paused")
+		} else {
+			s.logger.Info("polling resumed")
+		}
+	}
+	return paused
+}
+
+// TriggerRun fetches the named source now, in the background, and returns
+// the new run. The run stops when ctx is cancelled.
+func (s *JobScheduler) TriggerRun(ctx context.Context, name string) (RunStatus, error) {
+	source, err := s.source(name)
+	if err != nil {
+		return RunStatus{}, err
+	}
+	return s.startRun(ctx, name, "", func(ctx context.Context) error {
+		return s.fetchSource(ctx, source)
+	})
+}
+
+// RunOnce fetches the named source now and blocks until the run is done.
+func (s *JobScheduler) RunOnce(ctx context.Context, name string) (RunStatus, error) {
+	source, err := s.source(name)
+	if err != nil {
+		return RunStatus{}, err
+	}
+	record, err := s.beginRun(name, TriggerManual, "")
+	if err != nil {
+		return RunStatus{}, err
+	}
+	err = s.completeRun(ctx, record, func(ctx context.Context) error {
+		return s.fetchSource(ctx, source)
+	})
+	return record.snapshot(), err
+}
+
+// IngestThread processes a single HN thread in the background.
+func (s *JobScheduler) IngestThread(ctx context.Context, threadID int) (RunStatus, error) {
+	return s.startRun(ctx, models.SourceHackerNews, fmt.Sprintf("thread:%d", threadID), func(ctx context.Context) error {
+		_, err := s.ProcessStories(ctx, models.IntSlice{threadID})
+		return err
+	})
+}
+
+// IngestItem processes a single HN item in the background. A story is
+// processed as a thread; a comment is traced back to its top-level comment,
+// which is processed on its own.
+func (s *JobScheduler) IngestItem(ctx context.Context, itemID int) (RunStatus, error) {
+	return s.startRun(ctx, models.SourceHackerNews, fmt.Sprintf("item:%d", itemID), func(ctx context.Context) error {
+		_, err := s.ProcessItem(ctx, itemID)
+		return err
+	})
+}
+
+func (s *JobScheduler) CurrentRuns() []RunStatus {
+	return s.runs.current()
+}
+
+func (s *JobScheduler) RecentRuns(limit int) []RunStatus {
+	return s.runs.history(limit)
+}
+
+func (s *JobScheduler) Run(id string) (RunStatus, bool) {
+	return s.runs.get(id)
+}
+
+// ProcessItem processes one HN item and blocks until it is done.
+func (s *JobScheduler) ProcessItem(ctx context.Context, itemID int) (RunStats, error) {
+	ctx, span := tracer.Start(ctx, "JobScheduler.ProcessItem")
+	defer span.End()
+
+	item, err := s.hnClient.GetItem(ctx, itemID)
+	if err != nil {
+		span.RecordError(err)
+		return RunStats{}, err
+	}
+	if item.Type == "story" {
+		return s.ProcessStories(ctx, models.IntSlice{item.ID})
+	}
+
+	comment := item
+	var story *models.SourcePost
+	for depth := 0; story == nil; depth++ {
+		if depth >= maxParentDepth || comment.Parent == 0 {
+			return RunStats{}, errors.InvalidInput(fmt.Sprintf("item %d is not part of a story", itemID), nil)
+		}
+		parent, err := s.hnClient.GetItem(ctx, comment.Parent)
+		if err != nil {
+			span.RecordError(err)
+			return RunStats{}, err
+		}
+		if parent.Type == "story" {
+			story = parent
+		} else {
+			comment = parent
+		}
+	}
+
+	threadType, ok := models.ClassifyThread(story)
+	if !ok {
+		return RunStats{}, errors.InvalidInput(fmt.Sprintf("item %d is not part of a whoishiring thread", itemID), nil)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	run := s.newProcessingRun(ctx, sources.WithSource(s.publisher, models.SourceHackerNews), cancel)
+	run.stats.threadFound(story, threadType)
+	run.threads.load(runCtx, story.ID)
+
+	task := commentTask{
+		commentID:   comment.ID,
+		threadID:    story.ID,
+		threadType:  threadType,
+		threadMonth: story.ThreadMonth(),
+		fresh:       true,
+	}
+	result, err := s.processComment(runCtx, task, run)
+	if err != nil {
+		span.RecordError(err)
+		run.stats.commentFailed(err)
+	} else {
+		run.stats.commentDone(result)
+	}
+
+	if ferr := run.threads.flush(context.WithoutCancel(ctx)); ferr != nil && err == nil {
+		err = errors.Unavailable("failed to save thread state", ferr)
+	}
+	s.flushArchive()
+
+	return run.stats.snapshot(), err
+}
+
+func (s *JobScheduler) source(name string) (sources.JobSource, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if source, ok := s.sources[name]; ok {
+		return source, nil
+	}
+	built, err := s.registry.Build([]string{name})
+	if err != nil {
+		return nil, errors.InvalidInput(fmt.Sprintf("unknown job source %q", name), err)
+	}
+	s.sources[name] = built[0]
+	return built[0], nil
+}
+
+func (s *JobScheduler) startRun(ctx context.Context, source, target string, fn func(context.Context) error) (RunStatus, error) {
+	record, err := s.beginRun(source, TriggerManual, target)
+	if err != nil {
+		return RunStatus{}, err
+	}
+	go func() {
+		if err := s.completeRun(ctx, record, fn); err != nil {
+			s.logger.Error("on-demand run failed",
+				zap.String("source", source),
+				zap.String("target", target),
+				zap.Error(err))
+		}
+	}()
+	return record.snapshot(), nil
+}
+
+// beginRun reserves the source so that only one run fetches it at a time.
+func (s *JobScheduler) beginRun(source, trigger, target string) (*runRecord, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped() {
+		return nil, ErrStopping
+	}
+	if s.running[source] {
+		return nil, ErrRunInProgress
+	}
+	s.running[source] = true
+	s.inflight.Add(1)
+	return s.runs.start(source, trigger, target), nil
+}
+
+// completeRun runs fn for a run reserved by beginRun and records the result.
+func (s *JobScheduler) completeRun(ctx context.Context, record *runRecord, fn func(context.Context) error) error {
+	defer s.inflight.Done()
+
+	workCtx, cancel := s.workContext(ctx)
+	started := record.snapshot()
+	workCtx, span := tracer.Start(workCtx, "JobScheduler.run")
+	span.SetAttributes(
+		telemetry.String("run.id", started.ID),
+		telemetry.String("run.source", started.Source),
+		telemetry.String("run.trigger", started.Trigger),
+	)
+	if s.config.LeaderElection {
+		span.SetAttributes(telemetry.String("leader.id", s.config.InstanceID))
+	}
+	err := fn(withRunRecord(workCtx, record))
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+	cancel()
+	status := s.runs.finish(record, err)
+
+	s.mutex.Lock()
+	delete(s.running, status.Source)
+	s.mutex.Unlock()
+
+	s.logger.Info("run finished",
+		zap.String("run_id", status.ID),
+		zap.String("source", status.Source),
+		zap.String("trigger", status.Trigger),
+		zap.String("state", status.State))
+	s.recordRun(ctx, status)
+	return err
+}
+
+// recordRun saves a finished run to the run history, if one is configured.
+// A run that could not be recorded is still a finished run, so failures are
+// only logged.
+func (s *JobScheduler) recordRun(ctx context.Context, status RunStatus) {
+	if s.history == nil {
+		return
+	}
+	if err := s.history.RecordRun(context.WithoutCancel(ctx), status); err != nil {
+		s.logger.Warn("failed to record run", zap.String("run_id", status.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shenanigigs/common/cache"
+	"shenanigigs/common/cache/redis"
+	"shenanigigs/ingestion/internal/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.uber.org/zap"
+)
+
+func TestPauseIsShared(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	cfg := &config.Config{PauseKey: "ingestion:polling:paused", PauseTTL: time.Hour}
+
+	newReplica := func() *JobScheduler {
+		redisCache := redis.New(cache.Options{RedisURL: server.Addr()})
+		t.Cleanup(func() { redisCache.Close() })
+		return &JobScheduler{cache: redisCache, config: cfg, logger: zap.NewNop()}
+	}
+	leader, follower := newReplica(), newReplica()
+
+	if leader.Paused(ctx) {
+		t.Fatal("Paused() = true before pausing")
+	}
+	if err := follower.Pause(ctx); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if !leader.Paused(ctx) {
+		t.Error("Paused() on the leader = false after a follower paused")
+	}
+	if ttl := server.TTL(cfg.PauseKey); ttl != time.Hour {
+		t.Errorf("pause flag TTL = %v, want 1h", ttl)
+	}
+
+	if err := follower.Resume(ctx); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if leader.Paused(ctx) {
+		t.Error("Paused() on the leader = true after a follower resumed")
+	}
+}
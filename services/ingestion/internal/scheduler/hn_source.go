@@ -0,0 +1,27 @@
+package scheduler
+
+import (
+	"context"
+
+	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/models"
+	"shenanigigs/ingestion/internal/sources"
+)
+
+// hackerNewsSource exposes the "Who is hiring?" story and comment pipeline
+// as a sources.JobSource.
+type hackerNewsSource struct {
+	scheduler *JobScheduler
+}
+
+func (s *JobScheduler) newHackerNewsSource() (sources.JobSource, error) {
+	return &hackerNewsSource{scheduler: s}, nil
+}
+
+func (h *hackerNewsSource) Name() string {
+	return models.SourceHackerNews
+}
+
+func (h *hackerNewsSource) Fetch(ctx context.Context, publisher messaging.Publisher) error {
+	return h.scheduler.fetchWhoIsHiring(ctx, publisher)
+}
@@ -2,14 +2,22 @@ package scheduler
 
 import (
 	"context"
+	stderrors "errors"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"shenanigigs/common/cache"
 	"shenanigigs/common/telemetry"
 	"shenanigigs/ingestion/internal/api"
+	"shenanigigs/ingestion/internal/archive"
 	"shenanigigs/ingestion/internal/config"
 	"shenanigigs/ingestion/internal/errors"
 	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/models"
+	"shenanigigs/ingestion/internal/schedule"
+	"shenanigigs/ingestion/internal/sources"
 
 	"go.uber.org/zap"
 )
@@ -19,23 +27,55 @@ var tracer = telemetry.GetTracer("shenanigigs/ingestion/scheduler")
 type JobScheduler struct {
 	hnClient       api.JobSourceClient
 	publisher      messaging.Publisher
+	cache          cache.Cache
+	threadStore    ThreadStore
+	registry       *sources.Registry
+	archive        *archive.Archive
+	history        RunRecorder
+	queue          CommentQueue
 	logger         *zap.Logger
+	clock          schedule.Clock
 	config         *config.Config
 	mutex          sync.Mutex
 	isActive       bool
+	paused         atomic.Bool
+	sources        map[string]sources.JobSource
+	running        map[string]bool
+	runs           *runTracker
+	stopping       chan struct{}
+	stopOnce       sync.Once
+	inflight       sync.WaitGroup
+	abandonCtx     context.Context
+	abandon        context.CancelFunc
 	workerManager  *workerManager
 	storyProcessor *storyProcessor
 }
 
-func NewJobScheduler(hnClient api.JobSourceClient, publisher messaging.Publisher, logger *zap.Logger, config *config.Config) *JobScheduler {
+// NewJobScheduler creates a scheduler that runs the sources enabled in
+// config. The built-in Hacker News source is added to registry. Every item
+// fetched from HN is written to archive, which may be nil. Published
+// comments are recorded in threadStore.
+func NewJobScheduler(hnClient api.JobSourceClient, publisher messaging.Publisher, cache cache.Cache, threadStore ThreadStore, registry *sources.Registry, archive *archive.Archive, history RunRecorder, logger *zap.Logger, config *config.Config) *JobScheduler {
 	scheduler := &JobScheduler{
-		hnClient:  hnClient,
-		publisher: publisher,
-		logger:    logger,
-		config:    config,
+		hnClient:    hnClient,
+		publisher:   publisher,
+		cache:       cache,
+		threadStore: threadStore,
+		registry:    registry,
+		archive:     archive,
+		history:     history,
+		logger:      logger,
+		clock:       schedule.RealClock,
+		config:      config,
+		sources:     make(map[string]sources.JobSource),
+		running:     make(map[string]bool),
+		runs:        newRunTracker(),
+		stopping:    make(chan struct{}),
 	}
+	scheduler.abandonCtx, scheduler.abandon = context.WithCancel(context.Background())
 	scheduler.workerManager = newWorkerManager(scheduler, logger)
 	scheduler.storyProcessor = newStoryProcessor(scheduler, logger)
+	registry.Register(models.SourceHackerNews, scheduler.newHackerNewsSource)
 	return scheduler
 }
 
@@ -43,6 +83,10 @@ func (s *JobScheduler) Start(ctx context.Context) error {
 	ctx, span := tracer.Start(ctx, "JobScheduler.Start")
 	defer span.End()
 
+	if s.stopped() {
+		return ErrStopping
+	}
+
 	s.mutex.Lock()
 	if s.isActive {
 		s.mutex.Unlock()
@@ -50,38 +94,306 @@ func (s *JobScheduler) Start(ctx context.Context) error {
 	}
 	s.isActive = true
 	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		s.isActive = false
+		s.mutex.Unlock()
+	}()
+
+	enabled, err := s.registry.Build(s.config.Sources)
+	if err == nil && len(enabled) == 0 {
+		err = errors.InvalidInput("no job sources enabled", nil)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return errors.InvalidInput("building job sources", err)
+	}
+
+	schedules := make(map[string]schedule.Schedule, len(enabled))
+	for _, source := range enabled {
+		sched, err := s.sourceSchedule(source.Name())
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+		schedules[source.Name()] = sched
+	}
+
+	s.mutex.Lock()
+	for _, source := range enabled {
+		s.sources[source.Name()] = source
+	}
+	s.mutex.Unlock()
+
+	var wg sync.WaitGroup
+	for _, source := range enabled {
+		wg.Add(1)
+		go func(source sources.JobSource) {
+			defer wg.Done()
+			s.runSource(ctx, source, schedules[source.Name()])
+		}(source)
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
 
-	ticker := time.NewTicker(s.config.PollingInterval)
-	defer ticker.Stop()
+// SetClock replaces the clock used to schedule fetches. It must be called
+// before Start.
+func (s *JobScheduler) SetClock(clock schedule.Clock) {
+	s.clock = clock
+}
 
-	if err := s.fetchWhoIsHiring(ctx); err != nil {
-		s.logger.Error("initial fetch failed", zap.Error(err))
+func (s *JobScheduler) sourceSchedule(name string) (schedule.Schedule, error) {
+	spec := s.config.SourceSchedule(name)
+	sched, err := schedule.Parse(spec, s.config.SourceInterval(name), schedule.AdaptiveOptions{
+		MinInterval:    s.config.AdaptiveMinInterval,
+		MaxInterval:    s.config.AdaptiveMaxInterval,
+		FreshFor:       s.config.AdaptiveFreshFor,
+		TargetComments: s.config.AdaptiveTargetComments,
+	})
+	if err != nil {
+		return nil, errors.InvalidInput("invalid schedule for source "+name, err)
 	}
+	return sched, nil
+}
+
+// runObserver is implemented by schedules that adapt to the outcome of each
+// run.
+type runObserver interface {
+	Observe(now, threadPostedAt time.Time, newComments int)
+}
+
+// newComments is the number of comments source published in a run. With a
+// comment queue, Hacker News runs only queue comments, and the count is what
+// consumers published since the previous run.
+func (s *JobScheduler) newComments(ctx context.Context, source sources.JobSource, status RunStatus) int {
+	n := status.Stats.CommentsProcessed
+	if s.queue == nil || source.Name() != models.SourceHackerNews {
+		return n
+	}
+	published, err := s.queue.TakePublished(ctx)
+	if err != nil {
+		s.logger.Warn("failed to read published comment count", zap.Error(err))
+	}
+	return n + published
+}
+
+// runSource fetches from source immediately and then whenever its schedule
+// says so, until ctx is cancelled.
+func (s *JobScheduler) runSource(ctx context.Context, source sources.JobSource, sched schedule.Schedule) {
+	s.logger.Info("starting job source",
+		zap.String("source", source.Name()),
+		zap.String("schedule", s.config.SourceSchedule(source.Name())),
+		zap.Duration("interval", s.config.SourceInterval(source.Name())))
 
 	for {
+		if status, ok := s.pollSource(ctx, source); ok {
+			if observer, ok := sched.(runObserver); ok {
+				observer.Observe(s.clock.Now(), newestThread(status.Threads), s.newComments(ctx, source, status))
+			}
+		}
+
+		now := s.clock.Now()
+		next := sched.Next(now)
+		if next.IsZero() {
+			s.logger.Error("schedule never fires again, stopping source", zap.String("source", source.Name()))
+			return
+		}
+		s.logger.Debug("next scheduled fetch",
+			zap.String("source", source.Name()),
+			zap.Time("at", next))
+
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-			if err := s.fetchWhoIsHiring(ctx); err != nil {
-				s.logger.Error("periodic fetch failed", zap.Error(err))
-			}
+			return
+		case <-s.stopping:
+			return
+		case <-s.clock.After(next.Sub(now)):
+		}
+	}
+}
+
+func newestThread(threads []ThreadRef) time.Time {
+	var newest time.Time
+	for _, thread := range threads {
+		if thread.PostedAt.After(newest) {
+			newest = thread.PostedAt
 		}
 	}
+	return newest
 }
 
-func (s *JobScheduler) Stop() {
+// pollSource runs a scheduled fetch unless polling is paused or the source
+// is already being fetched on demand. It reports whether a run took place.
+func (s *JobScheduler) pollSource(ctx context.Context, source sources.JobSource) (RunStatus, bool) {
+	if s.stopped() {
+		return RunStatus{}, false
+	}
+	if s.Paused(ctx) {
+		s.logger.Debug("polling paused, skipping fetch", zap.String("source", source.Name()))
+		return RunStatus{}, false
+	}
+
+	record, err := s.beginRun(source.Name(), TriggerSchedule, "")
+	if err != nil {
+		s.logger.Info("skipping scheduled fetch", zap.String("source", source.Name()), zap.Error(err))
+		return RunStatus{}, false
+	}
+	if err := s.completeRun(ctx, record, func(ctx context.Context) error {
+		return s.fetchSource(ctx, source)
+	}); err != nil {
+		s.logger.Error("scheduled fetch failed", zap.String("source", source.Name()), zap.Error(err))
+	}
+	return record.snapshot(), true
+}
+
+func (s *JobScheduler) fetchSource(ctx context.Context, source sources.JobSource) error {
+	ctx, span := tracer.Start(ctx, "JobScheduler.fetchSource")
+	defer span.End()
+	span.SetAttributes(telemetry.String("source.name", source.Name()))
+
+	if err := source.Fetch(ctx, sources.WithSource(s.publisher, source.Name())); err != nil {
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+type jobProcessingStats struct {
+	hiringThreadsFound    int32
+	candidateThreadsFound int32
+	freelanceThreadsFound int32
+	commentsDispatched    int32
+	commentsQueued        int32
+	commentsFetched       int32
+	commentsProcessed     int32
+	commentsSkipped       int32
+	commentsRemoved       int32
+	storiesFailed         int32
+	commentsFailed        int32
+
+	mutex        sync.Mutex
+	threads      []ThreadRef
+	errorSamples []string
+}
+
+type RunStats struct {
+	HiringThreadsFound    int `json:"hiring_threads_found"`
+	CandidateThreadsFound int `json:"candidate_threads_found"`
+	FreelanceThreadsFound int `json:"freelance_threads_found"`
+	CommentsQueued        int `json:"comments_queued"`
+	CommentsFetched       int `json:"comments_fetched"`
+	CommentsProcessed     int `json:"comments_processed"`
+	CommentsSkipped       int `json:"comments_skipped"`
+	CommentsRemoved       int `json:"comments_removed"`
+	StoriesFailed         int `json:"stories_failed"`
+	CommentsFailed        int `json:"comments_failed"`
+}
+
+func (s *jobProcessingStats) snapshot() RunStats {
+	return RunStats{
+		HiringThreadsFound:    int(atomic.LoadInt32(&s.hiringThreadsFound)),
+		CandidateThreadsFound: int(atomic.LoadInt32(&s.candidateThreadsFound)),
+		FreelanceThreadsFound: int(atomic.LoadInt32(&s.freelanceThreadsFound)),
+		CommentsQueued:        int(atomic.LoadInt32(&s.commentsQueued)),
+		CommentsFetched:       int(atomic.LoadInt32(&s.commentsFetched)),
+		CommentsProcessed:     int(atomic.LoadInt32(&s.commentsProcessed)),
+		CommentsSkipped:       int(atomic.LoadInt32(&s.commentsSkipped)),
+		CommentsRemoved:       int(atomic.LoadInt32(&s.commentsRemoved)),
+		StoriesFailed:         int(atomic.LoadInt32(&s.storiesFailed)),
+		CommentsFailed:        int(atomic.LoadInt32(&s.commentsFailed)),
+	}
+}
+
+func (s *jobProcessingStats) commentDispatched() {
+	atomic.AddInt32(&s.commentsDispatched, 1)
+}
+
+// pending counts the comments handed to workers that are not finished yet.
+func (s *jobProcessingStats) pending() int {
+	finished := atomic.LoadInt32(&s.commentsQueued) +
+		atomic.LoadInt32(&s.commentsProcessed) +
+		atomic.LoadInt32(&s.commentsSkipped) +
+		atomic.LoadInt32(&s.commentsRemoved) +
+		atomic.LoadInt32(&s.commentsFailed)
+	return int(atomic.LoadInt32(&s.commentsDispatched) - finished)
+}
+
+func (s *jobProcessingStats) commentQueued() {
+	atomic.AddInt32(&s.commentsQueued, 1)
+}
+
+func (s *jobProcessingStats) commentFetched() {
+	atomic.AddInt32(&s.commentsFetched, 1)
+}
+
+func (s *jobProcessingStats) commentDone(result commentResult) {
+	switch result {
+	case commentPublished:
+		atomic.AddInt32(&s.commentsProcessed, 1)
+	case commentRemoved:
+		atomic.AddInt32(&s.commentsRemoved, 1)
+	default:
+		atomic.AddInt32(&s.commentsSkipped, 1)
+	}
+}
+
+func (s *jobProcessingStats) commentFailed(err error) {
+	atomic.AddInt32(&s.commentsFailed, 1)
+	s.sampleError(err)
+}
+
+func (s *jobProcessingStats) storyFailed(err error) {
+	atomic.AddInt32(&s.storiesFailed, 1)
+	s.sampleError(err)
+}
+
+// sampleError keeps the first few errors of a run so that a failed run can
+// be diagnosed from its history.
+func (s *jobProcessingStats) sampleError(err error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.isActive = false
+	if len(s.errorSamples) < maxErrorSamples {
+		s.errorSamples = append(s.errorSamples, err.Error())
+	}
 }
 
-type jobProcessingStats struct {
-	hiringThreadsFound int32
-	commentsProcessed  int32
+func (s *jobProcessingStats) threadFound(post *models.SourcePost, threadType models.ThreadType) {
+	s.mutex.Lock()
+	s.threads = append(s.threads, ThreadRef{
+		ID:       post.ID,
+		Type:     string(threadType),
+		Month:    post.ThreadMonth(),
+		PostedAt: time.Unix(post.Time, 0).UTC(),
+	})
+	s.mutex.Unlock()
+
+	switch threadType {
+	case models.ThreadTypeHiring:
+		atomic.AddInt32(&s.hiringThreadsFound, 1)
+	case models.ThreadTypeCandidates:
+		atomic.AddInt32(&s.candidateThreadsFound, 1)
+	case models.ThreadTypeFreelance:
+		atomic.AddInt32(&s.freelanceThreadsFound, 1)
+	}
 }
 
-func (s *JobScheduler) fetchWhoIsHiring(ctx context.Context) error {
+// threadTypeEnabled reports whether comments of the thread type should be
+// ingested. Only hiring threads are ingested when none are configured.
+func (s *JobScheduler) threadTypeEnabled(threadType models.ThreadType) bool {
+	if len(s.config.HNThreadTypes) == 0 {
+		return threadType == models.ThreadTypeHiring
+	}
+	for _, enabled := range s.config.HNThreadTypes {
+		if models.ThreadType(enabled) == threadType {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *JobScheduler) fetchWhoIsHiring(ctx context.Context, publisher messaging.Publisher) error {
 	ctx, span := tracer.Start(ctx, "JobScheduler.fetchWhoIsHiring")
 	defer span.End()
 
@@ -89,39 +401,178 @@ func (s *JobScheduler) fetchWhoIsHiring(ctx context.Context) error {
 	stories, err := s.hnClient.SearchHiringThreads(ctx)
 	if err != nil {
 		span.RecordError(err)
+		if errors.HasType(err, errors.ErrTypeUnavailable) {
+			return errors.Unavailable("failed to search hiring threads", err)
+		}
 		return errors.Internal("failed to search hiring threads", err)
 	}
 	span.SetAttributes(telemetry.Int("stories.count", len(stories)))
 	s.logger.Info("found hiring threads", zap.Int("count", len(stories)))
 
-	stats := &jobProcessingStats{}
+	_, err = s.processStories(ctx, stories, publisher, models.SourceHackerNews)
+	return err
+}
+
+// ProcessStories runs the given story IDs through the story and comment
+// pipeline and blocks until every comment has been handled.
+func (s *JobScheduler) ProcessStories(ctx context.Context, stories models.IntSlice) (RunStats, error) {
+	ctx, span := tracer.Start(ctx, "JobScheduler.ProcessStories")
+	defer span.End()
+	span.SetAttributes(telemetry.Int("stories.count", len(stories)))
+
+	publisher := sources.WithSource(s.publisher, models.SourceHackerNews)
+	stats, err := s.processStories(ctx, stories, publisher, storiesScope(stories))
+	if err != nil {
+		span.RecordError(err)
+	}
+	return stats.snapshot(), err
+}
+
+// processingRun carries the state shared by the workers of a single pass
+// over a set of stories.
+type processingRun struct {
+	stats      *jobProcessingStats
+	threads    *threadTracker
+	checkpoint *runCheckpoint
+	publisher  messaging.Publisher
+	cancel     context.CancelFunc
+
+	mutex    sync.Mutex
+	abortErr error
+}
+
+// abort ends the run early. The run context is cancelled so workers drain
+// their channels without doing further work, and err becomes the result of
+// the run.
+func (r *processingRun) abort(err error) {
+	r.mutex.Lock()
+	if r.abortErr == nil {
+		r.abortErr = err
+	}
+	r.mutex.Unlock()
+	r.cancel()
+}
+
+func (r *processingRun) err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.abortErr
+}
+
+// abortIfUnavailable aborts the run when err shows that a job source's
+// circuit breaker is open, since every further fetch would fail as well.
+func (r *processingRun) abortIfUnavailable(err error) {
+	if stderrors.Is(err, api.ErrCircuitOpen) {
+		r.abort(errors.Unavailable("job source unavailable, ending run early", err))
+	}
+}
+
+type commentResult int
+
+const (
+	commentSkipped commentResult = iota
+	commentPublished
+	commentRemoved
+)
+
+type commentTask struct {
+	commentID   int
+	threadID    int
+	threadType  models.ThreadType
+	threadMonth string
+	// fresh fetches the comment from the API rather than the cache, because
+	// the thread's kids changed since it was last fetched.
+	fresh bool
+}
+
+// processStories checkpoints its progress under scope, and resumes from the
+// checkpoint left by an earlier pass over the same scope that did not
+// complete.
+func (s *JobScheduler) processStories(ctx context.Context, stories models.IntSlice, publisher messaging.Publisher, scope string) (*jobProcessingStats, error) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	run := s.newProcessingRun(ctx, publisher, cancel)
+	run.checkpoint = s.loadCheckpoint(ctx, scope)
+	attachCheckpoint(ctx, run.checkpoint)
+	stopCheckpoints := s.saveCheckpoints(runCtx, run)
 	storyChan := make(chan int)
-	commentChan := make(chan int)
+	commentChan := make(chan commentTask)
 	doneChan := make(chan bool)
 
-	wg := s.startWorkers(ctx, stats, storyChan, commentChan, doneChan)
+	wg := s.startWorkers(runCtx, run, storyChan, commentChan, doneChan)
 
-	go s.feedStories(stories, storyChan)
+	go s.feedStories(runCtx, stories, storyChan)
 
 	go func() {
 		wg.Wait()
-		close(commentChan)
 		close(doneChan)
 	}()
 
-	return s.waitForCompletion(ctx, doneChan, stats)
+	err := s.waitForCompletion(ctx, doneChan, run.stats)
+	stopCheckpoints()
+	if abortErr := run.err(); abortErr != nil && err == nil {
+		s.logger.Error("run ended early", zap.Error(abortErr))
+		err = abortErr
+	}
+
+	// The run context may already be cancelled; the state of the comments
+	// that were published still needs to be saved. A run that did not
+	// complete leaves a checkpoint for the next one to resume from.
+	saveCtx := context.WithoutCancel(ctx)
+	if err != nil {
+		if cerr := s.saveCheckpoint(saveCtx, run); cerr != nil {
+			s.logger.Error("failed to save run checkpoint", zap.Error(cerr))
+		}
+	} else if ferr := run.threads.flush(saveCtx); ferr != nil {
+		err = errors.Unavailable("failed to save thread state", ferr)
+	} else {
+		run.checkpoint.clear(saveCtx)
+	}
+
+	s.flushArchive()
+
+	return run.stats, err
+}
+
+func (s *JobScheduler) newProcessingRun(ctx context.Context, publisher messaging.Publisher, cancel context.CancelFunc) *processingRun {
+	run := &processingRun{
+		stats:     &jobProcessingStats{},
+		threads:   newThreadTracker(s.threadStore, s.logger, s.config.ThreadStateTTL),
+		publisher: publisher,
+		cancel:    cancel,
+	}
+	attachStats(ctx, run.stats)
+	return run
+}
+
+func (s *JobScheduler) flushArchive() {
+	if err := s.archive.Flush(); err != nil {
+		s.logger.Warn("failed to flush item archive", zap.Error(err))
+	}
+}
+
+// archiveItem records a fetched item. Archiving is best effort and never
+// fails the run.
+func (s *JobScheduler) archiveItem(threadID int, item *models.SourcePost) {
+	if err := s.archive.Append(threadID, item); err != nil {
+		s.logger.Warn("failed to archive item",
+			zap.Int("id", item.ID),
+			zap.Int("thread_id", threadID),
+			zap.Error(err))
+	}
 }
 
-func (s *JobScheduler) startWorkers(ctx context.Context, stats *jobProcessingStats, storyChan chan int, commentChan chan int, doneChan chan bool) *sync.WaitGroup {
-	return s.workerManager.startWorkers(ctx, stats, storyChan, commentChan, doneChan)
+func (s *JobScheduler) startWorkers(ctx context.Context, run *processingRun, storyChan chan int, commentChan chan commentTask, doneChan chan bool) *sync.WaitGroup {
+	return s.workerManager.startWorkers(ctx, run, storyChan, commentChan, doneChan)
 }
 
-func (s *JobScheduler) processStory(ctx context.Context, id int, stats *jobProcessingStats, commentChan chan int) {
-	s.storyProcessor.processStory(ctx, id, stats, commentChan)
+func (s *JobScheduler) processStory(ctx context.Context, id int, run *processingRun, commentChan chan commentTask) {
+	s.storyProcessor.processStory(ctx, id, run, commentChan)
 }
 
-func (s *JobScheduler) feedStories(stories []int, storyChan chan int) {
-	s.storyProcessor.feedStories(stories, storyChan)
+func (s *JobScheduler) feedStories(ctx context.Context, stories []int, storyChan chan int) {
+	s.storyProcessor.feedStories(ctx, stories, storyChan)
 }
 
 func (s *JobScheduler) waitForCompletion(ctx context.Context, doneChan chan bool, stats *jobProcessingStats) error {
@@ -135,35 +586,168 @@ func (s *JobScheduler) waitForCompletion(ctx context.Context, doneChan chan bool
 	case <-doneChan:
 		span.SetAttributes(
 			telemetry.Int("hiring_threads_found", int(stats.hiringThreadsFound)),
+			telemetry.Int("candidate_threads_found", int(stats.candidateThreadsFound)),
+			telemetry.Int("freelance_threads_found", int(stats.freelanceThreadsFound)),
+			telemetry.Int("comments_queued", int(stats.commentsQueued)),
+			telemetry.Int("comments_fetched", int(stats.commentsFetched)),
 			telemetry.Int("comments_processed", int(stats.commentsProcessed)),
+			telemetry.Int("comments_skipped", int(stats.commentsSkipped)),
+			telemetry.Int("comments_removed", int(stats.commentsRemoved)),
+			telemetry.Int("stories_failed", int(stats.storiesFailed)),
+			telemetry.Int("comments_failed", int(stats.commentsFailed)),
 		)
 		s.logger.Info("completed fetching who is hiring posts",
 			zap.Int("hiring_threads_found", int(stats.hiringThreadsFound)),
-			zap.Int("comments_processed", int(stats.commentsProcessed)))
+			zap.Int("candidate_threads_found", int(stats.candidateThreadsFound)),
+			zap.Int("freelance_threads_found", int(stats.freelanceThreadsFound)),
+			zap.Int("comments_queued", int(stats.commentsQueued)),
+			zap.Int("comments_fetched", int(stats.commentsFetched)),
+			zap.Int("comments_processed", int(stats.commentsProcessed)),
+			zap.Int("comments_skipped", int(stats.commentsSkipped)),
+			zap.Int("comments_removed", int(stats.commentsRemoved)),
+			zap.Int("stories_failed", int(stats.storiesFailed)),
+			zap.Int("comments_failed", int(stats.commentsFailed)))
 		return nil
 	}
 }
 
-func (s *JobScheduler) processComment(ctx context.Context, commentID int) error {
+// processComment publishes the comment unless the thread state shows it was
+// already published with the same content. A published comment that has
+// since been deleted or killed is replaced by a tombstone.
+func (s *JobScheduler) processComment(ctx context.Context, task commentTask, run *processingRun) (commentResult, error) {
 	ctx, span := tracer.Start(ctx, "JobScheduler.processComment")
-	span.SetAttributes(telemetry.Int("comment_id", commentID))
+	span.SetAttributes(
+		telemetry.Int("comment_id", task.commentID),
+		telemetry.Int("thread_id", task.threadID),
+		telemetry.String("thread_type", string(task.threadType)),
+	)
 	defer span.End()
 
-	comment, err := s.hnClient.GetItem(ctx, commentID)
+	comment, changed, err := s.getItem(ctx, task.commentID, task.fresh)
 	if err != nil {
 		span.RecordError(err)
-		return errors.Internal("failed to fetch comment", err)
+		return commentSkipped, errors.Internal("failed to fetch comment", err)
 	}
+	run.stats.commentFetched()
+	s.archiveItem(task.threadID, comment)
 
-	jobPosting := comment.ToJobPosting()
-	s.logger.Debug("processing job posting",
-		zap.String("comment_id", jobPosting.ID),
-		zap.Time("posted_at", jobPosting.PostedAt))
+	if comment.Deleted || comment.Dead {
+		if !run.threads.published(task.threadID, task.commentID) {
+			span.SetAttributes(telemetry.String("comment.result", "ignored"))
+			return commentSkipped, nil
+		}
+		if err := s.publishTombstone(ctx, comment, task, run.publisher); err != nil {
+			span.RecordError(err)
+			return commentSkipped, err
+		}
+		run.threads.forget(task.threadID, task.commentID)
+		span.SetAttributes(telemetry.String("comment.result", "removed"))
+		return commentRemoved, nil
+	}
+
+	var updates []models.PostingUpdate
+	if s.config.FetchReplies && task.threadType == models.ThreadTypeHiring && len(comment.Kids) > 0 {
+		updates, err = s.fetchPosterReplies(ctx, task.threadID, comment, changed)
+		if err != nil {
+			span.RecordError(err)
+			return commentSkipped, err
+		}
+	}
+
+	hash := postingHash(comment.Text, updates)
+	if !run.threads.changed(task.threadID, task.commentID, hash) {
+		span.SetAttributes(telemetry.String("comment.result", "unchanged"))
+		s.logger.Debug("skipping unchanged comment",
+			zap.Int("comment_id", task.commentID),
+			zap.Int("thread_id", task.threadID))
+		return commentSkipped, nil
+	}
 
-	if err := s.publisher.PublishJobPosting(ctx, jobPosting); err != nil {
+	if err := s.publishComment(ctx, comment, updates, task, run.publisher); err != nil {
 		span.RecordError(err)
-		return errors.Internal("failed to publish job posting", err)
+		return commentSkipped, err
 	}
 
+	run.threads.markPublished(task.threadID, task.commentID, hash)
+	span.SetAttributes(telemetry.String("comment.result", "published"))
+	return commentPublished, nil
+}
+
+// getItem fetches the item from the API when fresh is set and through the
+// cache otherwise. Only fresh fetches report whether the item changed.
+func (s *JobScheduler) getItem(ctx context.Context, id int, fresh bool) (*models.SourcePost, bool, error) {
+	if fresh {
+		return s.hnClient.GetFreshItem(ctx, id)
+	}
+	post, err := s.hnClient.GetItem(ctx, id)
+	return post, false, err
+}
+
+// publishComment publishes the comment on the subject for its thread type.
+func (s *JobScheduler) publishComment(ctx context.Context, comment *models.SourcePost, updates []models.PostingUpdate, task commentTask, publisher messaging.Publisher) error {
+	switch task.threadType {
+	case models.ThreadTypeCandidates:
+		if err := publisher.PublishCandidate(ctx, comment.ToThreadPost(task.threadID, task.threadType, task.threadMonth)); err != nil {
+			return errors.Internal("failed to publish candidate", err)
+		}
+	case models.ThreadTypeFreelance:
+		if err := publisher.PublishFreelance(ctx, comment.ToThreadPost(task.threadID, task.threadType, task.threadMonth)); err != nil {
+			return errors.Internal("failed to publish freelance post", err)
+		}
+	default:
+		jobPosting := comment.ToJobPosting(task.threadID, task.threadMonth)
+		jobPosting.Updates = updates
+		status, statusUpdate := postingStatus(comment.Text, updates)
+		jobPosting.Status = status
+		s.logger.Debug("processing job posting",
+			zap.String("comment_id", jobPosting.ID),
+			zap.Time("posted_at", jobPosting.PostedAt),
+			zap.Int("updates", len(updates)))
+		if err := publisher.PublishJobPosting(ctx, jobPosting); err != nil {
+			return errors.Internal("failed to publish job posting", err)
+		}
+
+		if status != "" {
+			change := &models.JobStatusChange{
+				ID:        jobPosting.ID,
+				Source:    jobPosting.Source,
+				Status:    status,
+				ChangedAt: jobPosting.PostedAt,
+			}
+			if statusUpdate != nil {
+				change.ReplyID = statusUpdate.ID
+				change.Text = statusUpdate.Text
+				change.ChangedAt = statusUpdate.PostedAt
+			}
+			if err := publisher.PublishJobStatus(ctx, change); err != nil {
+				return errors.Internal("failed to publish job status", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *JobScheduler) publishTombstone(ctx context.Context, comment *models.SourcePost, task commentTask, publisher messaging.Publisher) error {
+	reason := models.RemovalReasonDead
+	if comment.Deleted {
+		reason = models.RemovalReasonDeleted
+	}
+
+	s.logger.Info("published comment was removed",
+		zap.Int("comment_id", comment.ID),
+		zap.Int("thread_id", task.threadID),
+		zap.String("reason", reason))
+
+	tombstone := &models.Tombstone{
+		ID:         strconv.Itoa(comment.ID),
+		Source:     models.SourceHackerNews,
+		ThreadID:   task.threadID,
+		ThreadType: task.threadType,
+		Reason:     reason,
+		RemovedAt:  time.Now(),
+	}
+	if err := publisher.PublishTombstone(ctx, tombstone); err != nil {
+		return errors.Internal("failed to publish tombstone", err)
+	}
 	return nil
 }
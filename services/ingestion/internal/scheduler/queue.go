@@ -0,0 +1,238 @@
+package scheduler
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"shenanigigs/ingestion/internal/api"
+	"shenanigigs/ingestion/internal/models"
+	"shenanigigs/ingestion/internal/sources"
+	"shenanigigs/ingestion/internal/workqueue"
+
+	"go.uber.org/zap"
+)
+
+const (
+	queueBatchSize    = 10
+	queueErrorBackoff = 5 * time.Second
+)
+
+// CommentQueue is a durable queue of comment tasks shared by every
+// ingestion replica. When one is set, runs enqueue the comments of each
+// thread instead of fetching them in process, and ConsumeComments fetches
+// and publishes them.
+type CommentQueue interface {
+	Enqueue(ctx context.Context, task workqueue.Task) error
+	Read(ctx context.Context, consumer string, count int) ([]workqueue.Message, error)
+	Ack(ctx context.Context, ids ...string) error
+	Retry(ctx context.Context, msg workqueue.Message, cause error) error
+	// Release requeues messages that were not attempted without counting
+	// an attempt.
+	Release(ctx context.Context, msgs ...workqueue.Message) error
+	// CountPublished and TakePublished keep a count of the comments
+	// consumers published, so the adaptive schedule of the run that queued
+	// them sees the thread's activity.
+	CountPublished(ctx context.Context, n int) error
+	TakePublished(ctx context.Context) (int, error)
+}
+
+// SetCommentQueue switches comment fetching to queue. It must be called
+// before Start.
+func (s *JobScheduler) SetCommentQueue(queue CommentQueue) {
+	s.queue = queue
+}
+
+func (w *workerManager) startQueueWriters(ctx context.Context, wg *sync.WaitGroup, run *processingRun, commentChan chan commentTask) {
+	const numWriters = 2
+	for i := 0; i < numWriters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range commentChan {
+				if ctx.Err() != nil {
+					continue
+				}
+				err := w.scheduler.queue.Enqueue(ctx, workqueue.Task{
+					CommentID:   task.commentID,
+					ThreadID:    task.threadID,
+					ThreadType:  string(task.threadType),
+					ThreadMonth: task.threadMonth,
+					Fresh:       task.fresh,
+				})
+				if err != nil {
+					if ctx.Err() != nil {
+						continue
+					}
+					run.stats.commentFailed(err)
+					run.checkpoint.commentFailed(task.threadID)
+					w.logger.Error("failed to enqueue comment",
+						zap.Int("comment_id", task.commentID),
+						zap.Error(err))
+					continue
+				}
+				run.stats.commentQueued()
+				run.checkpoint.commentCompleted(task.threadID, task.commentID)
+			}
+		}()
+	}
+}
+
+// ConsumeComments fetches and publishes queued comments with the given
+// number of workers until ctx is cancelled. Consumer names must be unique
+// across replicas.
+func (s *JobScheduler) ConsumeComments(ctx context.Context, consumer string, workers int) error {
+	if s.queue == nil {
+		return nil
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	s.logger.Info("consuming queued comments",
+		zap.String("consumer", consumer),
+		zap.Int("workers", workers))
+
+	s.mutex.Lock()
+	if s.stopped() {
+		s.mutex.Unlock()
+		return ErrStopping
+	}
+	s.inflight.Add(workers)
+	s.mutex.Unlock()
+
+	ctx, cancel := s.workContext(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer s.inflight.Done()
+			s.consume(ctx, name)
+		}(fmt.Sprintf("%s-%d", consumer, i))
+	}
+	wg.Wait()
+
+	if s.stopped() {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// consume processes batches until ctx is cancelled or the scheduler stops;
+// a batch in progress when Stop is called is finished first.
+func (s *JobScheduler) consume(ctx context.Context, consumer string) {
+	for ctx.Err() == nil && !s.stopped() {
+		messages, err := s.queue.Read(ctx, consumer, queueBatchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Error("failed to read comment queue", zap.String("consumer", consumer), zap.Error(err))
+			s.sleep(ctx, queueErrorBackoff)
+			continue
+		}
+		if len(messages) == 0 {
+			continue
+		}
+		if unavailable := s.consumeBatch(ctx, messages); unavailable {
+			s.sleep(ctx, s.config.BreakerOpenTimeout)
+		}
+	}
+}
+
+// consumeBatch processes messages as one small run. Messages are only
+// acknowledged once the thread state is saved, so a crash in between
+// republishes rather than loses them. Messages left over when the job
+// source is unavailable or the run is cancelled are released without
+// using up an attempt. It reports whether the job source was unavailable.
+func (s *JobScheduler) consumeBatch(ctx context.Context, messages []workqueue.Message) bool {
+	ctx, span := tracer.Start(ctx, "JobScheduler.consumeBatch")
+	defer span.End()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	run := s.newProcessingRun(ctx, sources.WithSource(s.publisher, models.SourceHackerNews), cancel)
+
+	var done []string
+	var released []workqueue.Message
+	unavailable := false
+	for i, msg := range messages {
+		if runCtx.Err() != nil || unavailable {
+			released = append(released, messages[i:]...)
+			break
+		}
+
+		task := commentTask{
+			commentID:   msg.Task.CommentID,
+			threadID:    msg.Task.ThreadID,
+			threadType:  models.ThreadType(msg.Task.ThreadType),
+			threadMonth: msg.Task.ThreadMonth,
+			fresh:       msg.Task.Fresh,
+		}
+		run.threads.load(runCtx, task.threadID)
+
+		result, err := s.processComment(runCtx, task, run)
+		if err != nil {
+			if runCtx.Err() != nil {
+				released = append(released, messages[i:]...)
+				break
+			}
+			run.stats.commentFailed(err)
+			s.logger.Error("failed to process queued comment",
+				zap.Int("comment_id", task.commentID),
+				zap.Int("attempt", msg.Task.Attempt),
+				zap.Error(err))
+			if stderrors.Is(err, api.ErrCircuitOpen) {
+				unavailable = true
+				released = append(released, msg)
+				continue
+			}
+			if err := s.queue.Retry(ctx, msg, err); err != nil {
+				s.logger.Error("failed to requeue comment", zap.Int("comment_id", task.commentID), zap.Error(err))
+			}
+			continue
+		}
+		run.stats.commentDone(result)
+		done = append(done, msg.ID)
+	}
+
+	if err := s.queue.Release(context.WithoutCancel(ctx), released...); err != nil {
+		span.RecordError(err)
+		s.logger.Error("failed to release queued comments", zap.Error(err))
+	}
+
+	if err := run.threads.flush(context.WithoutCancel(ctx)); err != nil {
+		span.RecordError(err)
+		s.logger.Error("failed to save thread state, leaving comments pending", zap.Error(err))
+		return unavailable
+	}
+	s.flushArchive()
+
+	if err := s.queue.Ack(context.WithoutCancel(ctx), done...); err != nil {
+		span.RecordError(err)
+		s.logger.Error("failed to acknowledge queued comments", zap.Error(err))
+	}
+
+	stats := run.stats.snapshot()
+	if err := s.queue.CountPublished(context.WithoutCancel(ctx), stats.CommentsProcessed); err != nil {
+		s.logger.Warn("failed to count published comments", zap.Error(err))
+	}
+	s.logger.Debug("processed queued comments",
+		zap.Int("comments_processed", stats.CommentsProcessed),
+		zap.Int("comments_skipped", stats.CommentsSkipped),
+		zap.Int("comments_removed", stats.CommentsRemoved),
+		zap.Int("comments_failed", stats.CommentsFailed))
+	return unavailable
+}
+
+func (s *JobScheduler) sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-s.clock.After(d):
+	}
+}
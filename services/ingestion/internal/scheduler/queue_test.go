@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"shenanigigs/ingestion/internal/api"
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/models"
+	"shenanigigs/ingestion/internal/workqueue"
+
+	"go.uber.org/zap"
+)
+
+// countingQueue is a CommentQueue that only keeps the published count.
+type countingQueue struct {
+	published int
+}
+
+func (q *countingQueue) Enqueue(ctx context.Context, task workqueue.Task) error { return nil }
+func (q *countingQueue) Read(ctx context.Context, consumer string, count int) ([]workqueue.Message, error) {
+	return nil, nil
+}
+func (q *countingQueue) Ack(ctx context.Context, ids ...string) error { return nil }
+func (q *countingQueue) Retry(ctx context.Context, msg workqueue.Message, cause error) error {
+	return nil
+}
+
+func (q *countingQueue) Release(ctx context.Context, msgs ...workqueue.Message) error {
+	return nil
+}
+
+func (q *countingQueue) CountPublished(ctx context.Context, n int) error {
+	q.published += n
+	return nil
+}
+
+func (q *countingQueue) TakePublished(ctx context.Context) (int, error) {
+	n := q.published
+	q.published = 0
+	return n, nil
+}
+
+// recordingQueue records the messages retried, released and acknowledged.
+type recordingQueue struct {
+	countingQueue
+	retried  []string
+	released []string
+	acked    []string
+}
+
+func (q *recordingQueue) Retry(ctx context.Context, msg workqueue.Message, cause error) error {
+	q.retried = append(q.retried, msg.ID)
+	return nil
+}
+
+func (q *recordingQueue) Release(ctx context.Context, msgs ...workqueue.Message) error {
+	for _, msg := range msgs {
+		q.released = append(q.released, msg.ID)
+	}
+	return nil
+}
+
+func (q *recordingQueue) Ack(ctx context.Context, ids ...string) error {
+	q.acked = append(q.acked, ids...)
+	return nil
+}
+
+type namedSource string
+
+func (s namedSource) Name() string { return string(s) }
+func (s namedSource) Fetch(ctx context.Context, publisher messaging.Publisher) error {
+	return nil
+}
+
+func TestNewCommentsCountsConsumers(t *testing.T) {
+	ctx := context.Background()
+	status := RunStatus{Stats: RunStats{CommentsProcessed: 2}}
+	s := &JobScheduler{logger: zap.NewNop()}
+
+	if got := s.newComments(ctx, namedSource(models.SourceHackerNews), status); got != 2 {
+		t.Errorf("newComments() without a queue = %d, want 2", got)
+	}
+
+	queue := &countingQueue{}
+	s.SetCommentQueue(queue)
+	_ = queue.CountPublished(ctx, 40)
+
+	if got := s.newComments(ctx, namedSource("feed"), status); got != 2 {
+		t.Errorf("newComments() for another source = %d, want 2", got)
+	}
+	if got := s.newComments(ctx, namedSource(models.SourceHackerNews), status); got != 42 {
+		t.Errorf("newComments() = %d, want the 40 published by consumers plus 2", got)
+	}
+	if got := s.newComments(ctx, namedSource(models.SourceHackerNews), status); got != 2 {
+		t.Errorf("newComments() on the next run = %d, want 2", got)
+	}
+}
+
+func TestConsumeBatchReleasesWhileUnavailable(t *testing.T) {
+	source := &fakeSource{err: errors.Unavailable("firebase unavailable", api.ErrCircuitOpen)}
+	s := newFakeScheduler(source, NewMemoryThreadStore())
+	queue := &recordingQueue{}
+	s.SetCommentQueue(queue)
+
+	messages := []workqueue.Message{
+		{ID: "1-0", Task: workqueue.Task{CommentID: 2, ThreadID: 1, Attempt: 1}},
+		{ID: "2-0", Task: workqueue.Task{CommentID: 3, ThreadID: 1}},
+	}
+	if unavailable := s.consumeBatch(context.Background(), messages); !unavailable {
+		t.Error("consumeBatch() = false, want unavailable")
+	}
+
+	if len(queue.retried) != 0 {
+		t.Errorf("retried %v, want no attempt used", queue.retried)
+	}
+	if !slices.Equal(queue.released, []string{"1-0", "2-0"}) {
+		t.Errorf("released %v, want both messages", queue.released)
+	}
+	if len(queue.acked) != 0 {
+		t.Errorf("acknowledged %v, want none", queue.acked)
+	}
+	if len(source.cached) != 1 {
+		t.Errorf("fetched %d comments, want to stop after the first", len(source.cached))
+	}
+}
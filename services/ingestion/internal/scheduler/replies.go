@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"shenanigigs/common/htmltext"
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const defaultMaxReplyDepth = 3
+
+// Replies are matched sentence by sentence, and only affirmative statements
+// count: "the position has been filled" or "we're no longer hiring", or a
+// reply that is just "Filled!". Questions and hedged forms such as "is this
+// role filled?" or "I'll reply here once the position is filled" are ignored.
+var (
+	filledPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(?:position|role|job|opening|spot|req|requisition|vacancy|it|this|both|all)s?(?:'s|\s+(?:(?:has|have)\s+(?:now\s+|already\s+)?been|is|are|was|were))?\s+(?:now\s+|already\s+)?filled\b`),
+		regexp.MustCompile(`(?i)\bwe(?:'ve|\s+have)?\s+(?:now\s+|already\s+)?filled\s+(?:the|this|that|these|both|all|our|it)\b`),
+		regexp.MustCompile(`(?i)^\W*(?:update\W*)?filled\W*$`),
+	}
+	closedPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bwe(?:'re|\s+are|'ve|\s+have)?\s+(?:now\s+)?no\s+longer\s+(?:hiring|accepting|looking)\b`),
+		regexp.MustCompile(`(?i)\b(?:position|role|job|posting|opening|req|requisition|vacancy|application)s?(?:'s|\s+(?:(?:has|have)\s+(?:now\s+)?been|is|are|was|were))?\s+(?:now\s+)?(?:closed|no\s+longer\s+(?:open|available|accepting))\b`),
+		regexp.MustCompile(`(?i)^\W*(?:update\W*)?closed\W*$`),
+	}
+	sentencePattern = regexp.MustCompile(`[^.!?\n]+[.!?]*`)
+	// A sentence opening like a question, even without a question mark.
+	interrogativePattern = regexp.MustCompile(`(?i)^\W*(?:is|are|was|were|has|have|did|does|do|can|could|will|would|any)\b`)
+	// Words before the match that make it a negation or a condition.
+	hedgePattern = regexp.MustCompile(`(?i)(?:\b(?:not|never|if|whether|when|once|until|unless|before)\b|n't\b)`)
+	// Posters often edit their comment to start with "[FILLED]" or "CLOSED".
+	statusPrefixPattern = regexp.MustCompile(`(?i)^\s*[\[(]?\s*(filled|closed)\b`)
+)
+
+// fetchPosterReplies walks the reply tree under comment, up to
+// config.MaxReplyDepth levels deep, and returns the replies written by the
+// comment's own author, oldest first. Replies by anyone else are only
+// traversed. The replies of an item are fetched fresh only when fresh is
+// set and the item's kids or time changed; the others come from the cache.
+func (s *JobScheduler) fetchPosterReplies(ctx context.Context, threadID int, comment *models.SourcePost, fresh bool) ([]models.PostingUpdate, error) {
+	ctx, span := tracer.Start(ctx, "JobScheduler.fetchPosterReplies")
+	defer span.End()
+
+	maxDepth := s.config.MaxReplyDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxReplyDepth
+	}
+
+	var updates []models.PostingUpdate
+	var walk func(kids models.IntSlice, fresh bool, depth int) error
+	walk = func(kids models.IntSlice, fresh bool, depth int) error {
+		for _, id := range kids {
+			reply, changed, err := s.getItem(ctx, id, fresh)
+			if err != nil {
+				if errors.HasType(err, errors.ErrTypeNotFound) {
+					continue
+				}
+				return err
+			}
+			s.archiveItem(threadID, reply)
+			if reply.Deleted || reply.Dead {
+				continue
+			}
+
+			if reply.By == comment.By {
+				updates = append(updates, models.PostingUpdate{
+					ID:       strconv.Itoa(reply.ID),
+					Text:     htmltext.Convert(reply.Text).Text,
+					PostedAt: time.Unix(reply.Time, 0),
+				})
+			}
+
+			if depth < maxDepth {
+				if err := walk(reply.Kids, changed, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := walk(comment.Kids, fresh, 1); err != nil {
+		span.RecordError(err)
+		return nil, errors.Internal("failed to fetch replies", err)
+	}
+
+	sort.SliceStable(updates, func(i, j int) bool {
+		return updates[i].PostedAt.Before(updates[j].PostedAt)
+	})
+	s.logger.Debug("fetched poster replies",
+		zap.Int("comment_id", comment.ID),
+		zap.Int("updates", len(updates)))
+	return updates, nil
+}
+
+// postingStatus looks for a filled or closed signal, first in the poster's
+// replies, newest first, and then at the start of the comment itself. It
+// returns the update the signal came from, if any.
+func postingStatus(text string, updates []models.PostingUpdate) (models.PostingStatus, *models.PostingUpdate) {
+	for i := len(updates) - 1; i >= 0; i-- {
+		if status := statusOf(updates[i].Text); status != "" {
+			return status, &updates[i]
+		}
+	}
+
+	if matches := statusPrefixPattern.FindStringSubmatch(text); len(matches) > 1 {
+		return models.PostingStatus(strings.ToLower(matches[1])), nil
+	}
+	return "", nil
+}
+
+func statusOf(text string) models.PostingStatus {
+	for _, sentence := range sentencePattern.FindAllString(text, -1) {
+		sentence = strings.TrimSpace(sentence)
+		if strings.HasSuffix(sentence, "?") || interrogativePattern.MatchString(sentence) {
+			continue
+		}
+		switch {
+		case affirms(sentence, filledPatterns):
+			return models.PostingStatusFilled
+		case affirms(sentence, closedPatterns):
+			return models.PostingStatusClosed
+		}
+	}
+	return ""
+}
+
+// affirms reports whether one of patterns matches sentence without a
+// negation or condition in front of it.
+func affirms(sentence string, patterns []*regexp.Regexp) bool {
+	for _, pattern := range patterns {
+		loc := pattern.FindStringIndex(sentence)
+		if loc != nil && !hedgePattern.MatchString(sentence[:loc[0]]) {
+			return true
+		}
+	}
+	return false
+}
+
+// postingHash covers the comment text and, when replies are fetched, the
+// poster's updates, so that a new update republishes the posting. Comments
+// without updates hash the same as before replies were tracked.
+func postingHash(text string, updates []models.PostingUpdate) string {
+	if len(updates) == 0 {
+		return contentHash(text)
+	}
+
+	var b strings.Builder
+	b.WriteString(text)
+	for _, update := range updates {
+		b.WriteString("\x00")
+		b.WriteString(update.ID)
+		b.WriteString("\x00")
+		b.WriteString(update.Text)
+	}
+	return contentHash(b.String())
+}
@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"shenanigigs/ingestion/internal/models"
+)
+
+func TestStatusOf(t *testing.T) {
+	tests := []struct {
+		text string
+		want models.PostingStatus
+	}{
+		// Affirmative statements.
+		{"The position has been filled, thanks everyone!", models.PostingStatusFilled},
+		{"Update: this role is now filled.", models.PostingStatusFilled},
+		{"Both roles have been filled.", models.PostingStatusFilled},
+		{"Position filled", models.PostingStatusFilled},
+		{"It's filled now, thanks for all the applications.", models.PostingStatusFilled},
+		{"We've filled the backend role.", models.PostingStatusFilled},
+		{"Filled!", models.PostingStatusFilled},
+		{"Update - FILLED", models.PostingStatusFilled},
+		{"Thanks for the interest. We're no longer hiring for this position.", models.PostingStatusClosed},
+		{"We are no longer accepting applications.", models.PostingStatusClosed},
+		{"Applications are now closed.", models.PostingStatusClosed},
+		{"This posting is no longer open.", models.PostingStatusClosed},
+		{"Closed.", models.PostingStatusClosed},
+
+		// Questions.
+		{"Is this role filled?", ""},
+		{"Has the position been filled yet", ""},
+		{"Are you still hiring or is the role closed?", ""},
+		{"Any update, is the position filled?", ""},
+
+		// Negations and conditions.
+		{"The position has not been filled yet, keep applying.", ""},
+		{"The role isn't filled.", ""},
+		{"We haven't filled the role yet.", ""},
+		{"Not sure if the position is filled, I'll check with HR.", ""},
+		{"I'll reply here once the position is filled.", ""},
+		{"We will post an update when the role is closed.", ""},
+		{"We are not closed to remote candidates.", ""},
+
+		// Unrelated uses of the words.
+		{"Our pipeline is filled with interesting problems.", ""},
+		{"The office is closed on Fridays.", ""},
+		{"Thanks! I sent my CV over email.", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := statusOf(tt.text); got != tt.want {
+				t.Errorf("statusOf(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostingStatus(t *testing.T) {
+	at := func(day int) time.Time {
+		return time.Date(2024, 10, day, 12, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name       string
+		text       string
+		updates    []models.PostingUpdate
+		want       models.PostingStatus
+		wantUpdate string
+	}{
+		{
+			name: "no signal",
+			text: "Acme | Senior Go Engineer | Remote",
+		},
+		{
+			name: "prefix in comment",
+			text: "[FILLED] Acme | Senior Go Engineer | Remote",
+			want: models.PostingStatusFilled,
+		},
+		{
+			name: "closed prefix in comment",
+			text: "(Closed) Acme | Senior Go Engineer | Remote",
+			want: models.PostingStatusClosed,
+		},
+		{
+			name: "newest update wins",
+			text: "Acme | Senior Go Engineer | Remote",
+			updates: []models.PostingUpdate{
+				{ID: "1", Text: "We're no longer hiring for the frontend role.", PostedAt: at(1)},
+				{ID: "2", Text: "The backend position has been filled too.", PostedAt: at(5)},
+			},
+			want:       models.PostingStatusFilled,
+			wantUpdate: "2",
+		},
+		{
+			name: "questions in updates are skipped",
+			text: "Acme | Senior Go Engineer | Remote",
+			updates: []models.PostingUpdate{
+				{ID: "1", Text: "Position filled.", PostedAt: at(1)},
+				{ID: "2", Text: "Someone asked: is the role filled? No, still open!", PostedAt: at(5)},
+			},
+			want:       models.PostingStatusFilled,
+			wantUpdate: "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, update := postingStatus(tt.text, tt.updates)
+			if got != tt.want {
+				t.Errorf("postingStatus() status = %q, want %q", got, tt.want)
+			}
+			gotUpdate := ""
+			if update != nil {
+				gotUpdate = update.ID
+			}
+			if gotUpdate != tt.wantUpdate {
+				t.Errorf("postingStatus() update = %q, want %q", gotUpdate, tt.wantUpdate)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package scheduler
+
+import (
+	"shenanigigs/common/cache"
+	"shenanigigs/common/cache/memory"
+	"shenanigigs/common/cache/redis"
+	"shenanigigs/ingestion/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// RunState is where runs record what they published and how far they got:
+// the thread store, and the cache holding run checkpoints, the pause flag
+// and the seen markers of the feed and ATS sources.
+type RunState struct {
+	Cache   cache.Cache
+	Threads ThreadStore
+}
+
+// NewRunState keeps run state in Redis when cfg.SharesState, and in the
+// process otherwise, so that dry and JSONL runs start from and leave behind
+// the state of the NATS runs untouched.
+func NewRunState(cfg *config.Config, redisCache *redis.Cache, logger *zap.Logger) RunState {
+	if cfg.SharesState() {
+		return RunState{
+			Cache:   redisCache,
+			Threads: NewRedisThreadStore(redisCache.Client()),
+		}
+	}
+	logger.Info("keeping run state in memory",
+		zap.String("publisher_backend", cfg.PublisherBackend))
+	return RunState{
+		Cache:   memory.New(cache.Options{DefaultTTL: cfg.CacheTTL}),
+		Threads: NewMemoryThreadStore(),
+	}
+}
@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shenanigigs/common/cache"
+	"shenanigigs/common/cache/redis"
+	"shenanigigs/ingestion/internal/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.uber.org/zap"
+)
+
+func TestRunStateIsOnlySharedByNATSRuns(t *testing.T) {
+	tests := []struct {
+		backend string
+		shared  bool
+	}{
+		{"nats", true},
+		{"tee", true},
+		{"jsonl", false},
+		{"count", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			ctx := context.Background()
+			server := miniredis.RunT(t)
+			redisCache := redis.New(cache.Options{RedisURL: server.Addr()})
+			t.Cleanup(func() { redisCache.Close() })
+
+			state := NewRunState(&config.Config{PublisherBackend: tt.backend}, redisCache, zap.NewNop())
+			if err := state.Threads.SaveThread(ctx, 1, map[int]string{10: "a"}, time.Hour); err != nil {
+				t.Fatalf("SaveThread() error = %v", err)
+			}
+			if err := state.Cache.Set(ctx, checkpointKey("threads"), "{}", time.Hour); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if comments, err := state.Threads.LoadThread(ctx, 1); err != nil || comments[10] != "a" {
+				t.Errorf("LoadThread() = %v, %v, want the saved comment", comments, err)
+			}
+
+			if keys := server.Keys(); (len(keys) > 0) != tt.shared {
+				t.Errorf("Redis keys = %v, want shared state %v", keys, tt.shared)
+			}
+		})
+	}
+}
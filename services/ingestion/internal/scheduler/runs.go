@@ -0,0 +1,261 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	RunStateRunning   = "running"
+	RunStateSucceeded = "succeeded"
+	RunStateFailed    = "failed"
+
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+
+	maxRecentRuns   = 100
+	maxErrorSamples = 5
+)
+
+// RunRecorder persists finished runs so they outlive the process.
+type RunRecorder interface {
+	RecordRun(ctx context.Context, run RunStatus) error
+}
+
+// ThreadRef identifies a Hacker News thread touched by a run.
+type ThreadRef struct {
+	ID       int       `json:"id"`
+	Type     string    `json:"type"`
+	Month    string    `json:"month"`
+	PostedAt time.Time `json:"posted_at"`
+}
+
+// RunStatus describes one fetch of a job source, or one on-demand ingest of
+// a thread or item. Stats are only collected for Hacker News runs.
+type RunStatus struct {
+	ID           string      `json:"id"`
+	Source       string      `json:"source"`
+	Trigger      string      `json:"trigger"`
+	Target       string      `json:"target,omitempty"`
+	State        string      `json:"state"`
+	StartedAt    time.Time   `json:"started_at"`
+	FinishedAt   *time.Time  `json:"finished_at,omitempty"`
+	Threads      []ThreadRef `json:"threads,omitempty"`
+	Stats        RunStats    `json:"stats"`
+	Error        string      `json:"error,omitempty"`
+	ErrorSamples []string    `json:"error_samples,omitempty"`
+	// Checkpoint is set for runs over Hacker News stories.
+	Checkpoint *CheckpointStatus `json:"checkpoint,omitempty"`
+}
+
+type runRecord struct {
+	mutex      sync.Mutex
+	status     RunStatus
+	stats      []*jobProcessingStats
+	checkpoint *runCheckpoint
+}
+
+func (r *runRecord) attach(stats *jobProcessingStats) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stats = append(r.stats, stats)
+}
+
+func (r *runRecord) snapshot() RunStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	status := r.status
+	status.Threads = nil
+	status.ErrorSamples = nil
+	for _, stats := range r.stats {
+		status.Stats = status.Stats.add(stats.snapshot())
+
+		stats.mutex.Lock()
+		status.Threads = append(status.Threads, stats.threads...)
+		for _, sample := range stats.errorSamples {
+			if len(status.ErrorSamples) < maxErrorSamples {
+				status.ErrorSamples = append(status.ErrorSamples, sample)
+			}
+		}
+		stats.mutex.Unlock()
+	}
+	if r.checkpoint != nil {
+		checkpoint := r.checkpoint.status()
+		status.Checkpoint = &checkpoint
+	}
+	return status
+}
+
+func (s RunStats) add(o RunStats) RunStats {
+	return RunStats{
+		HiringThreadsFound:    s.HiringThreadsFound + o.HiringThreadsFound,
+		CandidateThreadsFound: s.CandidateThreadsFound + o.CandidateThreadsFound,
+		FreelanceThreadsFound: s.FreelanceThreadsFound + o.FreelanceThreadsFound,
+		CommentsQueued:        s.CommentsQueued + o.CommentsQueued,
+		CommentsFetched:       s.CommentsFetched + o.CommentsFetched,
+		CommentsProcessed:     s.CommentsProcessed + o.CommentsProcessed,
+		CommentsSkipped:       s.CommentsSkipped + o.CommentsSkipped,
+		CommentsRemoved:       s.CommentsRemoved + o.CommentsRemoved,
+		StoriesFailed:         s.StoriesFailed + o.StoriesFailed,
+		CommentsFailed:        s.CommentsFailed + o.CommentsFailed,
+	}
+}
+
+// runTracker keeps the runs in progress and a bounded history of finished
+// ones, newest last.
+type runTracker struct {
+	mutex  sync.Mutex
+	nextID uint64
+	active map[string]*runRecord
+	recent []RunStatus
+}
+
+func newRunTracker() *runTracker {
+	return &runTracker{active: make(map[string]*runRecord)}
+}
+
+func (t *runTracker) start(source, trigger, target string) *runRecord {
+	now := time.Now().UTC()
+	id := fmt.Sprintf("%s-%d", now.Format("20060102T150405"), atomic.AddUint64(&t.nextID, 1))
+	record := &runRecord{status: RunStatus{
+		ID:        id,
+		Source:    source,
+		Trigger:   trigger,
+		Target:    target,
+		State:     RunStateRunning,
+		StartedAt: now,
+	}}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.active[id] = record
+	return record
+}
+
+func (t *runTracker) finish(record *runRecord, err error) RunStatus {
+	record.mutex.Lock()
+	finishedAt := time.Now().UTC()
+	record.status.FinishedAt = &finishedAt
+	record.status.State = RunStateSucceeded
+	if err != nil {
+		record.status.State = RunStateFailed
+		record.status.Error = err.Error()
+	}
+	record.mutex.Unlock()
+
+	status := record.snapshot()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.active, status.ID)
+	t.recent = append(t.recent, status)
+	if len(t.recent) > maxRecentRuns {
+		t.recent = t.recent[len(t.recent)-maxRecentRuns:]
+	}
+	return status
+}
+
+func (t *runTracker) current() []RunStatus {
+	t.mutex.Lock()
+	records := make([]*runRecord, 0, len(t.active))
+	for _, record := range t.active {
+		records = append(records, record)
+	}
+	t.mutex.Unlock()
+
+	statuses := make([]RunStatus, 0, len(records))
+	for _, record := range records {
+		statuses = append(statuses, record.snapshot())
+	}
+	return statuses
+}
+
+// pendingComments counts the comments handed to workers by the runs in
+// progress that are not finished yet.
+func (t *runTracker) pendingComments() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	pending := 0
+	for _, record := range t.active {
+		record.mutex.Lock()
+		for _, stats := range record.stats {
+			pending += stats.pending()
+		}
+		record.mutex.Unlock()
+	}
+	return pending
+}
+
+// history returns up to limit finished runs, newest first.
+func (t *runTracker) history(limit int) []RunStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if limit <= 0 || limit > len(t.recent) {
+		limit = len(t.recent)
+	}
+	statuses := make([]RunStatus, 0, limit)
+	for i := len(t.recent) - 1; i >= 0 && len(statuses) < limit; i-- {
+		statuses = append(statuses, t.recent[i])
+	}
+	return statuses
+}
+
+func (t *runTracker) get(id string) (RunStatus, bool) {
+	t.mutex.Lock()
+	record, ok := t.active[id]
+	if !ok {
+		for _, status := range t.recent {
+			if status.ID == id {
+				t.mutex.Unlock()
+				return status, true
+			}
+		}
+	}
+	t.mutex.Unlock()
+
+	if !ok {
+		return RunStatus{}, false
+	}
+	return record.snapshot(), true
+}
+
+type runRecordKey struct{}
+
+func withRunRecord(ctx context.Context, record *runRecord) context.Context {
+	return context.WithValue(ctx, runRecordKey{}, record)
+}
+
+// attachStats makes the stats of a processing run part of the run record
+// carried by ctx, if there is one.
+func attachStats(ctx context.Context, stats *jobProcessingStats) {
+	if record, ok := ctx.Value(runRecordKey{}).(*runRecord); ok {
+		record.attach(stats)
+	}
+}
+
+// attachCheckpoint makes the checkpoint of a processing run part of the run
+// record carried by ctx, if there is one.
+func attachCheckpoint(ctx context.Context, checkpoint *runCheckpoint) {
+	if record, ok := ctx.Value(runRecordKey{}).(*runRecord); ok {
+		record.mutex.Lock()
+		defer record.mutex.Unlock()
+		record.checkpoint = checkpoint
+	}
+}
+
+// runIDFrom returns the ID of the run record carried by ctx, or an empty
+// string.
+func runIDFrom(ctx context.Context) string {
+	if record, ok := ctx.Value(runRecordKey{}).(*runRecord); ok {
+		record.mutex.Lock()
+		defer record.mutex.Unlock()
+		return record.status.ID
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"context"
+	stderrors "errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// abandonGrace is how long cancelled runs get to save their thread state
+	// once the drain deadline has passed.
+	abandonGrace = 5 * time.Second
+	// flushTimeout bounds the publisher flush when the drain deadline has
+	// already passed.
+	flushTimeout = 5 * time.Second
+)
+
+// ErrStopping is returned for runs requested after Stop was called.
+var ErrStopping = stderrors.New("scheduler is stopping")
+
+// StopSummary describes what Stop left undone.
+type StopSummary struct {
+	// Drained reports whether all in-flight work finished before the
+	// deadline.
+	Drained bool
+	// AbandonedRuns are the runs cancelled at the deadline, with their
+	// progress at that point.
+	AbandonedRuns []RunStatus
+	// AbandonedComments counts comments handed to workers by the abandoned
+	// runs but not yet finished. Queued comments are not counted; they stay
+	// in the queue for the next consumer.
+	AbandonedComments int
+	// FlushErr is set when pending publishes could not be flushed.
+	FlushErr error
+}
+
+// Stop shuts the scheduler down. It stops scheduled polling and rejects new
+// runs, waits for in-flight runs and queue consumers to finish until ctx is
+// done, cancels whatever is still running, and flushes the publisher and the
+// archive. The publisher and other connections are left open for the caller
+// to close afterwards.
+func (s *JobScheduler) Stop(ctx context.Context) StopSummary {
+	// Closed under the mutex so that no run is added to inflight once the
+	// wait below has started.
+	s.mutex.Lock()
+	s.stopOnce.Do(func() { close(s.stopping) })
+	s.mutex.Unlock()
+	s.logger.Info("stopping job scheduler, draining in-flight work")
+
+	summary := StopSummary{Drained: wait(ctx, &s.inflight)}
+	if !summary.Drained {
+		summary.AbandonedRuns = s.runs.current()
+		summary.AbandonedComments = s.runs.pendingComments()
+		s.abandon()
+
+		graceCtx, cancel := context.WithTimeout(context.Background(), abandonGrace)
+		wait(graceCtx, &s.inflight)
+		cancel()
+	}
+	s.abandon()
+
+	flushCtx := ctx
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		flushCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+		defer cancel()
+	}
+	if err := s.publisher.Flush(flushCtx); err != nil {
+		summary.FlushErr = err
+	}
+	s.flushArchive()
+
+	fields := []zap.Field{
+		zap.Bool("drained", summary.Drained),
+		zap.Int("abandoned_runs", len(summary.AbandonedRuns)),
+		zap.Int("abandoned_comments", summary.AbandonedComments),
+	}
+	for _, run := range summary.AbandonedRuns {
+		fields = append(fields, zap.String("abandoned_run", run.ID+" "+run.Source+" "+run.Target))
+	}
+	if summary.FlushErr != nil {
+		s.logger.Error("job scheduler stopped, failed to flush publisher", append(fields, zap.Error(summary.FlushErr))...)
+	} else if !summary.Drained {
+		s.logger.Warn("job scheduler stopped, abandoned in-flight work", fields...)
+	} else {
+		s.logger.Info("job scheduler stopped", fields...)
+	}
+	return summary
+}
+
+func (s *JobScheduler) stopped() bool {
+	select {
+	case <-s.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// workContext derives the context of a run or consumer from ctx, so that
+// Stop can cancel it once the drain deadline has passed.
+func (s *JobScheduler) workContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.abandonCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// wait waits for wg until ctx is done and reports whether wg finished.
+func wait(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
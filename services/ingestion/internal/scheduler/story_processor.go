@@ -2,8 +2,6 @@ package scheduler
 
 import (
 	"context"
-	"strings"
-	"sync/atomic"
 
 	"shenanigigs/ingestion/internal/models"
 
@@ -22,37 +20,74 @@ func newStoryProcessor(scheduler *JobScheduler, logger *zap.Logger) *storyProces
 	}
 }
 
-func (p *storyProcessor) processStory(ctx context.Context, id int, stats *jobProcessingStats, commentChan chan int) {
-	post, err := p.scheduler.hnClient.GetItem(ctx, id)
+func (p *storyProcessor) processStory(ctx context.Context, id int, run *processingRun, commentChan chan commentTask) {
+	if run.checkpoint.skipStory(id) {
+		p.logger.Debug("skipping story completed by an earlier run", zap.Int("id", id))
+		return
+	}
+
+	post, changed, err := p.scheduler.hnClient.GetFreshItem(ctx, id)
 	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		run.stats.storyFailed(err)
 		p.logger.Error("failed to fetch story", zap.Int("id", id), zap.Error(err))
+		run.abortIfUnavailable(err)
 		return
 	}
+	p.scheduler.archiveItem(post.ID, post)
 
-	if p.isHiringThread(post) {
-		atomic.AddInt32(&stats.hiringThreadsFound, 1)
-		p.logger.Info("found hiring thread",
-			zap.Int("id", post.ID),
-			zap.String("title", post.Title),
-			zap.String("author", post.By),
-			zap.Int64("time", post.Time),
-			zap.Int("comments_count", len(post.Kids)))
-
-		for _, commentID := range post.Kids {
-			commentChan <- commentID
-		}
+	threadType, ok := models.ClassifyThread(post)
+	if !ok || !p.scheduler.threadTypeEnabled(threadType) {
+		run.checkpoint.storyDispatched(post.ID)
+		return
 	}
-}
 
-func (p *storyProcessor) isHiringThread(post *models.SourcePost) bool {
-	title := strings.ToLower(post.Title)
-	return (strings.Contains(title, "who is hiring?") || strings.Contains(title, "ask hn: who is hiring?")) &&
-		(post.By == "whoishiring")
+	run.stats.threadFound(post, threadType)
+	p.logger.Info("found whoishiring thread",
+		zap.Int("id", post.ID),
+		zap.String("title", post.Title),
+		zap.String("thread_type", string(threadType)),
+		zap.Int64("time", post.Time),
+		zap.Int("comments_count", len(post.Kids)))
+
+	run.threads.load(ctx, post.ID)
+
+	// Comments of a thread whose kids did not change are read from the
+	// cache. Published comments that dropped out of the kids are always
+	// fetched fresh to see whether they were removed.
+	commentIDs := append([]int{}, post.Kids...)
+	commentIDs = append(commentIDs, run.threads.missing(post.ID, post.Kids)...)
+	for i, commentID := range commentIDs {
+		if run.checkpoint.skipComment(post.ID, commentID) {
+			continue
+		}
+		task := commentTask{
+			commentID:   commentID,
+			threadID:    post.ID,
+			threadType:  threadType,
+			threadMonth: post.ThreadMonth(),
+			fresh:       changed || i >= len(post.Kids),
+		}
+		run.checkpoint.commentDispatched(post.ID)
+		select {
+		case commentChan <- task:
+			run.stats.commentDispatched()
+		case <-ctx.Done():
+			return
+		}
+	}
+	run.checkpoint.storyDispatched(post.ID)
 }
 
-func (p *storyProcessor) feedStories(stories []int, storyChan chan int) {
+func (p *storyProcessor) feedStories(ctx context.Context, stories []int, storyChan chan int) {
+	defer close(storyChan)
 	for _, id := range stories {
-		storyChan <- id
+		select {
+		case storyChan <- id:
+		case <-ctx.Done():
+			return
+		}
 	}
-	close(storyChan)
 }
@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"shenanigigs/ingestion/internal/api"
+	"shenanigigs/ingestion/internal/config"
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// fakeSource serves items from memory, or fails every fetch with err, and
+// records which were fetched fresh and which through the cache.
+type fakeSource struct {
+	api.JobSourceClient
+	items   map[int]*models.SourcePost
+	changed map[int]bool
+	err     error
+	fresh   []int
+	cached  []int
+}
+
+func (f *fakeSource) GetItem(ctx context.Context, id int) (*models.SourcePost, error) {
+	f.cached = append(f.cached, id)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.items[id], nil
+}
+
+func (f *fakeSource) GetFreshItem(ctx context.Context, id int) (*models.SourcePost, bool, error) {
+	f.fresh = append(f.fresh, id)
+	if f.err != nil {
+		return nil, false, f.err
+	}
+	return f.items[id], f.changed[id], nil
+}
+
+func newFakeScheduler(source *fakeSource, threads ThreadStore) *JobScheduler {
+	return &JobScheduler{
+		hnClient:    source,
+		threadStore: threads,
+		logger:      zap.NewNop(),
+		config:      &config.Config{FetchReplies: true, ThreadStateTTL: time.Hour},
+	}
+}
+
+func TestProcessStoryMarksFreshComments(t *testing.T) {
+	story := &models.SourcePost{
+		ID:    1,
+		Type:  "story",
+		By:    "whoishiring",
+		Title: "Ask HN: Who is hiring? (October 2026)",
+		Kids:  models.IntSlice{2, 3},
+	}
+
+	tests := []struct {
+		name      string
+		changed   bool
+		wantFresh map[int]bool
+	}{
+		{"kids unchanged", false, map[int]bool{2: false, 3: false, 4: true}},
+		{"kids changed", true, map[int]bool{2: true, 3: true, 4: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			threads := NewMemoryThreadStore()
+			// Comment 4 was published earlier and has since dropped out of
+			// the story's kids.
+			if err := threads.SaveThread(ctx, story.ID, map[int]string{4: "hash"}, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			source := &fakeSource{
+				items:   map[int]*models.SourcePost{story.ID: story},
+				changed: map[int]bool{story.ID: tt.changed},
+			}
+			s := newFakeScheduler(source, threads)
+			run := s.newProcessingRun(ctx, nil, func() {})
+
+			commentChan := make(chan commentTask, 10)
+			newStoryProcessor(s, s.logger).processStory(ctx, story.ID, run, commentChan)
+			close(commentChan)
+
+			got := make(map[int]bool)
+			for task := range commentChan {
+				got[task.commentID] = task.fresh
+			}
+			if len(got) != len(tt.wantFresh) {
+				t.Fatalf("dispatched %v, want %v", got, tt.wantFresh)
+			}
+			for id, want := range tt.wantFresh {
+				if got[id] != want {
+					t.Errorf("comment %d fresh = %v, want %v", id, got[id], want)
+				}
+			}
+			if !slices.Equal(source.fresh, []int{story.ID}) {
+				t.Errorf("fetched %v fresh, want only the story", source.fresh)
+			}
+		})
+	}
+}
+
+func TestFetchPosterRepliesFetchesChangedItemsFresh(t *testing.T) {
+	// 10 is the posting, 11 the poster's reply to it and 12 an answer to
+	// that reply by someone else.
+	items := map[int]*models.SourcePost{
+		10: {ID: 10, By: "acme", Kids: models.IntSlice{11}},
+		11: {ID: 11, By: "acme", Text: "Filled, thanks all!", Time: 200, Kids: models.IntSlice{12}},
+		12: {ID: 12, By: "someone", Time: 300},
+	}
+
+	tests := []struct {
+		name       string
+		fresh      bool
+		changed    map[int]bool
+		wantFresh  []int
+		wantCached []int
+	}{
+		{"comment unchanged", false, nil, nil, []int{11, 12}},
+		{"comment changed", true, map[int]bool{11: false}, []int{11}, []int{12}},
+		{"reply changed", true, map[int]bool{11: true}, []int{11, 12}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeSource{items: items, changed: tt.changed}
+			s := newFakeScheduler(source, NewMemoryThreadStore())
+
+			updates, err := s.fetchPosterReplies(context.Background(), 1, items[10], tt.fresh)
+			if err != nil {
+				t.Fatalf("fetchPosterReplies() error = %v", err)
+			}
+			if len(updates) != 1 || updates[0].ID != "11" {
+				t.Errorf("updates = %+v, want the poster's reply 11", updates)
+			}
+			if !slices.Equal(source.fresh, tt.wantFresh) {
+				t.Errorf("fetched %v fresh, want %v", source.fresh, tt.wantFresh)
+			}
+			if !slices.Equal(source.cached, tt.wantCached) {
+				t.Errorf("fetched %v through the cache, want %v", source.cached, tt.wantCached)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ThreadStore keeps the published-comment state of threads: the content
+// hash of every comment published so far, by thread. Runs on every replica
+// save their changes to the same threads, so SaveThread applies changes per
+// comment and never replaces a whole thread.
+type ThreadStore interface {
+	// LoadThread returns the hash of each published comment of a thread. A
+	// thread without state has no comments.
+	LoadThread(ctx context.Context, threadID int) (map[int]string, error)
+	// SaveThread sets the hash of each changed comment, or forgets the
+	// comment when its hash is empty, and keeps the thread for ttl.
+	SaveThread(ctx context.Context, threadID int, changes map[int]string, ttl time.Duration) error
+}
+
+// RedisThreadStore keeps each thread in a Redis hash of comment ID to
+// content hash, so concurrent savers only touch their own comments.
+type RedisThreadStore struct {
+	client *redis.Client
+}
+
+func NewRedisThreadStore(client *redis.Client) *RedisThreadStore {
+	return &RedisThreadStore{client: client}
+}
+
+func threadCommentsKey(threadID int) string {
+	return fmt.Sprintf("hn:thread:%d:comments", threadID)
+}
+
+func (s *RedisThreadStore) LoadThread(ctx context.Context, threadID int) (map[int]string, error) {
+	fields, err := s.client.HGetAll(ctx, threadCommentsKey(threadID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("loading thread %d: %w", threadID, err)
+	}
+
+	comments := make(map[int]string, len(fields))
+	for field, hash := range fields {
+		if commentID, err := strconv.Atoi(field); err == nil {
+			comments[commentID] = hash
+		}
+	}
+	return comments, nil
+}
+
+func (s *RedisThreadStore) SaveThread(ctx context.Context, threadID int, changes map[int]string, ttl time.Duration) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	key := threadCommentsKey(threadID)
+
+	var set []any
+	var forget []string
+	for commentID, hash := range changes {
+		if hash == "" {
+			forget = append(forget, strconv.Itoa(commentID))
+		} else {
+			set = append(set, strconv.Itoa(commentID), hash)
+		}
+	}
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(set) > 0 {
+			pipe.HSet(ctx, key, set...)
+		}
+		if len(forget) > 0 {
+			pipe.HDel(ctx, key, forget...)
+		}
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("saving thread %d: %w", threadID, err)
+	}
+	return nil
+}
+
+// MemoryThreadStore keeps thread state in the process. It is used when
+// nothing may be written to the shared store, such as in dry runs. Expiry is
+// not implemented.
+type MemoryThreadStore struct {
+	mutex   sync.Mutex
+	threads map[int]map[int]string
+}
+
+func NewMemoryThreadStore() *MemoryThreadStore {
+	return &MemoryThreadStore{threads: make(map[int]map[int]string)}
+}
+
+func (s *MemoryThreadStore) LoadThread(ctx context.Context, threadID int) (map[int]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	comments := make(map[int]string, len(s.threads[threadID]))
+	for commentID, hash := range s.threads[threadID] {
+		comments[commentID] = hash
+	}
+	return comments, nil
+}
+
+func (s *MemoryThreadStore) SaveThread(ctx context.Context, threadID int, changes map[int]string, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	comments := s.threads[threadID]
+	if comments == nil {
+		comments = make(map[int]string)
+		s.threads[threadID] = comments
+	}
+	for commentID, hash := range changes {
+		if hash == "" {
+			delete(comments, commentID)
+		} else {
+			comments[commentID] = hash
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func newTestThreadStore(t *testing.T) (*RedisThreadStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisThreadStore(client), server
+}
+
+func TestRedisThreadStoreSave(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestThreadStore(t)
+
+	if err := store.SaveThread(ctx, 1, map[int]string{10: "a", 11: "b"}, time.Hour); err != nil {
+		t.Fatalf("SaveThread() error = %v", err)
+	}
+	if err := store.SaveThread(ctx, 1, map[int]string{10: "", 11: "b2", 12: "c"}, time.Hour); err != nil {
+		t.Fatalf("SaveThread() error = %v", err)
+	}
+
+	comments, err := store.LoadThread(ctx, 1)
+	if err != nil {
+		t.Fatalf("LoadThread() error = %v", err)
+	}
+	want := map[int]string{11: "b2", 12: "c"}
+	if len(comments) != len(want) {
+		t.Fatalf("LoadThread() = %v, want %v", comments, want)
+	}
+	for id, hash := range want {
+		if comments[id] != hash {
+			t.Errorf("comment %d hash = %q, want %q", id, comments[id], hash)
+		}
+	}
+	if ttl := server.TTL(threadCommentsKey(1)); ttl != time.Hour {
+		t.Errorf("TTL = %v, want 1h", ttl)
+	}
+
+	if comments, err := store.LoadThread(ctx, 2); err != nil || len(comments) != 0 {
+		t.Errorf("LoadThread() of an unknown thread = %v, %v, want no comments", comments, err)
+	}
+}
+
+func TestThreadTrackerChanged(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestThreadStore(t)
+	if err := store.SaveThread(ctx, 1, map[int]string{10: contentHash("a")}, time.Hour); err != nil {
+		t.Fatalf("SaveThread() error = %v", err)
+	}
+
+	tracker := newThreadTracker(store, zap.NewNop(), time.Hour)
+	tracker.load(ctx, 1)
+
+	tests := []struct {
+		name      string
+		threadID  int
+		commentID int
+		text      string
+		want      bool
+	}{
+		{"published and unchanged", 1, 10, "a", false},
+		{"published and edited", 1, 10, "a, edited", true},
+		{"new comment", 1, 11, "b", true},
+		{"thread not loaded", 2, 20, "c", true},
+	}
+	for _, tt := range tests {
+		if got := tracker.changed(tt.threadID, tt.commentID, contentHash(tt.text)); got != tt.want {
+			t.Errorf("%s: changed() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	tracker.markPublished(1, 11, contentHash("b"))
+	if tracker.changed(1, 11, contentHash("b")) {
+		t.Error("changed() = true for a comment published in this run")
+	}
+}
+
+func TestConcurrentTrackersKeepEachOthersChanges(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestThreadStore(t)
+	if err := store.SaveThread(ctx, 1, map[int]string{10: "a", 11: "b"}, time.Hour); err != nil {
+		t.Fatalf("SaveThread() error = %v", err)
+	}
+
+	// Both trackers load the thread before either saves.
+	first := newThreadTracker(store, zap.NewNop(), time.Hour)
+	second := newThreadTracker(store, zap.NewNop(), time.Hour)
+	first.load(ctx, 1)
+	second.load(ctx, 1)
+
+	first.markPublished(1, 12, "c")
+	first.forget(1, 10)
+	second.markPublished(1, 13, "d")
+	second.markPublished(1, 11, "b2")
+
+	if err := first.flush(ctx); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+	if err := second.flush(ctx); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+
+	comments, err := store.LoadThread(ctx, 1)
+	if err != nil {
+		t.Fatalf("LoadThread() error = %v", err)
+	}
+	want := map[int]string{11: "b2", 12: "c", 13: "d"}
+	if len(comments) != len(want) {
+		t.Fatalf("LoadThread() = %v, want %v", comments, want)
+	}
+	for id, hash := range want {
+		if comments[id] != hash {
+			t.Errorf("comment %d hash = %q, want %q", id, comments[id], hash)
+		}
+	}
+}
+
+// failingThreadStore fails every call.
+type failingThreadStore struct{}
+
+var errStoreDown = stderrors.New("store down")
+
+func (failingThreadStore) LoadThread(ctx context.Context, threadID int) (map[int]string, error) {
+	return nil, errStoreDown
+}
+
+func (failingThreadStore) SaveThread(ctx context.Context, threadID int, changes map[int]string, ttl time.Duration) error {
+	return errStoreDown
+}
+
+func TestFlushKeepsChangesOnError(t *testing.T) {
+	ctx := context.Background()
+	tracker := newThreadTracker(failingThreadStore{}, zap.NewNop(), time.Hour)
+
+	// A failed load treats every comment as new, without losing the stored
+	// thread when the changes are saved.
+	tracker.load(ctx, 1)
+	tracker.markPublished(1, 10, "a")
+	if err := tracker.flush(ctx); !stderrors.Is(err, errStoreDown) {
+		t.Fatalf("flush() error = %v, want %v", err, errStoreDown)
+	}
+
+	store, _ := newTestThreadStore(t)
+	if err := store.SaveThread(ctx, 1, map[int]string{11: "b"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	tracker.store = store
+	if err := tracker.flush(ctx); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+	comments, _ := store.LoadThread(ctx, 1)
+	if len(comments) != 2 || comments[10] != "a" || comments[11] != "b" {
+		t.Errorf("LoadThread() = %v, want the kept change and the stored comment", comments)
+	}
+}
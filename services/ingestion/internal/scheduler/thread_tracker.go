@@ -0,0 +1,186 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const defaultThreadStateTTL = 90 * 24 * time.Hour
+
+// threadTracker holds the published-comment state of every thread touched by
+// a run. State is loaded from the store when a thread is first seen and
+// written back once the run completes. Only the comments changed by this
+// tracker are written back, so that runs and queue consumers working on the
+// same thread keep each other's changes.
+type threadTracker struct {
+	store   ThreadStore
+	logger  *zap.Logger
+	ttl     time.Duration
+	mutex   sync.Mutex
+	threads map[int]*models.ThreadState
+	// changes holds the new hash of each changed comment by thread, or an
+	// empty hash for a forgotten comment.
+	changes map[int]map[int]string
+}
+
+func newThreadTracker(store ThreadStore, logger *zap.Logger, ttl time.Duration) *threadTracker {
+	if ttl <= 0 {
+		ttl = defaultThreadStateTTL
+	}
+	return &threadTracker{
+		store:   store,
+		logger:  logger,
+		ttl:     ttl,
+		threads: make(map[int]*models.ThreadState),
+		changes: make(map[int]map[int]string),
+	}
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *threadTracker) load(ctx context.Context, threadID int) {
+	t.mutex.Lock()
+	_, loaded := t.threads[threadID]
+	t.mutex.Unlock()
+	if loaded {
+		return
+	}
+
+	state := models.NewThreadState(threadID)
+	comments, err := t.store.LoadThread(ctx, threadID)
+	if err != nil {
+		t.logger.Warn("failed to load thread state, treating every comment as new",
+			zap.Int("thread_id", threadID),
+			zap.Error(err))
+	} else {
+		state.Comments = comments
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, loaded := t.threads[threadID]; !loaded {
+		t.threads[threadID] = state
+	}
+}
+
+// changed reports whether the comment is new to the thread or its content
+// differs from what was last published.
+func (t *threadTracker) changed(threadID, commentID int, hash string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	state, ok := t.threads[threadID]
+	if !ok {
+		return true
+	}
+	return state.Comments[commentID] != hash
+}
+
+// published reports whether the comment was published in an earlier run or
+// earlier in this one.
+func (t *threadTracker) published(threadID, commentID int) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	state, ok := t.threads[threadID]
+	if !ok {
+		return false
+	}
+	_, ok = state.Comments[commentID]
+	return ok
+}
+
+// missing returns the published comments of a thread that are not in kids.
+// HN drops deleted comments without replies from their parent's kids, so
+// these have to be fetched on their own to notice the removal.
+func (t *threadTracker) missing(threadID int, kids []int) []int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	state, ok := t.threads[threadID]
+	if !ok {
+		return nil
+	}
+
+	present := make(map[int]bool, len(kids))
+	for _, id := range kids {
+		present[id] = true
+	}
+
+	var missing []int
+	for id := range state.Comments {
+		if !present[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Ints(missing)
+	return missing
+}
+
+// forget drops a comment that no longer exists from the thread state.
+func (t *threadTracker) forget(threadID, commentID int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	state, ok := t.threads[threadID]
+	if !ok {
+		return
+	}
+	if _, ok := state.Comments[commentID]; ok {
+		delete(state.Comments, commentID)
+		t.change(threadID, commentID, "")
+	}
+}
+
+func (t *threadTracker) markPublished(threadID, commentID int, hash string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	state, ok := t.threads[threadID]
+	if !ok {
+		state = models.NewThreadState(threadID)
+		t.threads[threadID] = state
+	}
+	state.Comments[commentID] = hash
+	t.change(threadID, commentID, hash)
+}
+
+func (t *threadTracker) change(threadID, commentID int, hash string) {
+	if t.changes[threadID] == nil {
+		t.changes[threadID] = make(map[int]string)
+	}
+	t.changes[threadID][commentID] = hash
+}
+
+// flush saves the comments changed since the last flush. A thread whose
+// changes could not be saved keeps them for the next flush.
+func (t *threadTracker) flush(ctx context.Context) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var firstErr error
+	for threadID, changes := range t.changes {
+		if err := t.store.SaveThread(ctx, threadID, changes, t.ttl); err != nil {
+			t.logger.Error("failed to save thread state",
+				zap.Int("thread_id", threadID),
+				zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		delete(t.changes, threadID)
+	}
+	return firstErr
+}
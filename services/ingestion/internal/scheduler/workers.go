@@ -3,7 +3,6 @@ package scheduler
 import (
 	"context"
 	"sync"
-	"sync/atomic"
 
 	"go.uber.org/zap"
 )
@@ -20,42 +19,70 @@ func newWorkerManager(scheduler *JobScheduler, logger *zap.Logger) *workerManage
 	}
 }
 
-func (w *workerManager) startWorkers(ctx context.Context, stats *jobProcessingStats, storyChan chan int, commentChan chan int, doneChan chan bool) *sync.WaitGroup {
-	var wg sync.WaitGroup
+func (w *workerManager) startWorkers(ctx context.Context, run *processingRun, storyChan chan int, commentChan chan commentTask, doneChan chan bool) *sync.WaitGroup {
+	var storyWG, commentWG, wg sync.WaitGroup
 
-	w.startCommentWorkers(ctx, &wg, stats, commentChan)
-	w.startStoryWorkers(ctx, &wg, stats, storyChan, commentChan)
+	if w.scheduler.queue != nil {
+		w.startQueueWriters(ctx, &commentWG, run, commentChan)
+	} else {
+		w.startCommentWorkers(ctx, &commentWG, run, commentChan)
+	}
+	w.startStoryWorkers(ctx, &storyWG, run, storyChan, commentChan)
+
+	// Comment workers drain commentChan until it is closed, which can only
+	// happen once every story worker has stopped sending to it.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		storyWG.Wait()
+		close(commentChan)
+		commentWG.Wait()
+	}()
 
 	return &wg
 }
 
-func (w *workerManager) startCommentWorkers(ctx context.Context, wg *sync.WaitGroup, stats *jobProcessingStats, commentChan chan int) {
+func (w *workerManager) startCommentWorkers(ctx context.Context, wg *sync.WaitGroup, run *processingRun, commentChan chan commentTask) {
 	const numWorkers = 10
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for commentID := range commentChan {
-				if err := w.scheduler.processComment(ctx, commentID); err != nil {
+			for task := range commentChan {
+				if ctx.Err() != nil {
+					continue
+				}
+				result, err := w.scheduler.processComment(ctx, task, run)
+				if err != nil {
+					if ctx.Err() != nil {
+						continue
+					}
+					run.stats.commentFailed(err)
+					run.checkpoint.commentFailed(task.threadID)
 					w.logger.Error("failed to process comment",
-						zap.Int("comment_id", commentID),
+						zap.Int("comment_id", task.commentID),
 						zap.Error(err))
+					run.abortIfUnavailable(err)
 					continue
 				}
-				atomic.AddInt32(&stats.commentsProcessed, 1)
+				run.stats.commentDone(result)
+				run.checkpoint.commentCompleted(task.threadID, task.commentID)
 			}
 		}()
 	}
 }
 
-func (w *workerManager) startStoryWorkers(ctx context.Context, wg *sync.WaitGroup, stats *jobProcessingStats, storyChan chan int, commentChan chan int) {
+func (w *workerManager) startStoryWorkers(ctx context.Context, wg *sync.WaitGroup, run *processingRun, storyChan chan int, commentChan chan commentTask) {
 	const storyWorkers = 5
 	for i := 0; i < storyWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for id := range storyChan {
-				w.scheduler.processStory(ctx, id, stats, commentChan)
+				if ctx.Err() != nil {
+					continue
+				}
+				w.scheduler.processStory(ctx, id, run, commentChan)
 			}
 		}()
 	}
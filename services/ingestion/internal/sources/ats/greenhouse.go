@@ -0,0 +1,118 @@
+package ats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"shenanigigs/ingestion/internal/models"
+)
+
+// greenhouseBoard is the response of GET /boards/{token}/jobs?content=true.
+type greenhouseBoard struct {
+	Jobs []greenhouseJob `json:"jobs"`
+}
+
+type greenhouseJob struct {
+	ID             int64  `json:"id"`
+	Title          string `json:"title"`
+	UpdatedAt      string `json:"updated_at"`
+	FirstPublished string `json:"first_published"`
+	AbsoluteURL    string `json:"absolute_url"`
+	CompanyName    string `json:"company_name"`
+	Content        string `json:"content"`
+	Location       struct {
+		Name string `json:"name"`
+	} `json:"location"`
+	Departments []struct {
+		Name string `json:"name"`
+	} `json:"departments"`
+	Metadata []struct {
+		Name  string          `json:"name"`
+		Value json.RawMessage `json:"value"`
+	} `json:"metadata"`
+}
+
+func (s *Source) fetchGreenhouse(ctx context.Context, board Board) ([]*models.JobPosting, error) {
+	endpoint := fmt.Sprintf("%s/boards/%s/jobs?content=true",
+		strings.TrimRight(s.options.GreenhouseBaseURL, "/"), url.PathEscape(board.Token))
+
+	var response greenhouseBoard
+	if err := s.getJSON(ctx, endpoint, &response); err != nil {
+		return nil, err
+	}
+
+	postings := make([]*models.JobPosting, 0, len(response.Jobs))
+	for _, job := range response.Jobs {
+		postings = append(postings, job.toJobPosting(board))
+	}
+	return postings, nil
+}
+
+func (j greenhouseJob) toJobPosting(board Board) *models.JobPosting {
+	// Greenhouse returns the job description as entity-escaped HTML.
+	description := html.UnescapeString(j.Content)
+
+	var departments []string
+	for _, department := range j.Departments {
+		if name := strings.TrimSpace(department.Name); name != "" {
+			departments = append(departments, name)
+		}
+	}
+
+	company := strings.TrimSpace(j.CompanyName)
+	if company == "" {
+		company = board.Token
+	}
+
+	posting := &models.JobPosting{
+		ID:             board.Token + ":" + strconv.FormatInt(j.ID, 10),
+		Title:          strings.TrimSpace(j.Title),
+		PostedAt:       parseTimestamp(j.FirstPublished, j.UpdatedAt),
+		Source:         board.Provider,
+		URL:            j.AbsoluteURL,
+		Company:        company,
+		Location:       strings.TrimSpace(j.Location.Name),
+		Department:     strings.Join(departments, ", "),
+		EmploymentType: j.employmentType(),
+		Board:          board.String(),
+	}
+	posting.SetHTML(description)
+	return posting
+}
+
+// employmentType looks for the custom "Employment Type" field most boards
+// define. Its value is a string for single-select fields and a list for
+// multi-select ones.
+func (j greenhouseJob) employmentType() string {
+	for _, field := range j.Metadata {
+		name := strings.ToLower(field.Name)
+		if !strings.Contains(name, "employment type") && !strings.Contains(name, "commitment") {
+			continue
+		}
+
+		var single string
+		if err := json.Unmarshal(field.Value, &single); err == nil {
+			return strings.TrimSpace(single)
+		}
+		var multiple []string
+		if err := json.Unmarshal(field.Value, &multiple); err == nil {
+			return strings.Join(multiple, ", ")
+		}
+	}
+	return ""
+}
+
+func parseTimestamp(values ...string) time.Time {
+	for _, value := range values {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
@@ -0,0 +1,91 @@
+package ats
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"shenanigigs/ingestion/internal/models"
+)
+
+// leverPosting is one element of GET /postings/{token}?mode=json.
+type leverPosting struct {
+	ID            string `json:"id"`
+	Text          string `json:"text"`
+	CreatedAt     int64  `json:"createdAt"`
+	HostedURL     string `json:"hostedUrl"`
+	Description   string `json:"description"`
+	Additional    string `json:"additional"`
+	WorkplaceType string `json:"workplaceType"`
+	Categories    struct {
+		Location   string `json:"location"`
+		Team       string `json:"team"`
+		Department string `json:"department"`
+		Commitment string `json:"commitment"`
+	} `json:"categories"`
+	Lists []struct {
+		Text    string `json:"text"`
+		Content string `json:"content"`
+	} `json:"lists"`
+}
+
+func (s *Source) fetchLever(ctx context.Context, board Board) ([]*models.JobPosting, error) {
+	endpoint := fmt.Sprintf("%s/postings/%s?mode=json",
+		strings.TrimRight(s.options.LeverBaseURL, "/"), url.PathEscape(board.Token))
+
+	var response []leverPosting
+	if err := s.getJSON(ctx, endpoint, &response); err != nil {
+		return nil, err
+	}
+
+	postings := make([]*models.JobPosting, 0, len(response))
+	for _, posting := range response {
+		postings = append(postings, posting.toJobPosting(board))
+	}
+	return postings, nil
+}
+
+func (p leverPosting) toJobPosting(board Board) *models.JobPosting {
+	// Lever splits the description into an intro, a number of titled lists
+	// and a closing section; stitch them back together.
+	var description strings.Builder
+	description.WriteString(p.Description)
+	for _, list := range p.Lists {
+		fmt.Fprintf(&description, "<h3>%s</h3><ul>%s</ul>", list.Text, list.Content)
+	}
+	description.WriteString(p.Additional)
+
+	department := p.Categories.Department
+	if department == "" {
+		department = p.Categories.Team
+	} else if p.Categories.Team != "" && p.Categories.Team != department {
+		department += ", " + p.Categories.Team
+	}
+
+	location := strings.TrimSpace(p.Categories.Location)
+	if p.WorkplaceType == "remote" && !strings.Contains(strings.ToLower(location), "remote") {
+		location = strings.TrimSpace("Remote " + location)
+	}
+
+	postedAt := time.Now()
+	if p.CreatedAt > 0 {
+		postedAt = time.UnixMilli(p.CreatedAt)
+	}
+
+	posting := &models.JobPosting{
+		ID:             board.Token + ":" + p.ID,
+		Title:          strings.TrimSpace(p.Text),
+		PostedAt:       postedAt,
+		Source:         board.Provider,
+		URL:            p.HostedURL,
+		Company:        board.Token,
+		Location:       location,
+		Department:     department,
+		EmploymentType: strings.TrimSpace(p.Categories.Commitment),
+		Board:          board.String(),
+	}
+	posting.SetHTML(description.String())
+	return posting
+}
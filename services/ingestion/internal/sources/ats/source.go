@@ -0,0 +1,230 @@
+package ats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"shenanigigs/common/cache"
+	"shenanigigs/common/telemetry"
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	Name = "ats"
+
+	ProviderGreenhouse = "greenhouse"
+	ProviderLever      = "lever"
+
+	defaultGreenhouseBaseURL = "https://boards-api.greenhouse.io/v1"
+	defaultLeverBaseURL      = "https://api.lever.co/v0"
+	defaultSeenTTL           = 30 * 24 * time.Hour
+)
+
+var tracer = telemetry.GetTracer("shenanigigs/ingestion/sources/ats")
+
+// Board identifies a public job board on an applicant tracking system.
+type Board struct {
+	Provider string
+	Token    string
+}
+
+func (b Board) String() string {
+	return b.Provider + ":" + b.Token
+}
+
+// ParseBoards parses board references of the form "provider:token", for
+// example "greenhouse:stripe" or "lever:netflix".
+func ParseBoards(values []string) ([]Board, error) {
+	boards := make([]Board, 0, len(values))
+	for _, value := range values {
+		provider, token, ok := strings.Cut(strings.TrimSpace(value), ":")
+		if !ok || token == "" {
+			return nil, fmt.Errorf("invalid board %q, expected provider:token", value)
+		}
+		provider = strings.ToLower(provider)
+		if provider != ProviderGreenhouse && provider != ProviderLever {
+			return nil, fmt.Errorf("unknown board provider %q", provider)
+		}
+		boards = append(boards, Board{Provider: provider, Token: token})
+	}
+	return boards, nil
+}
+
+type Options struct {
+	GreenhouseBaseURL string
+	LeverBaseURL      string
+	SeenTTL           time.Duration
+}
+
+// Source fetches the public job lists of Greenhouse and Lever boards. The
+// structured fields these boards expose are copied into the posting as they
+// are, so processing does not have to guess them from the text. A posting is
+// only republished when its content changes.
+type Source struct {
+	client  *http.Client
+	cache   cache.Cache
+	logger  *zap.Logger
+	boards  []Board
+	options Options
+}
+
+func New(client *http.Client, cache cache.Cache, logger *zap.Logger, boards []Board, options Options) *Source {
+	if options.GreenhouseBaseURL == "" {
+		options.GreenhouseBaseURL = defaultGreenhouseBaseURL
+	}
+	if options.LeverBaseURL == "" {
+		options.LeverBaseURL = defaultLeverBaseURL
+	}
+	if options.SeenTTL <= 0 {
+		options.SeenTTL = defaultSeenTTL
+	}
+	return &Source{
+		client:  client,
+		cache:   cache,
+		logger:  logger,
+		boards:  boards,
+		options: options,
+	}
+}
+
+func (s *Source) Name() string {
+	return Name
+}
+
+func (s *Source) Fetch(ctx context.Context, publisher messaging.Publisher) error {
+	ctx, span := tracer.Start(ctx, "ats.Fetch")
+	defer span.End()
+	span.SetAttributes(telemetry.Int("ats.boards", len(s.boards)))
+
+	var errs []error
+	for _, board := range s.boards {
+		if err := s.fetchBoard(ctx, board, publisher); err != nil {
+			span.RecordError(err)
+			s.logger.Error("failed to fetch board", zap.String("board", board.String()), zap.Error(err))
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Unavailable(fmt.Sprintf("%d of %d boards failed", len(errs), len(s.boards)), stderrors.Join(errs...))
+	}
+	return nil
+}
+
+func (s *Source) fetchBoard(ctx context.Context, board Board, publisher messaging.Publisher) error {
+	ctx, span := tracer.Start(ctx, "ats.fetchBoard")
+	defer span.End()
+	span.SetAttributes(telemetry.String("ats.board", board.String()))
+
+	var postings []*models.JobPosting
+	var err error
+	switch board.Provider {
+	case ProviderGreenhouse:
+		postings, err = s.fetchGreenhouse(ctx, board)
+	case ProviderLever:
+		postings, err = s.fetchLever(ctx, board)
+	default:
+		err = errors.InvalidInput(fmt.Sprintf("unknown board provider %q", board.Provider), nil)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	published, skipped := 0, 0
+	for _, posting := range postings {
+		key := seenKey(board, posting.ID)
+		hash := postingHash(posting)
+
+		var previous string
+		if err := s.cache.Get(ctx, key, &previous); err == nil && previous == hash {
+			skipped++
+			continue
+		} else if err != nil && err != cache.ErrNotFound {
+			s.logger.Warn("cache error, publishing posting anyway", zap.String("id", posting.ID), zap.Error(err))
+		}
+
+		if err := publisher.PublishJobPosting(ctx, posting); err != nil {
+			span.RecordError(err)
+			return err
+		}
+		published++
+
+		if err := s.cache.Set(ctx, key, hash, s.options.SeenTTL); err != nil {
+			s.logger.Warn("failed to record posting as seen", zap.String("id", posting.ID), zap.Error(err))
+		}
+	}
+
+	span.SetAttributes(
+		telemetry.Int("ats.postings", len(postings)),
+		telemetry.Int("ats.published", published),
+		telemetry.Int("ats.skipped", skipped),
+	)
+	s.logger.Info("fetched board",
+		zap.String("board", board.String()),
+		zap.Int("postings", len(postings)),
+		zap.Int("published", published),
+		zap.Int("skipped", skipped))
+	return nil
+}
+
+func (s *Source) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.InvalidInput("creating request", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Unavailable("executing request", err)
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			s.logger.Warn("failed to close response body", zap.Error(cerr))
+		}
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusNotFound:
+		return errors.NotFound(fmt.Sprintf("board not found: %s", url), nil)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return errors.RateLimit(fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return errors.Unavailable(fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
+	default:
+		return errors.Internal(fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Internal("decoding response", err)
+	}
+	return nil
+}
+
+func seenKey(board Board, id string) string {
+	return fmt.Sprintf("ats:%s:%s:%s", board.Provider, board.Token, id)
+}
+
+func postingHash(posting *models.JobPosting) string {
+	data, _ := json.Marshal(struct {
+		Title, Description, Company, Location, Department, EmploymentType, URL string
+	}{
+		posting.Title, posting.Description, posting.Company, posting.Location,
+		posting.Department, posting.EmploymentType, posting.URL,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
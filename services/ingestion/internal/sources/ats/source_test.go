@@ -0,0 +1,342 @@
+package ats
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shenanigigs/common/cache"
+	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// memoryCache is a cache.Cache backed by a map, standing in for Redis.
+type memoryCache struct {
+	mutex  sync.Mutex
+	values map[string][]byte
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: make(map[string][]byte)}
+}
+
+func (c *memoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.values[key] = data
+	return nil
+}
+
+func (c *memoryCache) Get(ctx context.Context, key string, value interface{}) error {
+	c.mutex.Lock()
+	data, ok := c.values[key]
+	c.mutex.Unlock()
+	if !ok {
+		return cache.ErrNotFound
+	}
+	return json.Unmarshal(data, value)
+}
+
+func (c *memoryCache) Delete(ctx context.Context, key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+func (c *memoryCache) Clear(ctx context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.values = make(map[string][]byte)
+	return nil
+}
+
+func (c *memoryCache) Close() error {
+	return nil
+}
+
+// recordingPublisher keeps the job postings it is given. Sources only
+// publish job postings, so the other methods are left unimplemented.
+type recordingPublisher struct {
+	messaging.Publisher
+	postings []*models.JobPosting
+}
+
+func newRecordingPublisher() *recordingPublisher {
+	return &recordingPublisher{}
+}
+
+func (p *recordingPublisher) PublishJobPosting(ctx context.Context, posting *models.JobPosting) error {
+	p.postings = append(p.postings, posting)
+	return nil
+}
+
+// boardServer serves the Greenhouse and Lever fixtures. The response bodies
+// can be replaced to simulate a board changing between polls.
+type boardServer struct {
+	*httptest.Server
+	mutex  sync.Mutex
+	bodies map[string][]byte
+}
+
+func newBoardServer(t *testing.T) *boardServer {
+	t.Helper()
+	server := &boardServer{bodies: map[string][]byte{
+		"/boards/acme/jobs": readFixture(t, "testdata/greenhouse_jobs.json"),
+		"/postings/globex":  readFixture(t, "testdata/lever_postings.json"),
+	}}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		body, ok := server.bodies[r.URL.Path]
+		server.mutex.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *boardServer) replace(path, old, new string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.bodies[path] = []byte(strings.Replace(string(s.bodies[path]), old, new, 1))
+}
+
+func (s *boardServer) source(boards ...Board) *Source {
+	return New(s.Client(), newMemoryCache(), zap.NewNop(), boards, Options{
+		GreenhouseBaseURL: s.URL,
+		LeverBaseURL:      s.URL,
+	})
+}
+
+func readFixture(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	return data
+}
+
+func TestFetchGreenhouse(t *testing.T) {
+	server := newBoardServer(t)
+	board := Board{Provider: ProviderGreenhouse, Token: "acme"}
+	publisher := newRecordingPublisher()
+
+	if err := server.source(board).Fetch(context.Background(), publisher); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	want := []models.JobPosting{
+		{
+			ID:             "acme:4012345",
+			Title:          "Senior Backend Engineer",
+			URL:            "https://boards.greenhouse.io/acme/jobs/4012345",
+			PostedAt:       time.Date(2024, 9, 20, 13, 0, 0, 0, time.UTC),
+			Company:        "Acme Corp",
+			Location:       "Berlin, Germany",
+			Department:     "Engineering, Payments",
+			EmploymentType: "Full-time",
+		},
+		{
+			ID:             "acme:4012399",
+			Title:          "Technical Writer",
+			URL:            "https://boards.greenhouse.io/acme/jobs/4012399",
+			PostedAt:       time.Date(2024, 10, 2, 8, 0, 0, 0, time.UTC),
+			Company:        "acme",
+			Location:       "Remote - US",
+			EmploymentType: "Part-time, Contract",
+		},
+	}
+	checkPostings(t, publisher.postings, want, board)
+
+	description := publisher.postings[0].Description
+	for _, fragment := range []string{"About the role", "payments APIs in Go", "PostgreSQL & Kafka"} {
+		if !strings.Contains(description, fragment) {
+			t.Errorf("Description = %q, want it to contain %q", description, fragment)
+		}
+	}
+	if strings.Contains(description, "&lt;") || strings.Contains(description, "<p>") {
+		t.Errorf("Description = %q, want the escaped HTML decoded to text", description)
+	}
+}
+
+func TestFetchLever(t *testing.T) {
+	server := newBoardServer(t)
+	board := Board{Provider: ProviderLever, Token: "globex"}
+	publisher := newRecordingPublisher()
+
+	if err := server.source(board).Fetch(context.Background(), publisher); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	want := []models.JobPosting{
+		{
+			ID:             "globex:5ac21346-8e0c-4494-8e7a-3eb92ff77902",
+			Title:          "Platform Engineer",
+			URL:            "https://jobs.lever.co/globex/5ac21346-8e0c-4494-8e7a-3eb92ff77902",
+			PostedAt:       time.UnixMilli(1727774400000),
+			Company:        "globex",
+			Location:       "Remote Toronto",
+			Department:     "Engineering, Platform",
+			EmploymentType: "Full-time",
+		},
+		{
+			ID:             "globex:0d6b7c2e-2f7b-4a4a-9d55-0c8f4b0e2f11",
+			Title:          "Product Design Intern",
+			URL:            "https://jobs.lever.co/globex/0d6b7c2e-2f7b-4a4a-9d55-0c8f4b0e2f11",
+			PostedAt:       time.UnixMilli(1727860800000),
+			Company:        "globex",
+			Location:       "London",
+			Department:     "Design",
+			EmploymentType: "Internship",
+		},
+	}
+	checkPostings(t, publisher.postings, want, board)
+
+	description := publisher.postings[0].Description
+	for _, fragment := range []string{"platform engineer", "Requirements", "Kubernetes", "Terraform", "learning budget"} {
+		if !strings.Contains(description, fragment) {
+			t.Errorf("Description = %q, want it to contain %q", description, fragment)
+		}
+	}
+}
+
+func TestFetchRepublishesOnlyChangedPostings(t *testing.T) {
+	server := newBoardServer(t)
+	source := server.source(
+		Board{Provider: ProviderGreenhouse, Token: "acme"},
+		Board{Provider: ProviderLever, Token: "globex"},
+	)
+
+	first := newRecordingPublisher()
+	if err := source.Fetch(context.Background(), first); err != nil {
+		t.Fatalf("first Fetch() error = %v", err)
+	}
+	if len(first.postings) != 4 {
+		t.Fatalf("first Fetch() published %d postings, want 4", len(first.postings))
+	}
+
+	second := newRecordingPublisher()
+	if err := source.Fetch(context.Background(), second); err != nil {
+		t.Fatalf("second Fetch() error = %v", err)
+	}
+	if len(second.postings) != 0 {
+		t.Fatalf("second Fetch() published %d unchanged postings, want 0", len(second.postings))
+	}
+
+	server.replace("/postings/globex", `"text": "Platform Engineer"`, `"text": "Senior Platform Engineer"`)
+
+	third := newRecordingPublisher()
+	if err := source.Fetch(context.Background(), third); err != nil {
+		t.Fatalf("third Fetch() error = %v", err)
+	}
+	if len(third.postings) != 1 || third.postings[0].Title != "Senior Platform Engineer" {
+		t.Fatalf("third Fetch() published %v, want only the retitled posting", titles(third.postings))
+	}
+}
+
+func TestFetchReportsMissingBoard(t *testing.T) {
+	server := newBoardServer(t)
+	source := server.source(
+		Board{Provider: ProviderGreenhouse, Token: "missing"},
+		Board{Provider: ProviderLever, Token: "globex"},
+	)
+
+	publisher := newRecordingPublisher()
+	if err := source.Fetch(context.Background(), publisher); err == nil {
+		t.Fatal("Fetch() error = nil, want an error for the missing board")
+	}
+	if len(publisher.postings) != 2 {
+		t.Errorf("Fetch() published %d postings, want 2 from the working board", len(publisher.postings))
+	}
+}
+
+func TestParseBoards(t *testing.T) {
+	boards, err := ParseBoards([]string{"greenhouse:acme", " Lever:globex "})
+	if err != nil {
+		t.Fatalf("ParseBoards() error = %v", err)
+	}
+	want := []Board{
+		{Provider: ProviderGreenhouse, Token: "acme"},
+		{Provider: ProviderLever, Token: "globex"},
+	}
+	if len(boards) != len(want) {
+		t.Fatalf("ParseBoards() = %v, want %v", boards, want)
+	}
+	for i := range want {
+		if boards[i] != want[i] {
+			t.Errorf("ParseBoards()[%d] = %v, want %v", i, boards[i], want[i])
+		}
+	}
+
+	for _, value := range []string{"acme", "greenhouse:", "workday:acme"} {
+		if _, err := ParseBoards([]string{value}); err == nil {
+			t.Errorf("ParseBoards(%q) error = nil, want an error", value)
+		}
+	}
+}
+
+func checkPostings(t *testing.T, got []*models.JobPosting, want []models.JobPosting, board Board) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("published %d postings, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.ID != w.ID {
+			t.Errorf("posting %d: ID = %q, want %q", i, g.ID, w.ID)
+		}
+		if g.Title != w.Title {
+			t.Errorf("posting %d: Title = %q, want %q", i, g.Title, w.Title)
+		}
+		if g.URL != w.URL {
+			t.Errorf("posting %d: URL = %q, want %q", i, g.URL, w.URL)
+		}
+		if !g.PostedAt.Equal(w.PostedAt) {
+			t.Errorf("posting %d: PostedAt = %v, want %v", i, g.PostedAt, w.PostedAt)
+		}
+		if g.Company != w.Company {
+			t.Errorf("posting %d: Company = %q, want %q", i, g.Company, w.Company)
+		}
+		if g.Location != w.Location {
+			t.Errorf("posting %d: Location = %q, want %q", i, g.Location, w.Location)
+		}
+		if g.Department != w.Department {
+			t.Errorf("posting %d: Department = %q, want %q", i, g.Department, w.Department)
+		}
+		if g.EmploymentType != w.EmploymentType {
+			t.Errorf("posting %d: EmploymentType = %q, want %q", i, g.EmploymentType, w.EmploymentType)
+		}
+		if g.Source != board.Provider {
+			t.Errorf("posting %d: Source = %q, want %q", i, g.Source, board.Provider)
+		}
+		if g.Board != board.String() {
+			t.Errorf("posting %d: Board = %q, want %q", i, g.Board, board.String())
+		}
+	}
+}
+
+func titles(postings []*models.JobPosting) []string {
+	titles := make([]string, 0, len(postings))
+	for _, posting := range postings {
+		titles = append(titles, posting.Title)
+	}
+	return titles
+}
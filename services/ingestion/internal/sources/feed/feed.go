@@ -0,0 +1,342 @@
+package feed
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"shenanigigs/common/cache"
+	"shenanigigs/common/telemetry"
+	"shenanigigs/ingestion/internal/errors"
+	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	Name = "feed"
+
+	defaultSeenTTL = 30 * 24 * time.Hour
+)
+
+var tracer = telemetry.GetTracer("shenanigigs/ingestion/sources/feed")
+
+// Source polls RSS and Atom feeds and publishes every entry it has not seen
+// before. Entries are identified by their GUID (RSS) or ID (Atom).
+type Source struct {
+	client  *http.Client
+	cache   cache.Cache
+	logger  *zap.Logger
+	urls    []string
+	seenTTL time.Duration
+}
+
+func New(client *http.Client, cache cache.Cache, logger *zap.Logger, urls []string, seenTTL time.Duration) *Source {
+	if seenTTL <= 0 {
+		seenTTL = defaultSeenTTL
+	}
+	return &Source{
+		client:  client,
+		cache:   cache,
+		logger:  logger,
+		urls:    urls,
+		seenTTL: seenTTL,
+	}
+}
+
+func (s *Source) Name() string {
+	return Name
+}
+
+func (s *Source) Fetch(ctx context.Context, publisher messaging.Publisher) error {
+	ctx, span := tracer.Start(ctx, "feed.Fetch")
+	defer span.End()
+	span.SetAttributes(telemetry.Int("feed.count", len(s.urls)))
+
+	var errs []error
+	for _, url := range s.urls {
+		if err := s.fetchFeed(ctx, url, publisher); err != nil {
+			span.RecordError(err)
+			s.logger.Error("failed to fetch feed", zap.String("url", url), zap.Error(err))
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Unavailable(fmt.Sprintf("%d of %d feeds failed", len(errs), len(s.urls)), stderrors.Join(errs...))
+	}
+	return nil
+}
+
+func (s *Source) fetchFeed(ctx context.Context, url string, publisher messaging.Publisher) error {
+	ctx, span := tracer.Start(ctx, "feed.fetchFeed")
+	defer span.End()
+	span.SetAttributes(telemetry.String("http.url", url))
+
+	entries, err := s.download(ctx, url)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	published, skipped := 0, 0
+	for _, entry := range entries {
+		if entry.GUID == "" {
+			s.logger.Warn("skipping feed entry without an identifier",
+				zap.String("url", url),
+				zap.String("title", entry.Title))
+			continue
+		}
+
+		seenKey := seenKey(entry.GUID)
+		var marker string
+		err := s.cache.Get(ctx, seenKey, &marker)
+		if err == nil {
+			skipped++
+			continue
+		}
+		if err != cache.ErrNotFound {
+			s.logger.Warn("cache error, publishing entry anyway", zap.String("guid", entry.GUID), zap.Error(err))
+		}
+
+		if err := publisher.PublishJobPosting(ctx, entry.toJobPosting()); err != nil {
+			span.RecordError(err)
+			return err
+		}
+		published++
+
+		if err := s.cache.Set(ctx, seenKey, url, s.seenTTL); err != nil {
+			s.logger.Warn("failed to record feed entry as seen", zap.String("guid", entry.GUID), zap.Error(err))
+		}
+	}
+
+	span.SetAttributes(
+		telemetry.Int("feed.entries", len(entries)),
+		telemetry.Int("feed.published", published),
+		telemetry.Int("feed.skipped", skipped),
+	)
+	s.logger.Info("fetched feed",
+		zap.String("url", url),
+		zap.Int("entries", len(entries)),
+		zap.Int("published", published),
+		zap.Int("skipped", skipped))
+	return nil
+}
+
+func (s *Source) download(ctx context.Context, url string) ([]Entry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.InvalidInput("creating request", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Unavailable("executing request", err)
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			s.logger.Warn("failed to close response body", zap.Error(cerr))
+		}
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errors.NotFound(fmt.Sprintf("feed not found: %s", url), nil)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, errors.RateLimit(fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return nil, errors.Unavailable(fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
+	default:
+		return nil, errors.Internal(fmt.Sprintf("unexpected status code: %d", resp.StatusCode), nil)
+	}
+
+	entries, err := Parse(resp.Body)
+	if err != nil {
+		return nil, errors.Internal("parsing feed", err)
+	}
+	return entries, nil
+}
+
+func seenKey(guid string) string {
+	sum := sha256.Sum256([]byte(guid))
+	return "feed:seen:" + hex.EncodeToString(sum[:])
+}
+
+// Entry is a feed item normalized across RSS and Atom.
+type Entry struct {
+	GUID        string
+	Title       string
+	Link        string
+	Description string
+	Published   time.Time
+}
+
+func (e Entry) toJobPosting() *models.JobPosting {
+	posting := &models.JobPosting{
+		ID:       e.GUID,
+		Title:    e.Title,
+		PostedAt: e.Published,
+		URL:      e.Link,
+		Source:   Name,
+	}
+	posting.SetHTML(e.Description)
+	return posting
+}
+
+// document covers RSS 2.0 (items under channel), RSS 1.0/RDF (items at the
+// top level) and Atom (entries at the top level).
+type document struct {
+	XMLName xml.Name
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+}
+
+type atomEntry struct {
+	ID        string   `xml:"id"`
+	Title     string   `xml:"title"`
+	Summary   atomText `xml:"summary"`
+	Content   atomText `xml:"content"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Links     []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+}
+
+// atomText holds an Atom text construct. XHTML content is kept as markup,
+// while text and HTML content are taken after entity decoding.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return t.Inner
+	}
+	return t.Text
+}
+
+// Parse reads an RSS or Atom document and returns its entries.
+func Parse(r io.Reader) ([]Entry, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+
+	var doc document
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding feed: %w", err)
+	}
+
+	var entries []Entry
+	for _, item := range append(doc.Channel.Items, doc.Items...) {
+		entries = append(entries, item.toEntry())
+	}
+	for _, atom := range doc.Entries {
+		entries = append(entries, atom.toEntry())
+	}
+	return entries, nil
+}
+
+func (i rssItem) toEntry() Entry {
+	description := i.Content
+	if strings.TrimSpace(description) == "" {
+		description = i.Description
+	}
+
+	return Entry{
+		GUID:        firstNonEmpty(i.GUID, i.About, i.Link),
+		Title:       strings.TrimSpace(i.Title),
+		Link:        strings.TrimSpace(i.Link),
+		Description: strings.TrimSpace(description),
+		Published:   parseDate(firstNonEmpty(i.PubDate, i.Date)),
+	}
+}
+
+func (a atomEntry) toEntry() Entry {
+	link := ""
+	for _, l := range a.Links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			link = l.Href
+			break
+		}
+	}
+	if link == "" && len(a.Links) > 0 {
+		link = a.Links[0].Href
+	}
+
+	description := a.Content.String()
+	if strings.TrimSpace(description) == "" {
+		description = a.Summary.String()
+	}
+
+	return Entry{
+		GUID:        firstNonEmpty(a.ID, link),
+		Title:       strings.TrimSpace(a.Title),
+		Link:        strings.TrimSpace(link),
+		Description: strings.TrimSpace(description),
+		Published:   parseDate(firstNonEmpty(a.Published, a.Updated)),
+	}
+}
+
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	time.RFC822Z,
+	time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseDate understands the date formats seen in the wild for pubDate,
+// dc:date and the Atom timestamps. Entries without a usable date are
+// treated as posted now.
+func parseDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
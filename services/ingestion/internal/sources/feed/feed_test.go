@@ -0,0 +1,231 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shenanigigs/common/cache"
+	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// memoryCache is a cache.Cache backed by a map, standing in for Redis.
+type memoryCache struct {
+	mutex  sync.Mutex
+	values map[string][]byte
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: make(map[string][]byte)}
+}
+
+func (c *memoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.values[key] = data
+	return nil
+}
+
+func (c *memoryCache) Get(ctx context.Context, key string, value interface{}) error {
+	c.mutex.Lock()
+	data, ok := c.values[key]
+	c.mutex.Unlock()
+	if !ok {
+		return cache.ErrNotFound
+	}
+	return json.Unmarshal(data, value)
+}
+
+func (c *memoryCache) Delete(ctx context.Context, key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+func (c *memoryCache) Clear(ctx context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.values = make(map[string][]byte)
+	return nil
+}
+
+func (c *memoryCache) Close() error {
+	return nil
+}
+
+// recordingPublisher keeps the job postings it is given. Sources only
+// publish job postings, so the other methods are left unimplemented.
+type recordingPublisher struct {
+	messaging.Publisher
+	postings []*models.JobPosting
+}
+
+func (p *recordingPublisher) PublishJobPosting(ctx context.Context, posting *models.JobPosting) error {
+	p.postings = append(p.postings, posting)
+	return nil
+}
+
+func newFeedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	for _, name := range []string{"rss.xml", "rdf.xml", "atom.xml"} {
+		path := "testdata/" + name
+		mux.HandleFunc("/"+name, func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, path)
+		})
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchMapsFields(t *testing.T) {
+	server := newFeedServer(t)
+	logger := zap.NewNop()
+
+	tests := []struct {
+		feed string
+		want []models.JobPosting
+	}{
+		{
+			feed: "rss.xml",
+			want: []models.JobPosting{
+				{
+					ID:          "job-1001",
+					Title:       "Senior Go Engineer at Acme",
+					URL:         "https://jobs.example.com/1001",
+					Description: "Acme is hiring a Senior Go Engineer.\n\nRemote, EU time zones.",
+					PostedAt:    time.Date(2024, 10, 1, 9, 30, 0, 0, time.UTC),
+				},
+				{
+					ID:          "https://jobs.example.com/1002",
+					Title:       "Data Engineer at Globex",
+					URL:         "https://jobs.example.com/1002",
+					Description: "Globex needs a data engineer.",
+					PostedAt:    time.Date(2024, 10, 2, 14, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			feed: "rdf.xml",
+			want: []models.JobPosting{
+				{
+					ID:          "https://board.example.org/jobs/77",
+					Title:       "Café Backend Developer",
+					URL:         "https://board.example.org/jobs/77",
+					Description: "Backend work in München.",
+					PostedAt:    time.Date(2024, 9, 15, 8, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			feed: "atom.xml",
+			want: []models.JobPosting{
+				{
+					ID:          "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a",
+					Title:       "Platform Engineer",
+					URL:         "https://careers.example.net/jobs/42",
+					Description: "Run our Kubernetes platform.",
+					PostedAt:    time.Date(2024, 10, 3, 10, 15, 0, 0, time.UTC),
+				},
+				{
+					ID:          "urn:uuid:5c6ee9c4-1f0b-4c43-bbbb-9e0d14a1d2c7",
+					Title:       "Frontend Engineer",
+					URL:         "https://careers.example.net/jobs/43",
+					Description: "React and TypeScript.",
+					PostedAt:    time.Date(2024, 10, 2, 14, 45, 0, 0, time.UTC),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.feed, func(t *testing.T) {
+			source := New(server.Client(), newMemoryCache(), logger, []string{server.URL + "/" + tt.feed}, 0)
+			publisher := &recordingPublisher{}
+
+			if err := source.Fetch(context.Background(), publisher); err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if len(publisher.postings) != len(tt.want) {
+				t.Fatalf("Fetch() published %d postings, want %d", len(publisher.postings), len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				got := publisher.postings[i]
+				if got.ID != want.ID {
+					t.Errorf("posting %d: ID = %q, want %q", i, got.ID, want.ID)
+				}
+				if got.Title != want.Title {
+					t.Errorf("posting %d: Title = %q, want %q", i, got.Title, want.Title)
+				}
+				if got.URL != want.URL {
+					t.Errorf("posting %d: URL = %q, want %q", i, got.URL, want.URL)
+				}
+				if strings.TrimSpace(got.Description) != want.Description {
+					t.Errorf("posting %d: Description = %q, want %q", i, got.Description, want.Description)
+				}
+				if !got.PostedAt.Equal(want.PostedAt) {
+					t.Errorf("posting %d: PostedAt = %v, want %v", i, got.PostedAt, want.PostedAt)
+				}
+				if got.Source != Name {
+					t.Errorf("posting %d: Source = %q, want %q", i, got.Source, Name)
+				}
+				if got.HTML == "" {
+					t.Errorf("posting %d: HTML is empty", i)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchSkipsSeenEntries(t *testing.T) {
+	server := newFeedServer(t)
+	logger := zap.NewNop()
+	urls := []string{server.URL + "/rss.xml", server.URL + "/rdf.xml", server.URL + "/atom.xml"}
+	source := New(server.Client(), newMemoryCache(), logger, urls, time.Hour)
+
+	first := &recordingPublisher{}
+	if err := source.Fetch(context.Background(), first); err != nil {
+		t.Fatalf("first Fetch() error = %v", err)
+	}
+	if len(first.postings) != 5 {
+		t.Fatalf("first Fetch() published %d postings, want 5", len(first.postings))
+	}
+
+	second := &recordingPublisher{}
+	if err := source.Fetch(context.Background(), second); err != nil {
+		t.Fatalf("second Fetch() error = %v", err)
+	}
+	if len(second.postings) != 0 {
+		t.Errorf("second Fetch() published %d postings, want 0", len(second.postings))
+	}
+}
+
+func TestFetchReportsFailedFeeds(t *testing.T) {
+	server := newFeedServer(t)
+	logger := zap.NewNop()
+	urls := []string{server.URL + "/missing.xml", server.URL + "/rss.xml"}
+	source := New(server.Client(), newMemoryCache(), logger, urls, 0)
+
+	publisher := &recordingPublisher{}
+	if err := source.Fetch(context.Background(), publisher); err == nil {
+		t.Fatal("Fetch() error = nil, want an error for the missing feed")
+	}
+	if len(publisher.postings) != 2 {
+		t.Errorf("Fetch() published %d postings, want 2 from the working feed", len(publisher.postings))
+	}
+}
@@ -0,0 +1,33 @@
+package sources
+
+import (
+	"net/http"
+
+	"shenanigigs/common/cache"
+	"shenanigigs/ingestion/internal/config"
+	"shenanigigs/ingestion/internal/sources/ats"
+	"shenanigigs/ingestion/internal/sources/feed"
+
+	"go.uber.org/zap"
+)
+
+// NewDefaultRegistry registers the feed and ATS sources configured by cfg.
+// The sources remember the postings they have seen in state.
+func NewDefaultRegistry(cfg *config.Config, state cache.Cache, logger *zap.Logger) *Registry {
+	registry := NewRegistry()
+	registry.Register(feed.Name, func() (JobSource, error) {
+		return feed.New(&http.Client{Timeout: cfg.FeedTimeout}, state, logger, cfg.FeedURLs, cfg.FeedSeenTTL), nil
+	})
+	registry.Register(ats.Name, func() (JobSource, error) {
+		boards, err := ats.ParseBoards(cfg.ATSBoards)
+		if err != nil {
+			return nil, err
+		}
+		return ats.New(&http.Client{Timeout: cfg.ATSTimeout}, state, logger, boards, ats.Options{
+			GreenhouseBaseURL: cfg.GreenhouseAPIBaseURL,
+			LeverBaseURL:      cfg.LeverAPIBaseURL,
+			SeenTTL:           cfg.ATSSeenTTL,
+		}), nil
+	})
+	return registry
+}
@@ -0,0 +1,111 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"shenanigigs/ingestion/internal/messaging"
+	"shenanigigs/ingestion/internal/models"
+)
+
+// JobSource fetches postings from one origin, normalizes them into
+// models.JobPosting values and hands them to the publisher.
+type JobSource interface {
+	Name() string
+	Fetch(ctx context.Context, publisher messaging.Publisher) error
+}
+
+type Factory func() (JobSource, error)
+
+// Registry maps source names to factories so that the sources to run can be
+// chosen by name in config.
+type Registry struct {
+	mutex     sync.RWMutex
+	factories map[string]Factory
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]Factory),
+	}
+}
+
+// Register adds a factory under name, replacing any previous registration.
+func (r *Registry) Register(name string, factory Factory) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.factories[name] = factory
+}
+
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build creates the named sources, failing on names that were never
+// registered.
+func (r *Registry) Build(names []string) ([]JobSource, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	built := make([]JobSource, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		factory, ok := r.factories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown job source %q", name)
+		}
+		source, err := factory()
+		if err != nil {
+			return nil, fmt.Errorf("creating job source %q: %w", name, err)
+		}
+		built = append(built, source)
+	}
+	return built, nil
+}
+
+// WithSource wraps publisher so that every posting carries the source name,
+// even when the source did not set it itself.
+func WithSource(publisher messaging.Publisher, source string) messaging.Publisher {
+	return &sourcePublisher{Publisher: publisher, source: source}
+}
+
+type sourcePublisher struct {
+	messaging.Publisher
+	source string
+}
+
+func (p *sourcePublisher) PublishJobPosting(ctx context.Context, posting *models.JobPosting) error {
+	if posting.Source == "" {
+		posting.Source = p.source
+	}
+	return p.Publisher.PublishJobPosting(ctx, posting)
+}
+
+func (p *sourcePublisher) PublishJobStatus(ctx context.Context, change *models.JobStatusChange) error {
+	if change.Source == "" {
+		change.Source = p.source
+	}
+	return p.Publisher.PublishJobStatus(ctx, change)
+}
+
+func (p *sourcePublisher) PublishTombstone(ctx context.Context, tombstone *models.Tombstone) error {
+	if tombstone.Source == "" {
+		tombstone.Source = p.source
+	}
+	return p.Publisher.PublishTombstone(ctx, tombstone)
+}
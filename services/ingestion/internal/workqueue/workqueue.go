@@ -0,0 +1,346 @@
+package workqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shenanigigs/common/telemetry"
+	"shenanigigs/ingestion/internal/config"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+
+	taskField = "task"
+)
+
+var errAbandoned = errors.New("abandoned by consumer")
+
+var tracer = telemetry.GetTracer("shenanigigs/ingestion/workqueue")
+
+// Task is a comment waiting to be fetched and published.
+type Task struct {
+	CommentID   int    `json:"comment_id"`
+	ThreadID    int    `json:"thread_id"`
+	ThreadType  string `json:"thread_type"`
+	ThreadMonth string `json:"thread_month"`
+	// Fresh fetches the comment from the API rather than the cache.
+	Fresh bool `json:"fresh,omitempty"`
+	// Attempt counts earlier deliveries that failed.
+	Attempt int `json:"attempt"`
+}
+
+// Message is a delivered task. It stays pending until it is acknowledged
+// or retried.
+type Message struct {
+	ID   string
+	Task Task
+}
+
+type Options struct {
+	Stream string
+	Group  string
+	// ClaimIdle is how long a delivered message may stay unacknowledged
+	// before another consumer takes it over, e.g. after a crash.
+	ClaimIdle time.Duration
+	// MaxAttempts is how often a task is delivered before it is moved to
+	// the dead-letter stream.
+	MaxAttempts int
+	// MaxLen caps the length of the dead-letter stream, approximately. The
+	// work stream is never trimmed, since that would drop pending tasks;
+	// finished tasks are deleted from it instead.
+	MaxLen int64
+	// Block is how long a read waits for new messages.
+	Block time.Duration
+}
+
+// RedisQueue is a durable work queue on a Redis stream. Every consumer of
+// the group gets its own share of the tasks; tasks are redelivered when
+// they fail or when their consumer dies before acknowledging them. Tasks are
+// deleted from the stream once acknowledged, so it only holds work that is
+// pending or not yet delivered.
+type RedisQueue struct {
+	client  *redis.Client
+	options Options
+	logger  *zap.Logger
+}
+
+// New builds the comment queue configured by cfg and sets it up. It returns
+// nil for the in-memory backend, where comments never leave the process, and
+// when cfg does not share state between replicas.
+func New(ctx context.Context, cfg *config.Config, client *redis.Client, logger *zap.Logger) (*RedisQueue, error) {
+	switch cfg.WorkQueueBackend {
+	case "", BackendMemory:
+		return nil, nil
+	case BackendRedis:
+		if !cfg.SharesState() {
+			// Tasks on the shared stream would be published by replicas
+			// with other publishers.
+			logger.Warn("ignoring redis work queue, publisher does not share state",
+				zap.String("publisher_backend", cfg.PublisherBackend))
+			return nil, nil
+		}
+		queue := NewRedis(client, Options{
+			Stream:      cfg.WorkQueueStream,
+			Group:       cfg.WorkQueueGroup,
+			ClaimIdle:   cfg.WorkQueueClaimIdle,
+			MaxAttempts: cfg.WorkQueueMaxAttempts,
+			MaxLen:      cfg.WorkQueueMaxLen,
+		}, logger)
+		if err := queue.Setup(ctx); err != nil {
+			return nil, err
+		}
+		return queue, nil
+	default:
+		return nil, fmt.Errorf("unknown work queue backend %q", cfg.WorkQueueBackend)
+	}
+}
+
+func NewRedis(client *redis.Client, options Options, logger *zap.Logger) *RedisQueue {
+	if options.ClaimIdle <= 0 {
+		options.ClaimIdle = 2 * time.Minute
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 5
+	}
+	if options.Block <= 0 {
+		options.Block = 2 * time.Second
+	}
+	return &RedisQueue{
+		client:  client,
+		options: options,
+		logger:  logger,
+	}
+}
+
+// DeadLetterStream holds tasks that failed MaxAttempts times.
+func (q *RedisQueue) DeadLetterStream() string {
+	return q.options.Stream + ":dead"
+}
+
+// Setup creates the stream and consumer group if they do not exist yet.
+func (q *RedisQueue) Setup(ctx context.Context) error {
+	err := q.client.XGroupCreateMkStream(ctx, q.options.Stream, q.options.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("creating consumer group %q on %q: %w", q.options.Group, q.options.Stream, err)
+	}
+	return nil
+}
+
+func (q *RedisQueue) Enqueue(ctx context.Context, task Task) error {
+	ctx, span := tracer.Start(ctx, "RedisQueue.Enqueue")
+	defer span.End()
+	span.SetAttributes(telemetry.Int("comment_id", task.CommentID))
+
+	if err := q.client.XAdd(ctx, q.addArgs(q.options.Stream, task)).Err(); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("enqueueing comment %d: %w", task.CommentID, err)
+	}
+	return nil
+}
+
+// Read returns up to count messages for consumer, waiting up to Block for
+// new ones; it may return none. Messages abandoned by other consumers are
+// requeued first.
+func (q *RedisQueue) Read(ctx context.Context, consumer string, count int) ([]Message, error) {
+	ctx, span := tracer.Start(ctx, "RedisQueue.Read")
+	defer span.End()
+
+	if err := q.reclaim(ctx, consumer, count); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.options.Group,
+		Consumer: consumer,
+		Streams:  []string{q.options.Stream, ">"},
+		Count:    int64(count),
+		Block:    q.options.Block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("reading from %q: %w", q.options.Stream, err)
+	}
+
+	var messages []Message
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			if message, ok := q.decode(ctx, msg); ok {
+				messages = append(messages, message)
+			}
+		}
+	}
+	span.SetAttributes(telemetry.Int("messages.read", len(messages)))
+	return messages, nil
+}
+
+// reclaim requeues messages left pending longer than ClaimIdle. Each
+// takeover counts as a failed attempt, so a task that keeps killing its
+// consumer ends up in the dead-letter stream.
+func (q *RedisQueue) reclaim(ctx context.Context, consumer string, count int) error {
+	claimed, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   q.options.Stream,
+		Group:    q.options.Group,
+		MinIdle:  q.options.ClaimIdle,
+		Start:    "0-0",
+		Count:    int64(count),
+		Consumer: consumer,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("claiming from %q: %w", q.options.Stream, err)
+	}
+
+	for _, msg := range claimed {
+		message, ok := q.decode(ctx, msg)
+		if !ok {
+			continue
+		}
+		if err := q.Retry(ctx, message, errAbandoned); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode reads the task of a message. Messages that cannot be decoded are
+// acknowledged and dropped, since no retry would make them readable.
+func (q *RedisQueue) decode(ctx context.Context, msg redis.XMessage) (Message, bool) {
+	message := Message{ID: msg.ID}
+	raw, _ := msg.Values[taskField].(string)
+	if err := json.Unmarshal([]byte(raw), &message.Task); err != nil {
+		q.logger.Error("dropping undecodable work queue message",
+			zap.String("id", msg.ID),
+			zap.Error(err))
+		if err := q.Ack(ctx, msg.ID); err != nil {
+			q.logger.Warn("failed to drop work queue message", zap.String("id", msg.ID), zap.Error(err))
+		}
+		return Message{}, false
+	}
+	return message, true
+}
+
+// Ack marks messages as done and deletes them from the stream.
+func (q *RedisQueue) Ack(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, q.options.Stream, q.options.Group, ids...)
+		pipe.XDel(ctx, q.options.Stream, ids...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("acknowledging %d messages: %w", len(ids), err)
+	}
+	return nil
+}
+
+// Retry requeues a failed task, or moves it to the dead-letter stream once
+// it has used up its attempts.
+func (q *RedisQueue) Retry(ctx context.Context, msg Message, cause error) error {
+	msg.Task.Attempt++
+	if msg.Task.Attempt >= q.options.MaxAttempts {
+		return q.deadLetter(ctx, msg, cause.Error())
+	}
+	return q.requeue(ctx, msg)
+}
+
+// Release requeues tasks that were delivered but not attempted, e.g. while
+// the job source is unavailable, without counting an attempt. Leaving them
+// pending instead would have them reclaimed as abandoned.
+func (q *RedisQueue) Release(ctx context.Context, msgs ...Message) error {
+	return q.requeue(ctx, msgs...)
+}
+
+func (q *RedisQueue) requeue(ctx context.Context, msgs ...Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, msg := range msgs {
+			pipe.XAdd(ctx, q.addArgs(q.options.Stream, msg.Task))
+			pipe.XAck(ctx, q.options.Stream, q.options.Group, msg.ID)
+			pipe.XDel(ctx, q.options.Stream, msg.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("requeueing %d comments: %w", len(msgs), err)
+	}
+	return nil
+}
+
+func (q *RedisQueue) deadLetter(ctx context.Context, msg Message, reason string) error {
+	q.logger.Warn("moving task to dead-letter stream",
+		zap.Int("comment_id", msg.Task.CommentID),
+		zap.Int("thread_id", msg.Task.ThreadID),
+		zap.Int("attempts", msg.Task.Attempt),
+		zap.String("reason", reason))
+
+	args := q.addArgs(q.DeadLetterStream(), msg.Task)
+	args.Values = append(args.Values.([]any), "reason", reason)
+	if q.options.MaxLen > 0 {
+		args.MaxLen = q.options.MaxLen
+		args.Approx = true
+	}
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, args)
+		pipe.XAck(ctx, q.options.Stream, q.options.Group, msg.ID)
+		pipe.XDel(ctx, q.options.Stream, msg.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("dead-lettering comment %d: %w", msg.Task.CommentID, err)
+	}
+	return nil
+}
+
+// PublishedKey counts the comments consumers published since the last
+// TakePublished.
+func (q *RedisQueue) PublishedKey() string {
+	return q.options.Stream + ":published"
+}
+
+// CountPublished adds n comments to the published count.
+func (q *RedisQueue) CountPublished(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if err := q.client.IncrBy(ctx, q.PublishedKey(), int64(n)).Err(); err != nil {
+		return fmt.Errorf("counting %d published comments: %w", n, err)
+	}
+	return nil
+}
+
+// TakePublished returns the number of comments consumers on every replica
+// published since the last call, and resets it.
+func (q *RedisQueue) TakePublished(ctx context.Context) (int, error) {
+	n, err := q.client.GetDel(ctx, q.PublishedKey()).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading published count: %w", err)
+	}
+	return n, nil
+}
+
+func (q *RedisQueue) addArgs(stream string, task Task) *redis.XAddArgs {
+	data, _ := json.Marshal(task)
+	return &redis.XAddArgs{
+		Stream: stream,
+		Values: []any{taskField, string(data)},
+	}
+}
@@ -0,0 +1,152 @@
+package workqueue
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func newTestQueue(t *testing.T, options Options) (*RedisQueue, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	options.Stream = "comments"
+	options.Group = "workers"
+	queue := NewRedis(client, options, zap.NewNop())
+	if err := queue.Setup(context.Background()); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	return queue, client
+}
+
+func TestPendingTasksAreNotTrimmed(t *testing.T) {
+	ctx := context.Background()
+	queue, client := newTestQueue(t, Options{MaxLen: 2})
+
+	for id := 1; id <= 5; id++ {
+		if err := queue.Enqueue(ctx, Task{CommentID: id, ThreadID: 100}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	messages, err := queue.Read(ctx, "a", 3)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("Read() returned %d messages, want 3", len(messages))
+	}
+	if err := queue.Enqueue(ctx, Task{CommentID: 6, ThreadID: 100}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	// Acknowledged tasks leave the stream; pending and new ones stay.
+	if err := queue.Ack(ctx, messages[0].ID, messages[1].ID); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if n := client.XLen(ctx, "comments").Val(); n != 4 {
+		t.Errorf("stream length = %d, want 4", n)
+	}
+
+	rest, err := queue.Read(ctx, "b", 10)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	var ids []int
+	for _, msg := range rest {
+		ids = append(ids, msg.Task.CommentID)
+	}
+	if len(ids) != 3 || ids[0] != 4 || ids[1] != 5 || ids[2] != 6 {
+		t.Errorf("second Read() = comments %v, want [4 5 6]", ids)
+	}
+}
+
+func TestRetryDeletesTheOriginal(t *testing.T) {
+	ctx := context.Background()
+	queue, client := newTestQueue(t, Options{MaxAttempts: 2, MaxLen: 100})
+
+	if err := queue.Enqueue(ctx, Task{CommentID: 1, ThreadID: 100}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	cause := stderrors.New("fetch failed")
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		messages, err := queue.Read(ctx, "a", 1)
+		if err != nil || len(messages) != 1 {
+			t.Fatalf("Read() = %d messages, %v, want 1", len(messages), err)
+		}
+		if messages[0].Task.Attempt != attempt-1 {
+			t.Errorf("Attempt = %d, want %d", messages[0].Task.Attempt, attempt-1)
+		}
+		if err := queue.Retry(ctx, messages[0], cause); err != nil {
+			t.Fatalf("Retry() error = %v", err)
+		}
+	}
+
+	if n := client.XLen(ctx, "comments").Val(); n != 0 {
+		t.Errorf("stream length = %d, want 0", n)
+	}
+	dead := client.XRange(ctx, queue.DeadLetterStream(), "-", "+").Val()
+	if len(dead) != 1 || dead[0].Values["reason"] != cause.Error() {
+		t.Errorf("dead-letter stream = %v, want the task with its reason", dead)
+	}
+}
+
+func TestReleaseKeepsAttempts(t *testing.T) {
+	ctx := context.Background()
+	queue, client := newTestQueue(t, Options{MaxAttempts: 2})
+
+	if err := queue.Enqueue(ctx, Task{CommentID: 1, ThreadID: 100, Attempt: 1}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	// Released again and again, e.g. during a long outage, the task is
+	// never dead-lettered.
+	for i := 0; i < 3; i++ {
+		messages, err := queue.Read(ctx, "a", 1)
+		if err != nil || len(messages) != 1 {
+			t.Fatalf("Read() = %d messages, %v, want 1", len(messages), err)
+		}
+		if messages[0].Task.Attempt != 1 {
+			t.Errorf("Attempt = %d after %d releases, want 1", messages[0].Task.Attempt, i)
+		}
+		if err := queue.Release(ctx, messages...); err != nil {
+			t.Fatalf("Release() error = %v", err)
+		}
+	}
+
+	if n := client.XLen(ctx, "comments").Val(); n != 1 {
+		t.Errorf("stream length = %d, want 1", n)
+	}
+	if pending := client.XPending(ctx, "comments", "workers").Val(); pending.Count != 0 {
+		t.Errorf("%d messages pending, want none", pending.Count)
+	}
+	if n := client.XLen(ctx, queue.DeadLetterStream()).Val(); n != 0 {
+		t.Errorf("dead-letter stream length = %d, want 0", n)
+	}
+}
+
+func TestPublishedCount(t *testing.T) {
+	ctx := context.Background()
+	queue, _ := newTestQueue(t, Options{})
+
+	if n, err := queue.TakePublished(ctx); err != nil || n != 0 {
+		t.Errorf("TakePublished() = %d, %v, want 0", n, err)
+	}
+	for _, n := range []int{3, 0, 4} {
+		if err := queue.CountPublished(ctx, n); err != nil {
+			t.Fatalf("CountPublished(%d) error = %v", n, err)
+		}
+	}
+	if n, err := queue.TakePublished(ctx); err != nil || n != 7 {
+		t.Errorf("TakePublished() = %d, %v, want 7", n, err)
+	}
+	if n, err := queue.TakePublished(ctx); err != nil || n != 0 {
+		t.Errorf("TakePublished() after taking = %d, %v, want 0", n, err)
+	}
+}
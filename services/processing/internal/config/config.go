@@ -1,82 +1,55 @@
 package config
 
 import (
-	"os"
-	"strconv"
 	"time"
+
+	commonconfig "shenanigigs/common/config"
 )
 
 type Config struct {
-	NATSURL         string
-	NATSConnTimeout time.Duration
-
-	ClickHouseDSN          string
-	ClickHouseMaxOpenConns int
-	ClickHouseMaxIdleConns int
-	ClickHouseConnMaxLife  time.Duration
-	ClickHouseUsername     string
-	ClickHousePassword     string
-	ClickHouseDatabase     string
-
-	RedisAddr     string
-	RedisPassword string
-	RedisDB       int
-	CacheTTL      time.Duration
-
-	BatchSize         int
-	ProcessingTimeout time.Duration
-	MaxRetries        int
-	RetryDelay        time.Duration
+	NATSURL         string        `config:"nats_url" default:"nats://localhost:4222" validate:"required"`
+	NATSConnTimeout time.Duration `config:"nats_conn_timeout" default:"10s" validate:"min=1ms"`
+
+	JetStreamStream          string        `config:"jetstream_stream" default:"JOBS" validate:"required"`
+	JetStreamMaxAge          time.Duration `config:"jetstream_max_age" default:"168h" validate:"min=1m"`
+	JetStreamReplicas        int           `config:"jetstream_replicas" default:"1" validate:"min=1,max=5"`
+	JetStreamDuplicateWindow time.Duration `config:"jetstream_duplicate_window" default:"2m" validate:"min=1s"`
+	// ConsumerPrefix names the durable consumers, one per subject.
+	ConsumerPrefix        string `config:"consumer_prefix" default:"processing" validate:"required"`
+	ConsumerMaxAckPending int    `config:"consumer_max_ack_pending" default:"1000" validate:"min=1"`
+
+	ClickHouseDSN          string        `config:"clickhouse_dsn" default:"localhost:9000" validate:"required"`
+	ClickHouseMaxOpenConns int           `config:"clickhouse_max_open_conns" default:"10" validate:"min=1"`
+	ClickHouseMaxIdleConns int           `config:"clickhouse_max_idle_conns" default:"5" validate:"min=0"`
+	ClickHouseConnMaxLife  time.Duration `config:"clickhouse_conn_max_life" default:"1h"`
+	ClickHouseUsername     string        `config:"clickhouse_username" default:"default"`
+	ClickHousePassword     string        `config:"clickhouse_password" secret:"true"`
+	ClickHouseDatabase     string        `config:"clickhouse_database" default:"shenanigigs" validate:"required"`
+
+	RedisAddr     string        `config:"redis_addr" default:"localhost:6379" validate:"required"`
+	RedisPassword string        `config:"redis_password" secret:"true"`
+	RedisDB       int           `config:"redis_db" default:"0" validate:"min=0"`
+	CacheTTL      time.Duration `config:"cache_ttl" default:"24h" validate:"min=1s"`
+
+	// BatchSize is the number of messages each consumer pulls at a time.
+	BatchSize int `config:"batch_size" default:"100" validate:"min=1"`
+	// ProcessingTimeout bounds the handling of one message. A message left
+	// unacknowledged for half as long again is redelivered.
+	ProcessingTimeout time.Duration `config:"processing_timeout" default:"5m" validate:"min=1s"`
+	// A message that fails MaxRetries+1 times is terminated, as is one that
+	// cannot be parsed, on its first failure. Retries wait RetryDelay,
+	// doubling each time up to RetryMaxDelay.
+	MaxRetries    int           `config:"max_retries" default:"3" validate:"min=0"`
+	RetryDelay    time.Duration `config:"retry_delay" default:"30s" validate:"min=0s"`
+	RetryMaxDelay time.Duration `config:"retry_max_delay" default:"10m" validate:"min=0s"`
 }
 
+// LoadConfig reads the config from the file named by CONFIG_FILE, if any,
+// and the environment.
 func LoadConfig() (*Config, error) {
-	config := &Config{
-		NATSURL:         getEnvString("NATS_URL", "nats://localhost:4222"),
-		NATSConnTimeout: getEnvDuration("NATS_CONN_TIMEOUT", 10*time.Second),
-
-		ClickHouseDSN:          getEnvString("CLICKHOUSE_DSN", "localhost:9000"),
-		ClickHouseMaxOpenConns: getEnvInt("CLICKHOUSE_MAX_OPEN_CONNS", 10),
-		ClickHouseMaxIdleConns: getEnvInt("CLICKHOUSE_MAX_IDLE_CONNS", 5),
-		ClickHouseConnMaxLife:  getEnvDuration("CLICKHOUSE_CONN_MAX_LIFE", time.Hour),
-		ClickHouseUsername:     getEnvString("CLICKHOUSE_USERNAME", "default"),
-		ClickHousePassword:     getEnvString("CLICKHOUSE_PASSWORD", ""),
-		ClickHouseDatabase:     getEnvString("CLICKHOUSE_DATABASE", "shenanigigs"),
-
-		RedisAddr:     getEnvString("REDIS_ADDR", "localhost:6379"),
-		RedisPassword: getEnvString("REDIS_PASSWORD", ""),
-		RedisDB:       getEnvInt("REDIS_DB", 0),
-		CacheTTL:      getEnvDuration("CACHE_TTL", 24*time.Hour),
-
-		BatchSize:         getEnvInt("BATCH_SIZE", 100),
-		ProcessingTimeout: getEnvDuration("PROCESSING_TIMEOUT", 5*time.Minute),
-		MaxRetries:        getEnvInt("MAX_RETRIES", 3),
-		RetryDelay:        getEnvDuration("RETRY_DELAY", 30*time.Second),
+	config := &Config{}
+	if err := commonconfig.Load(config, commonconfig.Options{}); err != nil {
+		return nil, err
 	}
-
 	return config, nil
 }
-
-func getEnvString(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-	return defaultValue
-}
-
-func getEnvInt(key string, defaultValue int) int {
-	if value, exists := os.LookupEnv(key); exists {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
-	}
-	return defaultValue
-}
-
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value, exists := os.LookupEnv(key); exists {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
-		}
-	}
-	return defaultValue
-}
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 
 	goerrors "github.com/go-errors/errors"
@@ -82,3 +83,14 @@ func Unavailable(message string, err error) *DomainError {
 func RateLimit(message string, err error) *DomainError {
 	return New(ErrTypeRateLimit, message, err)
 }
+
+// HasType reports whether any DomainError in err's chain has the given type.
+func HasType(err error, errType ErrorType) bool {
+	for err != nil {
+		if domainErr, ok := err.(*DomainError); ok && domainErr.Type == errType {
+			return true
+		}
+		err = stderrors.Unwrap(err)
+	}
+	return false
+}
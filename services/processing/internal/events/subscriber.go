@@ -3,62 +3,305 @@ package events
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	"shenanigigs/common/stream"
+	"shenanigigs/processing/internal/config"
+	"shenanigigs/processing/internal/errors"
 	"shenanigigs/processing/internal/processor"
 )
 
+const (
+	JobPostingsSubject = stream.JobPostingsSubject
+	CandidatesSubject  = stream.CandidatesSubject
+	FreelanceSubject   = stream.FreelanceSubject
+	JobStatusSubject   = stream.JobStatusSubject
+	JobRemovedSubject  = stream.JobRemovedSubject
+)
+
+// Processor stores the messages of each subject. It is implemented by
+// *processor.JobProcessor.
+type Processor interface {
+	ProcessJobPosting(ctx context.Context, rawData []byte) error
+	ProcessCandidate(ctx context.Context, rawData []byte) error
+	ProcessFreelancePost(ctx context.Context, rawData []byte) error
+	ProcessJobStatus(ctx context.Context, rawData []byte) error
+	ProcessTombstone(ctx context.Context, rawData []byte) error
+}
+
+// Handler consumes the job pipeline stream through one durable pull
+// consumer per subject. Messages are acked once processed; failed ones are
+// redelivered with a growing delay until MaxRetries is exhausted, and ones
+// that cannot be decoded are terminated right away.
 type Handler struct {
 	logger       *zap.Logger
 	nc           *nats.Conn
 	tracer       trace.Tracer
-	jobProcessor *processor.JobProcessor
-	sub          *nats.Subscription
+	jobProcessor Processor
+	config       *config.Config
+	consumers    []jetstream.ConsumeContext
 }
 
-func NewHandler(logger *zap.Logger, nc *nats.Conn, tracer trace.Tracer, jobProcessor *processor.JobProcessor) *Handler {
+func NewHandler(logger *zap.Logger, nc *nats.Conn, tracer trace.Tracer, jobProcessor *processor.JobProcessor, config *config.Config) *Handler {
 	return &Handler{
 		logger:       logger,
 		nc:           nc,
 		tracer:       tracer,
 		jobProcessor: jobProcessor,
+		config:       config,
 	}
 }
 
+type messageHandler func(ctx context.Context, msg jetstream.Msg) error
+
 func (h *Handler) RegisterSubscriptions(lc fx.Lifecycle) error {
-	sub, err := h.nc.QueueSubscribe("jobs.new", "processing-service", h.handleJobPosting)
+	handlers := map[string]messageHandler{
+		JobPostingsSubject: h.handleJobPosting,
+		CandidatesSubject:  h.handleCandidate,
+		FreelanceSubject:   h.handleFreelancePost,
+		JobStatusSubject:   h.handleJobStatus,
+		JobRemovedSubject:  h.handleTombstone,
+	}
+
+	js, err := jetstream.New(h.nc)
+	if err != nil {
+		return fmt.Errorf("create JetStream context: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.NATSConnTimeout)
+	defer cancel()
+
+	s, err := stream.Ensure(ctx, js, stream.Options{
+		Name:            h.config.JetStreamStream,
+		MaxAge:          h.config.JetStreamMaxAge,
+		Replicas:        h.config.JetStreamReplicas,
+		DuplicateWindow: h.config.JetStreamDuplicateWindow,
+	})
 	if err != nil {
-		return fmt.Errorf("subscribe to jobs.new: %w", err)
+		return err
 	}
 
-	h.sub = sub
-	h.logger.Info("Registered NATS subscriptions")
+	for subject, handler := range handlers {
+		consumer, err := s.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+			Durable:       h.consumerName(subject),
+			FilterSubject: subject,
+			DeliverPolicy: jetstream.DeliverAllPolicy,
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			AckWait:       h.ackWait(),
+			MaxDeliver:    h.config.MaxRetries + 1,
+			MaxAckPending: h.config.ConsumerMaxAckPending,
+		})
+		if err != nil {
+			h.stop()
+			return fmt.Errorf("provision consumer for %s: %w", subject, err)
+		}
+
+		consumeCtx, err := consumer.Consume(h.consume(handler),
+			jetstream.PullMaxMessages(h.config.BatchSize),
+			jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+				h.logger.Warn("Consumer error", zap.String("subject", subject), zap.Error(err))
+			}))
+		if err != nil {
+			h.stop()
+			return fmt.Errorf("consume %s: %w", subject, err)
+		}
+		h.consumers = append(h.consumers, consumeCtx)
+	}
+
+	h.logger.Info("Registered JetStream consumers", zap.String("stream", s.CachedInfo().Config.Name))
 
 	lc.Append(fx.Hook{
 		OnStop: func(ctx context.Context) error {
-			return h.sub.Unsubscribe()
+			h.stop()
+			return nil
 		},
 	})
 
 	return nil
 }
 
-func (h *Handler) handleJobPosting(msg *nats.Msg) {
-	ctx, span := h.tracer.Start(context.Background(), "handleJobPosting")
+// consumerName derives the durable name for subject, e.g.
+// processing-jobs-new.
+func (h *Handler) consumerName(subject string) string {
+	return h.config.ConsumerPrefix + "-" + strings.ReplaceAll(subject, ".", "-")
+}
+
+func (h *Handler) stop() {
+	for _, consumeCtx := range h.consumers {
+		consumeCtx.Stop()
+	}
+	h.consumers = nil
+}
+
+// ackWait leaves the handler time to settle a message after its processing
+// timed out, before the server redelivers it.
+func (h *Handler) ackWait() time.Duration {
+	return h.config.ProcessingTimeout + h.config.ProcessingTimeout/2
+}
+
+// consume handles the messages of a pulled batch one after the other. The
+// ack wait of each is restarted when its handling starts, so that messages
+// waiting behind slow ones in the batch are not redelivered meanwhile.
+func (h *Handler) consume(handler messageHandler) jetstream.MessageHandler {
+	return func(msg jetstream.Msg) {
+		if err := msg.InProgress(); err != nil {
+			h.logger.Warn("Failed to extend ack wait", zap.String("subject", msg.Subject()), zap.Error(err))
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), h.config.ProcessingTimeout)
+		defer cancel()
+		h.settle(msg, handler(ctx, msg))
+	}
+}
+
+// settle acks a processed message and naks a failed one with a delay. A
+// message on its last delivery, or one that is invalid and would fail the
+// same way on every delivery, is terminated instead; it stays in the stream
+// for inspection until it ages out.
+func (h *Handler) settle(msg jetstream.Msg, err error) {
+	if err == nil {
+		if ackErr := msg.Ack(); ackErr != nil {
+			h.logger.Warn("Failed to ack message", zap.String("subject", msg.Subject()), zap.Error(ackErr))
+		}
+		return
+	}
+
+	delivered := uint64(1)
+	var sequence uint64
+	if meta, metaErr := msg.Metadata(); metaErr == nil {
+		delivered = meta.NumDelivered
+		sequence = meta.Sequence.Stream
+	}
+
+	permanent := errors.HasType(err, errors.ErrTypeInvalidInput)
+	if permanent || delivered > uint64(h.config.MaxRetries) {
+		h.logger.Error("Giving up on message",
+			zap.String("subject", msg.Subject()),
+			zap.Uint64("stream_sequence", sequence),
+			zap.Uint64("deliveries", delivered),
+			zap.Bool("invalid", permanent),
+			zap.Error(err))
+		if termErr := msg.Term(); termErr != nil {
+			h.logger.Warn("Failed to terminate message", zap.String("subject", msg.Subject()), zap.Error(termErr))
+		}
+		return
+	}
+
+	delay := h.retryDelay(delivered)
+	if nakErr := msg.NakWithDelay(delay); nakErr != nil {
+		h.logger.Warn("Failed to nak message", zap.String("subject", msg.Subject()), zap.Error(nakErr))
+		return
+	}
+	h.logger.Info("Retrying message",
+		zap.String("subject", msg.Subject()),
+		zap.Uint64("stream_sequence", sequence),
+		zap.Uint64("deliveries", delivered),
+		zap.Duration("delay", delay))
+}
+
+// retryDelay doubles RetryDelay for every delivery so far, up to
+// RetryMaxDelay.
+func (h *Handler) retryDelay(delivered uint64) time.Duration {
+	delay := h.config.RetryDelay
+	for i := uint64(1); i < delivered && delay < h.config.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if h.config.RetryMaxDelay > 0 && delay > h.config.RetryMaxDelay {
+		delay = h.config.RetryMaxDelay
+	}
+	return delay
+}
+
+func (h *Handler) handleJobPosting(ctx context.Context, msg jetstream.Msg) error {
+	ctx, span := h.tracer.Start(ctx, "handleJobPosting")
 	defer span.End()
 
-	if err := h.jobProcessor.ProcessJobPosting(ctx, msg.Data); err != nil {
+	if err := h.jobProcessor.ProcessJobPosting(ctx, msg.Data()); err != nil {
 		h.logger.Error("Failed to process job posting",
 			zap.Error(err),
-			zap.String("subject", msg.Subject),
+			zap.String("subject", msg.Subject()),
 		)
-		return
+		return err
 	}
 
 	h.logger.Info("Successfully processed job posting",
-		zap.String("subject", msg.Subject),
+		zap.String("subject", msg.Subject()),
 	)
+	return nil
+}
+
+func (h *Handler) handleCandidate(ctx context.Context, msg jetstream.Msg) error {
+	ctx, span := h.tracer.Start(ctx, "handleCandidate")
+	defer span.End()
+
+	if err := h.jobProcessor.ProcessCandidate(ctx, msg.Data()); err != nil {
+		h.logger.Error("Failed to process candidate",
+			zap.Error(err),
+			zap.String("subject", msg.Subject()),
+		)
+		return err
+	}
+
+	h.logger.Info("Successfully processed candidate",
+		zap.String("subject", msg.Subject()),
+	)
+	return nil
+}
+
+func (h *Handler) handleFreelancePost(ctx context.Context, msg jetstream.Msg) error {
+	ctx, span := h.tracer.Start(ctx, "handleFreelancePost")
+	defer span.End()
+
+	if err := h.jobProcessor.ProcessFreelancePost(ctx, msg.Data()); err != nil {
+		h.logger.Error("Failed to process freelance post",
+			zap.Error(err),
+			zap.String("subject", msg.Subject()),
+		)
+		return err
+	}
+
+	h.logger.Info("Successfully processed freelance post",
+		zap.String("subject", msg.Subject()),
+	)
+	return nil
+}
+
+func (h *Handler) handleJobStatus(ctx context.Context, msg jetstream.Msg) error {
+	ctx, span := h.tracer.Start(ctx, "handleJobStatus")
+	defer span.End()
+
+	if err := h.jobProcessor.ProcessJobStatus(ctx, msg.Data()); err != nil {
+		h.logger.Error("Failed to process job status change",
+			zap.Error(err),
+			zap.String("subject", msg.Subject()),
+		)
+		return err
+	}
+
+	h.logger.Info("Successfully processed job status change",
+		zap.String("subject", msg.Subject()),
+	)
+	return nil
+}
+
+func (h *Handler) handleTombstone(ctx context.Context, msg jetstream.Msg) error {
+	ctx, span := h.tracer.Start(ctx, "handleTombstone")
+	defer span.End()
+
+	if err := h.jobProcessor.ProcessTombstone(ctx, msg.Data()); err != nil {
+		h.logger.Error("Failed to process tombstone",
+			zap.Error(err),
+			zap.String("subject", msg.Subject()),
+		)
+		return err
+	}
+
+	h.logger.Info("Successfully processed tombstone",
+		zap.String("subject", msg.Subject()),
+	)
+	return nil
 }
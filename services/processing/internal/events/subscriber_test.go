@@ -0,0 +1,358 @@
+package events
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"shenanigigs/common/stream"
+	"shenanigigs/processing/internal/config"
+	"shenanigigs/processing/internal/errors"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/fx/fxtest"
+	"go.uber.org/zap"
+)
+
+// delivery is one call of the processor.
+type delivery struct {
+	subject string
+	data    string
+	at      time.Time
+}
+
+// fakeProcessor records every message it is given and fails them as told by
+// fail, which gets the data and the number of earlier calls for it.
+type fakeProcessor struct {
+	mutex      sync.Mutex
+	fail       func(data string, attempt int) error
+	attempts   map[string]int
+	deliveries chan delivery
+}
+
+func newFakeProcessor(fail func(data string, attempt int) error) *fakeProcessor {
+	return &fakeProcessor{
+		fail:       fail,
+		attempts:   make(map[string]int),
+		deliveries: make(chan delivery, 100),
+	}
+}
+
+func (p *fakeProcessor) process(subject string, rawData []byte) error {
+	p.mutex.Lock()
+	attempt := p.attempts[string(rawData)]
+	p.attempts[string(rawData)]++
+	p.mutex.Unlock()
+
+	p.deliveries <- delivery{subject: subject, data: string(rawData), at: time.Now()}
+	if p.fail == nil {
+		return nil
+	}
+	return p.fail(string(rawData), attempt)
+}
+
+func (p *fakeProcessor) ProcessJobPosting(ctx context.Context, rawData []byte) error {
+	return p.process(JobPostingsSubject, rawData)
+}
+
+func (p *fakeProcessor) ProcessCandidate(ctx context.Context, rawData []byte) error {
+	return p.process(CandidatesSubject, rawData)
+}
+
+func (p *fakeProcessor) ProcessFreelancePost(ctx context.Context, rawData []byte) error {
+	return p.process(FreelanceSubject, rawData)
+}
+
+func (p *fakeProcessor) ProcessJobStatus(ctx context.Context, rawData []byte) error {
+	return p.process(JobStatusSubject, rawData)
+}
+
+func (p *fakeProcessor) ProcessTombstone(ctx context.Context, rawData []byte) error {
+	return p.process(JobRemovedSubject, rawData)
+}
+
+// next waits for the next delivery.
+func (p *fakeProcessor) next(t *testing.T) delivery {
+	t.Helper()
+	select {
+	case d := <-p.deliveries:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message delivered")
+		return delivery{}
+	}
+}
+
+// none fails if a message is delivered within wait.
+func (p *fakeProcessor) none(t *testing.T, wait time.Duration) {
+	t.Helper()
+	select {
+	case d := <-p.deliveries:
+		t.Fatalf("unexpected delivery of %q on %s", d.data, d.subject)
+	case <-time.After(wait):
+	}
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		NATSConnTimeout:          5 * time.Second,
+		JetStreamStream:          "JOBS",
+		JetStreamMaxAge:          time.Hour,
+		JetStreamReplicas:        1,
+		JetStreamDuplicateWindow: time.Minute,
+		ConsumerPrefix:           "processing",
+		ConsumerMaxAckPending:    100,
+		BatchSize:                10,
+		ProcessingTimeout:        5 * time.Second,
+		MaxRetries:               3,
+		RetryDelay:               50 * time.Millisecond,
+		RetryMaxDelay:            200 * time.Millisecond,
+	}
+}
+
+func runServer(t *testing.T, storeDir string, port int) *server.Server {
+	t.Helper()
+	opts := natsserver.DefaultTestOptions
+	opts.Port = port
+	opts.JetStream = true
+	opts.StoreDir = storeDir
+	s := natsserver.RunServer(&opts)
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func connect(t *testing.T, s *server.Server) (*nats.Conn, jetstream.JetStream) {
+	t.Helper()
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("connecting to NATS: %v", err)
+	}
+	t.Cleanup(nc.Close)
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nc, js
+}
+
+// startHandler registers the consumers of a new Handler and returns the
+// lifecycle that stops them.
+func startHandler(t *testing.T, nc *nats.Conn, cfg *config.Config, p Processor) *fxtest.Lifecycle {
+	t.Helper()
+	h := &Handler{logger: zap.NewNop(), nc: nc, tracer: noop.NewTracerProvider().Tracer(""), jobProcessor: p, config: cfg}
+	lc := fxtest.NewLifecycle(t)
+	if err := h.RegisterSubscriptions(lc); err != nil {
+		t.Fatalf("RegisterSubscriptions() error = %v", err)
+	}
+	lc.RequireStart()
+	t.Cleanup(func() { lc.Stop(context.Background()) })
+	return lc
+}
+
+// publish sends data the way the ingestion publisher does, with a
+// deduplication ID derived from the content.
+func publish(t *testing.T, js jetstream.JetStream, subject, data string) *jetstream.PubAck {
+	t.Helper()
+	msg := nats.NewMsg(subject)
+	msg.Data = []byte(data)
+	msg.Header.Set(nats.MsgIdHdr, stream.MsgID(subject, msg.Data))
+	ack, err := js.PublishMsg(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("publishing to %s: %v", subject, err)
+	}
+	return ack
+}
+
+// settled waits until the consumer of subject has nothing pending or
+// unacknowledged.
+func settled(t *testing.T, js jetstream.JetStream, cfg *config.Config, subject string) *jetstream.ConsumerInfo {
+	t.Helper()
+	h := &Handler{config: cfg}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		consumer, err := js.Consumer(context.Background(), cfg.JetStreamStream, h.consumerName(subject))
+		if err != nil {
+			t.Fatalf("looking up consumer: %v", err)
+		}
+		info, err := consumer.Info(context.Background())
+		if err != nil {
+			t.Fatalf("consumer info: %v", err)
+		}
+		if info.NumAckPending == 0 && info.NumPending == 0 {
+			return info
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("consumer for %s not settled: %d unacknowledged, %d pending", subject, info.NumAckPending, info.NumPending)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPublishDeduplicatesAndAcks(t *testing.T) {
+	s := runServer(t, t.TempDir(), -1)
+	nc, js := connect(t, s)
+	cfg := testConfig()
+	p := newFakeProcessor(nil)
+	startHandler(t, nc, cfg, p)
+
+	first := publish(t, js, JobPostingsSubject, "posting 1")
+	again := publish(t, js, JobPostingsSubject, "posting 1")
+	if first.Duplicate || !again.Duplicate || again.Sequence != first.Sequence {
+		t.Errorf("acks = %+v, %+v, want the second one a duplicate of the first", first, again)
+	}
+	publish(t, js, CandidatesSubject, "candidate 1")
+
+	got := map[string]string{}
+	for i := 0; i < 2; i++ {
+		d := p.next(t)
+		got[d.subject] = d.data
+	}
+	if got[JobPostingsSubject] != "posting 1" || got[CandidatesSubject] != "candidate 1" {
+		t.Errorf("deliveries = %v, want each message on its own subject", got)
+	}
+	p.none(t, 200*time.Millisecond)
+
+	info := settled(t, js, cfg, JobPostingsSubject)
+	if info.AckFloor.Stream != first.Sequence || info.NumRedelivered != 0 {
+		t.Errorf("ack floor = %d, redelivered = %d, want %d acked on the first delivery", info.AckFloor.Stream, info.NumRedelivered, first.Sequence)
+	}
+}
+
+func TestRetryBacksOff(t *testing.T) {
+	s := runServer(t, t.TempDir(), -1)
+	nc, js := connect(t, s)
+	cfg := testConfig()
+	p := newFakeProcessor(func(data string, attempt int) error {
+		if attempt < 3 {
+			return stderrors.New("database unavailable")
+		}
+		return nil
+	})
+	startHandler(t, nc, cfg, p)
+
+	publish(t, js, JobPostingsSubject, "posting 1")
+	h := &Handler{config: cfg}
+	previous := p.next(t)
+	for delivered := uint64(1); delivered <= 3; delivered++ {
+		d := p.next(t)
+		if gap, want := d.at.Sub(previous.at), h.retryDelay(delivered); gap < want {
+			t.Errorf("delivery %d came %v after the previous one, want at least %v", delivered+1, gap, want)
+		}
+		previous = d
+	}
+	p.none(t, 300*time.Millisecond)
+	settled(t, js, cfg, JobPostingsSubject)
+}
+
+func TestTermAfterMaxRetries(t *testing.T) {
+	s := runServer(t, t.TempDir(), -1)
+	nc, js := connect(t, s)
+	cfg := testConfig()
+	cfg.MaxRetries = 2
+	p := newFakeProcessor(func(data string, attempt int) error {
+		return stderrors.New("database unavailable")
+	})
+	startHandler(t, nc, cfg, p)
+
+	publish(t, js, JobPostingsSubject, "posting 1")
+	for i := 0; i < cfg.MaxRetries+1; i++ {
+		p.next(t)
+	}
+	p.none(t, 500*time.Millisecond)
+	settled(t, js, cfg, JobPostingsSubject)
+}
+
+func TestTermInvalidMessage(t *testing.T) {
+	s := runServer(t, t.TempDir(), -1)
+	nc, js := connect(t, s)
+	cfg := testConfig()
+	p := newFakeProcessor(func(data string, attempt int) error {
+		if data == "not json" {
+			return fmt.Errorf("handling: %w", errors.InvalidInput("parse job posting", stderrors.New("invalid character")))
+		}
+		return nil
+	})
+	startHandler(t, nc, cfg, p)
+
+	publish(t, js, JobPostingsSubject, "not json")
+	publish(t, js, JobPostingsSubject, "posting 1")
+	if d := p.next(t); d.data != "not json" {
+		t.Fatalf("first delivery = %q", d.data)
+	}
+	if d := p.next(t); d.data != "posting 1" {
+		t.Fatalf("second delivery = %q, want the invalid message not redelivered", d.data)
+	}
+	p.none(t, 300*time.Millisecond)
+	settled(t, js, cfg, JobPostingsSubject)
+}
+
+func TestDurableConsumerResumes(t *testing.T) {
+	storeDir := t.TempDir()
+	s := runServer(t, storeDir, -1)
+	port := s.Addr().(*net.TCPAddr).Port
+	nc, js := connect(t, s)
+	cfg := testConfig()
+
+	p := newFakeProcessor(nil)
+	lc := startHandler(t, nc, cfg, p)
+	publish(t, js, JobPostingsSubject, "posting 1")
+	p.next(t)
+	settled(t, js, cfg, JobPostingsSubject)
+	lc.RequireStop()
+
+	// Published while processing is down, and kept across a server
+	// restart.
+	publish(t, js, JobPostingsSubject, "posting 2")
+	nc.Close()
+	s.Shutdown()
+	s.WaitForShutdown()
+
+	s = runServer(t, storeDir, port)
+	nc, js = connect(t, s)
+	p = newFakeProcessor(nil)
+	startHandler(t, nc, cfg, p)
+	publish(t, js, JobPostingsSubject, "posting 3")
+
+	for _, want := range []string{"posting 2", "posting 3"} {
+		if d := p.next(t); d.data != want {
+			t.Errorf("delivery = %q, want %q", d.data, want)
+		}
+	}
+	p.none(t, 200*time.Millisecond)
+}
+
+func TestSlowMessagesAreNotRedelivered(t *testing.T) {
+	s := runServer(t, t.TempDir(), -1)
+	nc, js := connect(t, s)
+	cfg := testConfig()
+	cfg.ProcessingTimeout = 200 * time.Millisecond
+	// Each message is settled a little after its processing timed out, and
+	// the second one waits behind the first after both were pulled.
+	p := newFakeProcessor(func(data string, attempt int) error {
+		time.Sleep(240 * time.Millisecond)
+		return nil
+	})
+	startHandler(t, nc, cfg, p)
+
+	publish(t, js, JobPostingsSubject, "posting 1")
+	publish(t, js, JobPostingsSubject, "posting 2")
+	for _, want := range []string{"posting 1", "posting 2"} {
+		if d := p.next(t); d.data != want {
+			t.Errorf("delivery = %q, want %q", d.data, want)
+		}
+	}
+	p.none(t, 500*time.Millisecond)
+
+	info := settled(t, js, cfg, JobPostingsSubject)
+	if info.NumRedelivered != 0 {
+		t.Errorf("%d messages redelivered, want none", info.NumRedelivered)
+	}
+}
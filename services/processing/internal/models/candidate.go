@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// Candidate is a profile posted in a "Who wants to be hired?" thread.
+type Candidate struct {
+	ID                string
+	ThreadID          int
+	Location          string
+	Remote            string
+	WillingToRelocate string
+	Technologies      []string
+	ResumeURL         string
+	Email             string
+	Description       string
+	Source            string
+	SourceURL         string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	RawData           string
+}
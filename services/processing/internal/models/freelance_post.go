@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// FreelancePost is a comment from a "Freelancer? Seeking freelancer?"
+// thread. Kind tells offers of work apart from requests for it.
+type FreelancePost struct {
+	ID           string
+	ThreadID     int
+	Kind         string
+	Location     string
+	RemotePolicy string
+	Technologies []string
+	Rate         string
+	Email        string
+	URL          string
+	Description  string
+	Source       string
+	SourceURL    string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	RawData      string
+}
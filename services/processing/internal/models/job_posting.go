@@ -10,6 +10,8 @@ type JobPosting struct {
 	Company              string
 	Location             string
 	Description          string
+	DescriptionMarkdown  string
+	Links                []string
 	Technologies         []string
 	ExperienceLevel      string
 	CompensationMin      float64
@@ -17,8 +19,19 @@ type JobPosting struct {
 	CompensationCurrency string
 	CompensationPeriod   string
 	RemotePolicy         string
+	Department           string
+	EmploymentType       string
 	Source               string
 	SourceURL            string
+	SourceBoard          string
+	SourceItemID         string
+	Author               string
+	ThreadID             int
+	ThreadMonth          string
+	Updates              []string
+	IsActive             bool
+	Status               string
+	StatusChangedAt      *time.Time
 	CreatedAt            time.Time
 	UpdatedAt            time.Time
 	RawData              string
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+// JobStatusChange marks the job with the given row ID as no longer open.
+type JobStatusChange struct {
+	JobID     string
+	Status    string
+	ChangedAt time.Time
+}
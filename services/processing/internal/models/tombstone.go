@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// Tombstone marks the row with the given ID in Table as removed at the
+// source.
+type Tombstone struct {
+	Table     string
+	ID        string
+	Reason    string
+	RemovedAt time.Time
+}
@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"shenanigigs/processing/internal/models"
+)
+
+type RawJobStatusChange struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source"`
+	Status    string    `json:"status"`
+	ReplyID   string    `json:"reply_id"`
+	Text      string    `json:"text"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+func ParseJobStatusChange(rawData string) (*models.JobStatusChange, error) {
+	var raw RawJobStatusChange
+	if err := json.Unmarshal([]byte(rawData), &raw); err != nil {
+		return nil, err
+	}
+	if raw.ID == "" || raw.Status == "" {
+		return nil, fmt.Errorf("status change is missing id or status")
+	}
+
+	changedAt := raw.ChangedAt
+	if changedAt.IsZero() {
+		changedAt = time.Now()
+	}
+
+	return &models.JobStatusChange{
+		JobID:     jobUUID(raw.Source, raw.ID),
+		Status:    raw.Status,
+		ChangedAt: changedAt,
+	}, nil
+}
@@ -18,8 +18,42 @@ type RawJobPosting struct {
 	PostedAt    time.Time `json:"posted_at"`
 	RawText     string    `json:"raw_text"`
 	ParentID    int       `json:"parent_id"`
+	Source      string    `json:"source"`
+	URL         string    `json:"url"`
+	HTML        string    `json:"html"`
+	Markdown    string    `json:"markdown"`
+	Links       []RawLink `json:"links"`
+	Author      string    `json:"author"`
+	ItemID      int       `json:"item_id"`
+	ThreadMonth string    `json:"thread_month"`
+
+	Company        string `json:"company"`
+	Location       string `json:"location"`
+	Department     string `json:"department"`
+	EmploymentType string `json:"employment_type"`
+	Board          string `json:"board"`
+
+	Updates []RawPostingUpdate `json:"updates"`
+	Status  string             `json:"status"`
 }
 
+type RawLink struct {
+	URL  string `json:"url"`
+	Text string `json:"text"`
+}
+
+type RawPostingUpdate struct {
+	ID       string    `json:"id"`
+	Text     string    `json:"text"`
+	PostedAt time.Time `json:"posted_at"`
+}
+
+const (
+	defaultSource = "hackernews"
+
+	StatusOpen = "open"
+)
+
 var (
 	companyPattern    = regexp.MustCompile(`(?i)(company|at):\s*([^,|\n]+)`)
 	locationPattern   = regexp.MustCompile(`(?i)(location|remote):\s*([^,|\n]+)`)
@@ -36,16 +70,37 @@ func generateUUIDFromID(id string) string {
 	return uuid.String()
 }
 
+// jobUUID derives the row ID of a posting. Hacker News IDs are hashed on
+// their own so existing rows keep their UUIDs; IDs from other sources are
+// namespaced to avoid collisions.
+func jobUUID(source, id string) string {
+	if source == "" || source == defaultSource {
+		return generateUUIDFromID(id)
+	}
+	return generateUUIDFromID(source + ":" + id)
+}
+
 func ParseJobPosting(rawData string) (*models.JobPosting, error) {
 	var raw RawJobPosting
 	if err := json.Unmarshal([]byte(rawData), &raw); err != nil {
 		return nil, err
 	}
 
-	uuidStr := generateUUIDFromID(raw.ID)
+	source := raw.Source
+	if source == "" {
+		source = defaultSource
+	}
+
+	uuidStr := jobUUID(source, raw.ID)
 
 	cleanText := normalizeText(raw.RawText)
-	titleParts := strings.Split(cleanText, " | ")
+
+	// Only HN comments follow the "Company | Location | Role | ..." header
+	// convention; other sources rely on their own title and the patterns.
+	titleParts := []string{""}
+	if source == defaultSource {
+		titleParts = strings.Split(cleanText, " | ")
+	}
 
 	company := titleParts[0]
 	location := ""
@@ -67,6 +122,18 @@ func ParseJobPosting(rawData string) (*models.JobPosting, error) {
 		}
 	}
 
+	// Structured fields from the source win over anything parsed out of
+	// the text.
+	if raw.Company != "" {
+		company = strings.TrimSpace(raw.Company)
+	}
+	if raw.Location != "" {
+		location = strings.TrimSpace(raw.Location)
+	}
+	if strings.TrimSpace(raw.Title) != "" {
+		title = strings.TrimSpace(raw.Title)
+	}
+
 	if title == "" {
 		if matches := titlePattern.FindStringSubmatch(raw.Description); len(matches) > 2 {
 			title = strings.TrimSpace(matches[2])
@@ -120,12 +187,37 @@ func ParseJobPosting(rawData string) (*models.JobPosting, error) {
 		remotePolicy = "remote"
 	}
 
+	links := make([]string, 0, len(raw.Links))
+	for _, link := range raw.Links {
+		links = append(links, link.URL)
+	}
+
+	updates := make([]string, 0, len(raw.Updates))
+	for _, update := range raw.Updates {
+		updates = append(updates, update.Text)
+	}
+
+	status := raw.Status
+	if status == "" {
+		status = StatusOpen
+	}
+	var statusChangedAt *time.Time
+	if status != StatusOpen {
+		changedAt := raw.PostedAt
+		if len(raw.Updates) > 0 {
+			changedAt = raw.Updates[len(raw.Updates)-1].PostedAt
+		}
+		statusChangedAt = &changedAt
+	}
+
 	return &models.JobPosting{
 		ID:                   uuidStr,
 		Title:                title,
 		Company:              company,
 		Location:             location,
 		Description:          raw.Description,
+		DescriptionMarkdown:  raw.Markdown,
+		Links:                links,
 		Technologies:         technologies,
 		ExperienceLevel:      experienceLevel,
 		CompensationMin:      compMin,
@@ -133,14 +225,34 @@ func ParseJobPosting(rawData string) (*models.JobPosting, error) {
 		CompensationCurrency: "USD",
 		CompensationPeriod:   "yearly",
 		RemotePolicy:         remotePolicy,
-		Source:               "hackernews",
-		SourceURL:            "",
+		Source:               source,
+		SourceURL:            sourceURL(source, raw.ID, raw.URL),
+		SourceItemID:         raw.ID,
+		Author:               raw.Author,
+		ThreadID:             raw.ParentID,
+		ThreadMonth:          raw.ThreadMonth,
+		SourceBoard:          raw.Board,
+		Updates:              updates,
+		IsActive:             status == StatusOpen,
+		Status:               status,
+		StatusChangedAt:      statusChangedAt,
+		Department:           raw.Department,
+		EmploymentType:       raw.EmploymentType,
 		CreatedAt:            raw.PostedAt,
 		UpdatedAt:            time.Now(),
 		RawData:              rawData,
 	}, nil
 }
 
+// sourceURL falls back to the permalink for Hacker News postings published
+// before ingestion sent one.
+func sourceURL(source, id, url string) string {
+	if url == "" && source == defaultSource {
+		return hnItemURL + id
+	}
+	return url
+}
+
 func normalizeText(text string) string {
 	text = regexp.MustCompile(`\n\s*\n`).ReplaceAllString(text, "\n")
 	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
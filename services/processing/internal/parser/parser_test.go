@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseJobPostingStructuredFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		raw          RawJobPosting
+		wantTitle    string
+		wantCompany  string
+		wantLocation string
+	}{
+		{
+			name: "source title wins over title pattern",
+			raw: RawJobPosting{
+				ID:          "acme:1",
+				Source:      "greenhouse",
+				Title:       "Staff Engineer",
+				Company:     "Acme",
+				Location:    "Berlin",
+				Description: "Role: Engineer on the payments team. Location: anywhere",
+			},
+			wantTitle:    "Staff Engineer",
+			wantCompany:  "Acme",
+			wantLocation: "Berlin",
+		},
+		{
+			name: "source title wins over HN header",
+			raw: RawJobPosting{
+				ID:      "42",
+				Title:   "Backend Engineer",
+				RawText: "Globex | Remote | Senior Go Developer | Full-time",
+			},
+			wantTitle:    "Backend Engineer",
+			wantCompany:  "Globex",
+			wantLocation: "Remote",
+		},
+		{
+			name: "title pattern without source title",
+			raw: RawJobPosting{
+				ID:          "feed-1",
+				Source:      "feed",
+				Description: "Company: Initech\nPosition: Data Engineer\nLocation: Austin",
+			},
+			wantTitle:    "Data Engineer",
+			wantCompany:  "Initech",
+			wantLocation: "Austin",
+		},
+		{
+			name: "HN header without source title",
+			raw: RawJobPosting{
+				ID:      "43",
+				RawText: "Hooli | Palo Alto | Senior Platform Engineer | Full-time",
+			},
+			wantTitle:    "Platform Engineer",
+			wantCompany:  "Hooli",
+			wantLocation: "Palo Alto",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.raw)
+			if err != nil {
+				t.Fatalf("marshaling raw posting: %v", err)
+			}
+
+			posting, err := ParseJobPosting(string(data))
+			if err != nil {
+				t.Fatalf("ParseJobPosting() error = %v", err)
+			}
+			if posting.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", posting.Title, tt.wantTitle)
+			}
+			if posting.Company != tt.wantCompany {
+				t.Errorf("Company = %q, want %q", posting.Company, tt.wantCompany)
+			}
+			if posting.Location != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", posting.Location, tt.wantLocation)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"shenanigigs/common/htmltext"
+	"shenanigigs/processing/internal/models"
+)
+
+// RawThreadPost is a comment from a "Who wants to be hired?" or
+// "Freelancer? Seeking freelancer?" thread as published by ingestion.
+type RawThreadPost struct {
+	ID         string    `json:"id"`
+	ThreadID   int       `json:"thread_id"`
+	ThreadType string    `json:"thread_type"`
+	Text       string    `json:"text"`
+	PostedAt   time.Time `json:"posted_at"`
+	Source     string    `json:"source"`
+	URL        string    `json:"url"`
+}
+
+const (
+	FreelanceSeekingWork       = "seeking_work"
+	FreelanceSeekingFreelancer = "seeking_freelancer"
+	FreelanceUnknown           = "unknown"
+
+	hnItemURL = "https://news.ycombinator.com/item?id="
+)
+
+var (
+	fieldPattern = regexp.MustCompile(`^\s*([A-Za-zÀ-ÿ][A-Za-zÀ-ÿ /&-]{1,30}?)\s*:\s*(.+)$`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	urlPattern   = regexp.MustCompile(`https?://\S+`)
+	ratePattern  = regexp.MustCompile(`(?i)[$€£]\s?\d+(?:[.,]\d+)?\s*[kK]?\s*(?:/|per\s+)\s*(?:h|hr|hour|day|d|month|mo)\b`)
+)
+
+func ParseCandidate(rawData string) (*models.Candidate, error) {
+	raw, err := parseRawThreadPost(rawData)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := htmltext.Convert(raw.Text)
+	lines := textLines(doc.Text)
+	fields := parseFields(lines)
+	description := strings.Join(lines, "\n")
+
+	return &models.Candidate{
+		ID:                generateUUIDFromID(raw.ID),
+		ThreadID:          raw.ThreadID,
+		Location:          fields.get("location"),
+		Remote:            yesNo(fields.get("remote")),
+		WillingToRelocate: yesNo(fields.get("willing to relocate", "relocate", "relocation")),
+		Technologies:      technologiesFrom(fields, description),
+		ResumeURL:         resumeURL(fields, doc.Links),
+		Email:             emailFrom(fields, description),
+		Description:       description,
+		Source:            raw.Source,
+		SourceURL:         sourceURL(raw.Source, raw.ID, raw.URL),
+		CreatedAt:         raw.PostedAt,
+		UpdatedAt:         time.Now(),
+		RawData:           rawData,
+	}, nil
+}
+
+func ParseFreelancePost(rawData string) (*models.FreelancePost, error) {
+	raw, err := parseRawThreadPost(rawData)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := textLines(htmltext.Convert(raw.Text).Text)
+	fields := parseFields(lines)
+	description := strings.Join(lines, "\n")
+
+	// Posts open with a "SEEKING WORK | Location | Remote" style header.
+	header := ""
+	if len(lines) > 0 {
+		header = lines[0]
+	}
+
+	kind := FreelanceUnknown
+	switch upper := strings.ToUpper(header); {
+	case strings.Contains(upper, "SEEKING WORK"):
+		kind = FreelanceSeekingWork
+	case strings.Contains(upper, "SEEKING FREELANCER"), strings.Contains(upper, "HIRING"):
+		kind = FreelanceSeekingFreelancer
+	}
+
+	location := fields.get("location")
+	if location == "" {
+		if parts := strings.Split(header, "|"); len(parts) > 1 {
+			location = strings.TrimSpace(parts[1])
+		}
+	}
+
+	remotePolicy := "unknown"
+	if remote := yesNo(fields.get("remote")); remote == "yes" ||
+		strings.Contains(strings.ToLower(location), "remote") ||
+		remotePattern.MatchString(header) {
+		remotePolicy = "remote"
+	}
+
+	rate := fields.get("rate", "rates", "hourly rate", "budget")
+	if rate == "" {
+		rate = ratePattern.FindString(description)
+	}
+
+	return &models.FreelancePost{
+		ID:           generateUUIDFromID(raw.ID),
+		ThreadID:     raw.ThreadID,
+		Kind:         kind,
+		Location:     location,
+		RemotePolicy: remotePolicy,
+		Technologies: technologiesFrom(fields, description),
+		Rate:         rate,
+		Email:        emailFrom(fields, description),
+		URL:          fields.get("website", "portfolio", "url", "site"),
+		Description:  description,
+		Source:       raw.Source,
+		SourceURL:    sourceURL(raw.Source, raw.ID, raw.URL),
+		CreatedAt:    raw.PostedAt,
+		UpdatedAt:    time.Now(),
+		RawData:      rawData,
+	}, nil
+}
+
+func parseRawThreadPost(rawData string) (*RawThreadPost, error) {
+	var raw RawThreadPost
+	if err := json.Unmarshal([]byte(rawData), &raw); err != nil {
+		return nil, err
+	}
+	if raw.Source == "" {
+		raw.Source = defaultSource
+	}
+	return &raw, nil
+}
+
+// textLines splits the text of an HN comment, as rendered by htmltext, into
+// trimmed, non-empty lines.
+func textLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// postFields holds the "Key: value" lines of a post keyed by lower-cased
+// key. Only the first occurrence of a key is kept.
+type postFields map[string]string
+
+func parseFields(lines []string) postFields {
+	fields := postFields{}
+	for _, line := range lines {
+		matches := fieldPattern.FindStringSubmatch(line)
+		if len(matches) < 3 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(matches[1]))
+		if _, ok := fields[key]; !ok {
+			fields[key] = strings.TrimSpace(matches[2])
+		}
+	}
+	return fields
+}
+
+func (f postFields) get(keys ...string) string {
+	for _, key := range keys {
+		if value := f[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func yesNo(value string) string {
+	value = strings.ToLower(value)
+	switch {
+	case value == "":
+		return "unknown"
+	case strings.HasPrefix(value, "yes"), strings.HasPrefix(value, "y "), value == "y":
+		return "yes"
+	case strings.HasPrefix(value, "no"), value == "n":
+		return "no"
+	default:
+		return value
+	}
+}
+
+func technologiesFrom(fields postFields, description string) []string {
+	if listed := fields.get("technologies", "tech stack", "skills", "stack"); listed != "" {
+		var technologies []string
+		for _, tech := range strings.Split(listed, ",") {
+			if tech = strings.ToLower(strings.TrimSpace(tech)); tech != "" {
+				technologies = append(technologies, tech)
+			}
+		}
+		return technologies
+	}
+	return extractTechnologies(description)
+}
+
+func resumeURL(fields postFields, links []htmltext.Link) string {
+	value := fields.get("résumé/cv", "resume/cv", "résumé", "resume", "cv")
+	if value == "" {
+		return ""
+	}
+	// HN shortens the text of long links, so prefer the href of the first
+	// link that starts like the field value.
+	for _, link := range links {
+		if prefix := strings.TrimSuffix(urlPattern.FindString(value), "..."); prefix != "" && strings.HasPrefix(link.URL, prefix) {
+			return link.URL
+		}
+	}
+	if url := urlPattern.FindString(value); url != "" {
+		return url
+	}
+	return value
+}
+
+func emailFrom(fields postFields, description string) string {
+	if email := fields.get("email", "e-mail", "contact"); email != "" {
+		return email
+	}
+	return emailPattern.FindString(description)
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func rawThreadPost(t *testing.T, threadType, text string) string {
+	t.Helper()
+	data, err := json.Marshal(RawThreadPost{ID: "41000001", ThreadID: 41000000, ThreadType: threadType, Text: text})
+	if err != nil {
+		t.Fatalf("marshaling raw thread post: %v", err)
+	}
+	return string(data)
+}
+
+func TestParseCandidate(t *testing.T) {
+	text := `Location: Lisbon, Portugal<p>Remote: Yes<p>Willing to relocate: No<p>` +
+		`Technologies: TypeScript, React, Node.js<p>` +
+		`Résumé&#x2F;CV: <a href="https:&#x2F;&#x2F;drive.example.com&#x2F;file&#x2F;d&#x2F;1a2b3c4d5e6f7g8h9i0j&#x2F;view" rel="nofollow">https:&#x2F;&#x2F;drive.example.com&#x2F;file&#x2F;d&#x2F;1a2b3c...</a><p>` +
+		`Email: jane.doe@example.org<p>I care about <i>boring</i> technology &amp; shipping.`
+
+	candidate, err := ParseCandidate(rawThreadPost(t, "candidates", text))
+	if err != nil {
+		t.Fatalf("ParseCandidate() error = %v", err)
+	}
+
+	if candidate.Location != "Lisbon, Portugal" {
+		t.Errorf("Location = %q", candidate.Location)
+	}
+	if candidate.Remote != "yes" || candidate.WillingToRelocate != "no" {
+		t.Errorf("Remote = %q, WillingToRelocate = %q, want yes and no", candidate.Remote, candidate.WillingToRelocate)
+	}
+	if got := strings.Join(candidate.Technologies, ","); got != "typescript,react,node.js" {
+		t.Errorf("Technologies = %q", got)
+	}
+	if want := "https://drive.example.com/file/d/1a2b3c4d5e6f7g8h9i0j/view"; candidate.ResumeURL != want {
+		t.Errorf("ResumeURL = %q, want %q", candidate.ResumeURL, want)
+	}
+	if candidate.Email != "jane.doe@example.org" {
+		t.Errorf("Email = %q", candidate.Email)
+	}
+	if !strings.HasSuffix(candidate.Description, "I care about boring technology & shipping.") {
+		t.Errorf("Description = %q, want decoded text without tags", candidate.Description)
+	}
+}
+
+func TestParseFreelancePost(t *testing.T) {
+	text := `SEEKING WORK | Remote | US time zones<p>Data engineer. Airflow, dbt, Python.<p>` +
+		`Rates: $120&#x2F;hr<p>Portfolio: <a href="https:&#x2F;&#x2F;portfolio.example.dev" rel="nofollow">https:&#x2F;&#x2F;portfolio.example.dev</a><br>` +
+		`Email: hello@portfolio.example.dev`
+
+	post, err := ParseFreelancePost(rawThreadPost(t, "freelance", text))
+	if err != nil {
+		t.Fatalf("ParseFreelancePost() error = %v", err)
+	}
+
+	if post.Kind != FreelanceSeekingWork {
+		t.Errorf("Kind = %q, want %q", post.Kind, FreelanceSeekingWork)
+	}
+	if post.Location != "Remote" || post.RemotePolicy != "remote" {
+		t.Errorf("Location = %q, RemotePolicy = %q", post.Location, post.RemotePolicy)
+	}
+	if post.Rate != "$120/hr" {
+		t.Errorf("Rate = %q, want $120/hr", post.Rate)
+	}
+	if post.URL != "https://portfolio.example.dev" {
+		t.Errorf("URL = %q", post.URL)
+	}
+	if post.Email != "hello@portfolio.example.dev" {
+		t.Errorf("Email = %q", post.Email)
+	}
+}
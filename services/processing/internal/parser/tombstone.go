@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"shenanigigs/processing/internal/models"
+)
+
+type RawTombstone struct {
+	ID         string    `json:"id"`
+	Source     string    `json:"source"`
+	ThreadID   int       `json:"thread_id"`
+	ThreadType string    `json:"thread_type"`
+	Reason     string    `json:"reason"`
+	RemovedAt  time.Time `json:"removed_at"`
+}
+
+// ParseTombstone works out which table and row a removed post was stored
+// in. Candidate and freelance posts are keyed by their ID alone.
+func ParseTombstone(rawData string) (*models.Tombstone, error) {
+	var raw RawTombstone
+	if err := json.Unmarshal([]byte(rawData), &raw); err != nil {
+		return nil, err
+	}
+	if raw.ID == "" {
+		return nil, fmt.Errorf("tombstone is missing id")
+	}
+
+	tombstone := &models.Tombstone{
+		Reason:    raw.Reason,
+		RemovedAt: raw.RemovedAt,
+	}
+	if tombstone.RemovedAt.IsZero() {
+		tombstone.RemovedAt = time.Now()
+	}
+
+	switch raw.ThreadType {
+	case "candidates":
+		tombstone.Table = "candidates"
+		tombstone.ID = generateUUIDFromID(raw.ID)
+	case "freelance":
+		tombstone.Table = "freelance_posts"
+		tombstone.ID = generateUUIDFromID(raw.ID)
+	default:
+		tombstone.Table = "jobs"
+		tombstone.ID = jobUUID(raw.Source, raw.ID)
+	}
+
+	return tombstone, nil
+}
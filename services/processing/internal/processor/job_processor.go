@@ -6,6 +6,7 @@ import (
 
 	"shenanigigs/common/telemetry"
 	"shenanigigs/processing/internal/config"
+	"shenanigigs/processing/internal/errors"
 	"shenanigigs/processing/internal/models"
 	"shenanigigs/processing/internal/parser"
 
@@ -15,6 +16,12 @@ import (
 	"go.uber.org/zap"
 )
 
+// nextVersion is the updated_at of a row version written to mark a row
+// inactive. The tables are ReplacingMergeTree(updated_at) with second
+// precision, so the version must be strictly newer than the row it replaces
+// even when both are written within the same second.
+const nextVersion = "greatest(updated_at + 1, now())"
+
 type JobProcessor struct {
 	logger *zap.Logger
 	db     clickhouse.Conn
@@ -41,7 +48,7 @@ func (p *JobProcessor) ProcessJobPosting(ctx context.Context, rawData []byte) er
 	parsedPosting, err := parser.ParseJobPosting(string(rawData))
 	if err != nil {
 		p.logger.Error("Failed to parse job posting", zap.Error(err))
-		return fmt.Errorf("parse job posting: %w", err)
+		return errors.InvalidInput("parse job posting", err)
 	}
 
 	if err := p.storeJobPosting(ctx, parsedPosting); err != nil {
@@ -55,12 +62,17 @@ func (p *JobProcessor) ProcessJobPosting(ctx context.Context, rawData []byte) er
 func (p *JobProcessor) storeJobPosting(ctx context.Context, posting *models.JobPosting) error {
 	query := `
 		INSERT INTO jobs (
-			id, title, company, location, description, technologies,
+			id, title, company, location, description, description_markdown,
+			links, technologies,
 			experience_level, compensation_min, compensation_max,
 			compensation_currency, compensation_period, remote_policy,
-			source, source_url, created_at, updated_at, raw_data
+			department, employment_type, source, source_url, source_board,
+			source_item_id, author, thread_id, thread_month,
+			updates, is_active, status, status_changed_at,
+			created_at, updated_at, raw_data
 		) VALUES (
-			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+			?, ?, ?, ?
 		)
 	`
 
@@ -70,6 +82,8 @@ func (p *JobProcessor) storeJobPosting(ctx context.Context, posting *models.JobP
 		posting.Company,
 		posting.Location,
 		posting.Description,
+		posting.DescriptionMarkdown,
+		posting.Links,
 		posting.Technologies,
 		posting.ExperienceLevel,
 		posting.CompensationMin,
@@ -77,8 +91,19 @@ func (p *JobProcessor) storeJobPosting(ctx context.Context, posting *models.JobP
 		posting.CompensationCurrency,
 		posting.CompensationPeriod,
 		posting.RemotePolicy,
+		posting.Department,
+		posting.EmploymentType,
 		posting.Source,
 		posting.SourceURL,
+		posting.SourceBoard,
+		posting.SourceItemID,
+		posting.Author,
+		uint64(posting.ThreadID),
+		posting.ThreadMonth,
+		posting.Updates,
+		posting.IsActive,
+		posting.Status,
+		posting.StatusChangedAt,
 		posting.CreatedAt,
 		posting.UpdatedAt,
 		posting.RawData,
@@ -88,3 +113,113 @@ func (p *JobProcessor) storeJobPosting(ctx context.Context, posting *models.JobP
 
 	return nil
 }
+
+func (p *JobProcessor) ProcessJobStatus(ctx context.Context, rawData []byte) error {
+	ctx, span := p.tracer.Start(ctx, "ProcessJobStatus")
+	defer span.End()
+
+	change, err := parser.ParseJobStatusChange(string(rawData))
+	if err != nil {
+		p.logger.Error("Failed to parse job status change", zap.Error(err))
+		return errors.InvalidInput("parse job status change", err)
+	}
+
+	// The status is applied to the stored posting. When the change overtook
+	// the posting on the stream, it is retried until the posting is stored.
+	// A posting republished after the signal carries the status itself.
+	// Rather than mutating the row, a new version of it is inserted and
+	// ReplacingMergeTree keeps the newest.
+	if err := p.requireRow(ctx, "jobs", change.JobID); err != nil {
+		p.logger.Warn("Job for status change not found, retrying later",
+			zap.String("job_id", change.JobID),
+			zap.Error(err))
+		return err
+	}
+
+	query := `
+		INSERT INTO jobs
+		SELECT * REPLACE (
+			0 AS is_active,
+			? AS status,
+			? AS status_changed_at,
+			` + nextVersion + ` AS updated_at
+		)
+		FROM jobs FINAL
+		WHERE id = ?
+	`
+
+	if err := p.db.Exec(ctx, query, change.Status, change.ChangedAt, change.JobID); err != nil {
+		p.logger.Error("Failed to update job status",
+			zap.String("job_id", change.JobID),
+			zap.Error(err))
+		return fmt.Errorf("update job status: %w", err)
+	}
+
+	p.logger.Info("Marked job inactive",
+		zap.String("job_id", change.JobID),
+		zap.String("status", change.Status))
+	return nil
+}
+
+func (p *JobProcessor) ProcessTombstone(ctx context.Context, rawData []byte) error {
+	ctx, span := p.tracer.Start(ctx, "ProcessTombstone")
+	defer span.End()
+
+	tombstone, err := parser.ParseTombstone(string(rawData))
+	if err != nil {
+		p.logger.Error("Failed to parse tombstone", zap.Error(err))
+		return errors.InvalidInput("parse tombstone", err)
+	}
+
+	// Table comes from a fixed set chosen by the parser, never from the
+	// message itself.
+	if err := p.requireRow(ctx, tombstone.Table, tombstone.ID); err != nil {
+		p.logger.Warn("Row to soft-delete not found, retrying later",
+			zap.String("table", tombstone.Table),
+			zap.String("id", tombstone.ID),
+			zap.Error(err))
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s
+		SELECT * REPLACE (
+			0 AS is_active,
+			? AS removed_at,
+			%[2]s AS updated_at
+		)
+		FROM %[1]s FINAL
+		WHERE id = ?
+	`, tombstone.Table, nextVersion)
+
+	if err := p.db.Exec(ctx, query, tombstone.RemovedAt, tombstone.ID); err != nil {
+		p.logger.Error("Failed to soft-delete row",
+			zap.String("table", tombstone.Table),
+			zap.String("id", tombstone.ID),
+			zap.Error(err))
+		return fmt.Errorf("soft-delete %s row: %w", tombstone.Table, err)
+	}
+
+	p.logger.Info("Soft-deleted removed post",
+		zap.String("table", tombstone.Table),
+		zap.String("id", tombstone.ID),
+		zap.String("reason", tombstone.Reason))
+	return nil
+}
+
+// requireRow returns a NotFound error when the row a status change or
+// tombstone applies to has not been stored yet. The message then overtook
+// the posting it refers to; copying the row would insert nothing and the
+// change would be lost. The error is retried like any other, so the change
+// is applied once the posting is stored.
+func (p *JobProcessor) requireRow(ctx context.Context, table, id string) error {
+	var count uint64
+	query := fmt.Sprintf("SELECT count() FROM %s WHERE id = ?", table)
+	if err := p.db.QueryRow(ctx, query, id).Scan(&count); err != nil {
+		return fmt.Errorf("look up %s row: %w", table, err)
+	}
+	if count == 0 {
+		return errors.NotFound(fmt.Sprintf("%s row %s is not stored yet", table, id), nil)
+	}
+	return nil
+}
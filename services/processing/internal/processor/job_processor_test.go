@@ -0,0 +1,289 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"shenanigigs/common/database/schema"
+	"shenanigigs/common/database/schema/migrations"
+	"shenanigigs/processing/internal/config"
+	"shenanigigs/processing/internal/errors"
+	"shenanigigs/processing/internal/parser"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"go.uber.org/zap"
+)
+
+const (
+	testPosting   = `{"id": "41000001", "source": "hackernews", "raw_text": "Acme | Berlin | Backend Engineer | Full-time"}`
+	testTombstone = `{"id": "41000001", "source": "hackernews", "reason": "deleted"}`
+	testStatus    = `{"id": "41000001", "source": "hackernews", "status": "filled"}`
+)
+
+// fakeConn stands in for ClickHouse. Count queries report whether the
+// queried ID is in stored, and other statements are recorded.
+type fakeConn struct {
+	driver.Conn
+	stored map[string]bool
+	execs  []string
+}
+
+func (c *fakeConn) QueryRow(ctx context.Context, query string, args ...any) driver.Row {
+	var count uint64
+	if c.stored[args[0].(string)] {
+		count = 1
+	}
+	return countRow{count: count}
+}
+
+func (c *fakeConn) Exec(ctx context.Context, query string, args ...any) error {
+	c.execs = append(c.execs, query)
+	return nil
+}
+
+type countRow struct {
+	driver.Row
+	count uint64
+}
+
+func (r countRow) Scan(dest ...any) error {
+	*dest[0].(*uint64) = r.count
+	return nil
+}
+
+func TestProcessTombstoneWaitsForRow(t *testing.T) {
+	tombstone, err := parser.ParseTombstone(testTombstone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		stored    bool
+		wantErr   bool
+		wantExecs int
+	}{
+		{"posting stored", true, false, 1},
+		{"posting not stored yet", false, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &fakeConn{stored: map[string]bool{tombstone.ID: tt.stored}}
+			p := NewJobProcessor(zap.NewNop(), conn, nil, &config.Config{})
+
+			err := p.ProcessTombstone(context.Background(), []byte(testTombstone))
+			if tt.wantErr {
+				if err == nil || errors.HasType(err, errors.ErrTypeInvalidInput) {
+					t.Fatalf("ProcessTombstone() error = %v, want a retryable error", err)
+				}
+			} else if err != nil {
+				t.Fatalf("ProcessTombstone() error = %v", err)
+			}
+			if len(conn.execs) != tt.wantExecs {
+				t.Errorf("ran %d statements, want %d", len(conn.execs), tt.wantExecs)
+			}
+		})
+	}
+}
+
+func TestProcessJobStatusWaitsForRow(t *testing.T) {
+	change, err := parser.ParseJobStatusChange(testStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		stored    bool
+		wantErr   bool
+		wantExecs int
+	}{
+		{"posting stored", true, false, 1},
+		{"posting not stored yet", false, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &fakeConn{stored: map[string]bool{change.JobID: tt.stored}}
+			p := NewJobProcessor(zap.NewNop(), conn, nil, &config.Config{})
+
+			err := p.ProcessJobStatus(context.Background(), []byte(testStatus))
+			if tt.wantErr {
+				if err == nil || errors.HasType(err, errors.ErrTypeInvalidInput) {
+					t.Fatalf("ProcessJobStatus() error = %v, want a retryable error", err)
+				}
+			} else if err != nil {
+				t.Fatalf("ProcessJobStatus() error = %v", err)
+			}
+			if len(conn.execs) != tt.wantExecs {
+				t.Errorf("ran %d statements, want %d", len(conn.execs), tt.wantExecs)
+			}
+		})
+	}
+}
+
+// newClickHouseProcessor connects to the server in CLICKHOUSE_TEST_DSN and
+// creates the jobs table in a new database, which is dropped afterwards.
+func newClickHouseProcessor(t *testing.T) (*JobProcessor, clickhouse.Conn) {
+	t.Helper()
+	dsn := os.Getenv("CLICKHOUSE_TEST_DSN")
+	if dsn == "" {
+		t.Skip("CLICKHOUSE_TEST_DSN not set")
+	}
+
+	ctx := context.Background()
+	options, err := clickhouse.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("parsing CLICKHOUSE_TEST_DSN: %v", err)
+	}
+	admin, err := clickhouse.Open(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	database := fmt.Sprintf("processing_test_%d", time.Now().UnixNano())
+	if err := admin.Exec(ctx, "CREATE DATABASE "+database); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec(context.Background(), "DROP DATABASE "+database) })
+
+	options.Auth.Database = database
+	conn, err := clickhouse.Open(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	for _, migration := range []schema.Migration{
+		migrations.CreateJobsTable,
+		migrations.AddBoardColumnsToJobs,
+		migrations.AddStatusColumnsToJobs,
+		migrations.AddRemovedAtToJobs,
+		migrations.AddMarkdownAndLinksToJobs,
+		migrations.AddProvenanceToJobs,
+	} {
+		if err := conn.Exec(ctx, migration.Up); err != nil {
+			t.Fatalf("migration %d: %v", migration.Version, err)
+		}
+	}
+
+	return NewJobProcessor(zap.NewNop(), conn, nil, &config.Config{}), conn
+}
+
+// jobActive reads whether the job is active and when it was removed.
+func jobActive(t *testing.T, conn clickhouse.Conn, id string) (bool, *time.Time) {
+	t.Helper()
+	var active uint8
+	var removedAt *time.Time
+	err := conn.QueryRow(context.Background(),
+		"SELECT is_active, removed_at FROM jobs FINAL WHERE id = ?", id,
+	).Scan(&active, &removedAt)
+	if err != nil {
+		t.Fatalf("reading job %s: %v", id, err)
+	}
+	return active != 0, removedAt
+}
+
+func TestProcessTombstoneClickHouse(t *testing.T) {
+	tombstone, err := parser.ParseTombstone(testTombstone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("posting first", func(t *testing.T) {
+		p, conn := newClickHouseProcessor(t)
+		ctx := context.Background()
+
+		if err := p.ProcessJobPosting(ctx, []byte(testPosting)); err != nil {
+			t.Fatalf("ProcessJobPosting() error = %v", err)
+		}
+		if err := p.ProcessTombstone(ctx, []byte(testTombstone)); err != nil {
+			t.Fatalf("ProcessTombstone() error = %v", err)
+		}
+		if active, removedAt := jobActive(t, conn, tombstone.ID); active || removedAt == nil {
+			t.Errorf("job active = %v, removed_at = %v, want removed", active, removedAt)
+		}
+	})
+
+	t.Run("tombstone first", func(t *testing.T) {
+		p, conn := newClickHouseProcessor(t)
+		ctx := context.Background()
+
+		err := p.ProcessTombstone(ctx, []byte(testTombstone))
+		if err == nil || errors.HasType(err, errors.ErrTypeInvalidInput) {
+			t.Fatalf("ProcessTombstone() before the posting error = %v, want a retryable error", err)
+		}
+		if err := p.ProcessJobPosting(ctx, []byte(testPosting)); err != nil {
+			t.Fatalf("ProcessJobPosting() error = %v", err)
+		}
+		// The redelivered tombstone now finds the posting.
+		if err := p.ProcessTombstone(ctx, []byte(testTombstone)); err != nil {
+			t.Fatalf("ProcessTombstone() redelivery error = %v", err)
+		}
+		if active, removedAt := jobActive(t, conn, tombstone.ID); active || removedAt == nil {
+			t.Errorf("job active = %v, removed_at = %v, want removed", active, removedAt)
+		}
+	})
+}
+
+// jobStatus reads whether the job is active and its status.
+func jobStatus(t *testing.T, conn clickhouse.Conn, id string) (bool, string) {
+	t.Helper()
+	var active uint8
+	var status string
+	err := conn.QueryRow(context.Background(),
+		"SELECT is_active, status FROM jobs FINAL WHERE id = ?", id,
+	).Scan(&active, &status)
+	if err != nil {
+		t.Fatalf("reading job %s: %v", id, err)
+	}
+	return active != 0, status
+}
+
+func TestProcessJobStatusClickHouse(t *testing.T) {
+	change, err := parser.ParseJobStatusChange(testStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("posting first", func(t *testing.T) {
+		p, conn := newClickHouseProcessor(t)
+		ctx := context.Background()
+
+		if err := p.ProcessJobPosting(ctx, []byte(testPosting)); err != nil {
+			t.Fatalf("ProcessJobPosting() error = %v", err)
+		}
+		if err := p.ProcessJobStatus(ctx, []byte(testStatus)); err != nil {
+			t.Fatalf("ProcessJobStatus() error = %v", err)
+		}
+		if active, status := jobStatus(t, conn, change.JobID); active || status != "filled" {
+			t.Errorf("job active = %v, status = %q, want inactive and filled", active, status)
+		}
+	})
+
+	t.Run("status first", func(t *testing.T) {
+		p, conn := newClickHouseProcessor(t)
+		ctx := context.Background()
+
+		err := p.ProcessJobStatus(ctx, []byte(testStatus))
+		if err == nil || errors.HasType(err, errors.ErrTypeInvalidInput) {
+			t.Fatalf("ProcessJobStatus() before the posting error = %v, want a retryable error", err)
+		}
+		if err := p.ProcessJobPosting(ctx, []byte(testPosting)); err != nil {
+			t.Fatalf("ProcessJobPosting() error = %v", err)
+		}
+		// The redelivered status change now finds the posting.
+		if err := p.ProcessJobStatus(ctx, []byte(testStatus)); err != nil {
+			t.Fatalf("ProcessJobStatus() redelivery error = %v", err)
+		}
+		if active, status := jobStatus(t, conn, change.JobID); active || status != "filled" {
+			t.Errorf("job active = %v, status = %q, want inactive and filled", active, status)
+		}
+	})
+}
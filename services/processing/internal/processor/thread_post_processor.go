@@ -0,0 +1,115 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"shenanigigs/processing/internal/errors"
+	"shenanigigs/processing/internal/models"
+	"shenanigigs/processing/internal/parser"
+
+	"go.uber.org/zap"
+)
+
+func (p *JobProcessor) ProcessCandidate(ctx context.Context, rawData []byte) error {
+	ctx, span := p.tracer.Start(ctx, "ProcessCandidate")
+	defer span.End()
+
+	candidate, err := parser.ParseCandidate(string(rawData))
+	if err != nil {
+		p.logger.Error("Failed to parse candidate", zap.Error(err))
+		return errors.InvalidInput("parse candidate", err)
+	}
+
+	if err := p.storeCandidate(ctx, candidate); err != nil {
+		p.logger.Error("Failed to store candidate", zap.Error(err))
+		return fmt.Errorf("store candidate: %w", err)
+	}
+
+	return nil
+}
+
+func (p *JobProcessor) ProcessFreelancePost(ctx context.Context, rawData []byte) error {
+	ctx, span := p.tracer.Start(ctx, "ProcessFreelancePost")
+	defer span.End()
+
+	post, err := parser.ParseFreelancePost(string(rawData))
+	if err != nil {
+		p.logger.Error("Failed to parse freelance post", zap.Error(err))
+		return errors.InvalidInput("parse freelance post", err)
+	}
+
+	if err := p.storeFreelancePost(ctx, post); err != nil {
+		p.logger.Error("Failed to store freelance post", zap.Error(err))
+		return fmt.Errorf("store freelance post: %w", err)
+	}
+
+	return nil
+}
+
+func (p *JobProcessor) storeCandidate(ctx context.Context, candidate *models.Candidate) error {
+	query := `
+		INSERT INTO candidates (
+			id, thread_id, location, remote, willing_to_relocate, technologies,
+			resume_url, email, description, source, source_url,
+			created_at, updated_at, raw_data
+		) VALUES (
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		)
+	`
+
+	if err := p.db.Exec(ctx, query,
+		candidate.ID,
+		uint64(candidate.ThreadID),
+		candidate.Location,
+		candidate.Remote,
+		candidate.WillingToRelocate,
+		candidate.Technologies,
+		candidate.ResumeURL,
+		candidate.Email,
+		candidate.Description,
+		candidate.Source,
+		candidate.SourceURL,
+		candidate.CreatedAt,
+		candidate.UpdatedAt,
+		candidate.RawData,
+	); err != nil {
+		return fmt.Errorf("insert candidate: %w", err)
+	}
+
+	return nil
+}
+
+func (p *JobProcessor) storeFreelancePost(ctx context.Context, post *models.FreelancePost) error {
+	query := `
+		INSERT INTO freelance_posts (
+			id, thread_id, kind, location, remote_policy, technologies, rate,
+			email, url, description, source, source_url,
+			created_at, updated_at, raw_data
+		) VALUES (
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		)
+	`
+
+	if err := p.db.Exec(ctx, query,
+		post.ID,
+		uint64(post.ThreadID),
+		post.Kind,
+		post.Location,
+		post.RemotePolicy,
+		post.Technologies,
+		post.Rate,
+		post.Email,
+		post.URL,
+		post.Description,
+		post.Source,
+		post.SourceURL,
+		post.CreatedAt,
+		post.UpdatedAt,
+		post.RawData,
+	); err != nil {
+		return fmt.Errorf("insert freelance post: %w", err)
+	}
+
+	return nil
+}